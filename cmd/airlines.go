@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/northeastloon/flight_tracker/internal/config"
+	"github.com/northeastloon/flight_tracker/internal/provider"
+)
+
+// runAirlines loads the OpenFlights airline table into the store.
+func runAirlines(ctx context.Context, args []string) error {
+	fs := newEnvFlags("airlines")
+	cf := config.BindFlags(fs.FlagSet)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: flight_tracker airlines airlines.dat")
+		fmt.Fprintln(fs.Output(), "\nReplaces the stored airlines with those of an OpenFlights airlines.dat")
+		fmt.Fprintln(fs.Output(), "(https://openflights.org/data), optionally gzipped. Callsigns are decoded")
+		fmt.Fprint(fs.Output(), "against the airline table when queried, so no stored data is rewritten.\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("airlines: expected exactly one airlines.dat")
+	}
+
+	cfg, cleanup, err := setup(ctx, cf)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	airlines, rejected, err := provider.ReadOpenFlightsAirlines(f)
+	if err != nil {
+		return fmt.Errorf("airlines %s: %w", fs.Arg(0), err)
+	}
+
+	db, err := openDatabase(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := requireMigrations(ctx, db); err != nil {
+		return err
+	}
+
+	if err := db.ImportAirlines(ctx, airlines); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "%d airlines loaded, %d rows rejected\n", len(airlines), rejected)
+	return nil
+}
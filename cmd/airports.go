@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/northeastloon/flight_tracker/internal/config"
+	"github.com/northeastloon/flight_tracker/internal/provider"
+)
+
+// runAirports loads the OurAirports airport and runway CSVs into the store.
+func runAirports(ctx context.Context, args []string) error {
+	fs := newEnvFlags("airports")
+	runwaysPath := fs.String("runways", "AIRPORTS_RUNWAYS", "", "OurAirports runways.csv to load with the airports")
+	cf := config.BindFlags(fs.FlagSet)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: flight_tracker airports [-runways runways.csv] airports.csv")
+		fmt.Fprintln(fs.Output(), "\nReplaces the stored airports with those of an OurAirports airports.csv")
+		fmt.Fprintln(fs.Output(), "(https://ourairports.com/data/), optionally gzipped, and infers the departure")
+		fmt.Fprint(fs.Output(), "and arrival airports of stored flights again.\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("airports: expected exactly one airports.csv")
+	}
+
+	cfg, cleanup, err := setup(ctx, cf)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	airportsFile, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer airportsFile.Close()
+
+	var runwaysFile io.Reader
+	if *runwaysPath != "" {
+		f, err := os.Open(*runwaysPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		runwaysFile = f
+	}
+
+	airports, rejected, err := provider.ReadOurAirports(airportsFile, runwaysFile)
+	if err != nil {
+		return err
+	}
+	runways := 0
+	for _, a := range airports {
+		runways += len(a.Runways)
+	}
+
+	db, err := openDatabase(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := requireMigrations(ctx, db); err != nil {
+		return err
+	}
+
+	flights, err := db.ImportAirports(ctx, airports)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "%d airports and %d runways loaded, %d rows rejected, airports of %d flights inferred\n",
+		len(airports), runways, rejected, flights)
+	return nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/http"
+
+	"github.com/joho/godotenv"
+	"github.com/northeastloon/flight_tracker/internal/config"
+	storage "github.com/northeastloon/flight_tracker/internal/postgres"
+	"github.com/northeastloon/flight_tracker/internal/tracing"
+	"golang.org/x/sync/errgroup"
+)
+
+// loadConfig loads .env into the environment and then the layered config.
+func loadConfig(flags *config.Flags) (config.Config, error) {
+
+	// load environmental vars
+	if err := godotenv.Load(); err != nil {
+		log.Printf("Warning: .env file not found")
+	}
+
+	cfg, err := config.Load(flags)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return cfg, nil
+}
+
+// setup loads the config and installs tracing. The returned function flushes
+// pending spans and must be called before exit.
+func setup(ctx context.Context, flags *config.Flags) (config.Config, func(), error) {
+	cfg, err := loadConfig(flags)
+	if err != nil {
+		return cfg, nil, err
+	}
+
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing.Exporter)
+	if err != nil {
+		return cfg, nil, fmt.Errorf("failed to initialise tracing: %w", err)
+	}
+
+	return cfg, func() {
+		flushCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := shutdownTracing(flushCtx); err != nil {
+			slog.Error("failed to flush traces", slog.Any("err", err))
+		}
+	}, nil
+}
+
+func openDatabase(cfg config.Database, opts ...storage.Option) (*storage.Database, error) {
+	db, err := storage.NewDatabase(cfg, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the database: %w", err)
+	}
+	return db, nil
+}
+
+// requireMigrations fails when the schema is behind this binary, so that
+// workers started before `migrate up` do not write to an old schema.
+func requireMigrations(ctx context.Context, db *storage.Database) error {
+	applied, err := db.MigrationsApplied(ctx)
+	if err != nil {
+		return err
+	}
+	if !applied {
+		return errors.New("database schema is out of date: run `migrate up` first")
+	}
+	return nil
+}
+
+// httpServer is implemented by *http.Server and *echo.Echo.
+type httpServer interface {
+	Shutdown(ctx context.Context) error
+}
+
+// serveHTTP runs start in g and shuts the server down once ctx is done.
+func serveHTTP(ctx context.Context, g *errgroup.Group, srv httpServer, start func() error) {
+	g.Go(func() error {
+		if err := start(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("failed to start server: %w", err)
+		}
+		return nil
+	})
+
+	g.Go(func() error {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			return fmt.Errorf("failed to shut down server: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/northeastloon/flight_tracker/internal/config"
+)
+
+// runConfig prints the configuration the other commands would run with.
+func runConfig(ctx context.Context, args []string) error {
+	fs := newEnvFlags("config")
+	cf := config.BindFlags(fs.FlagSet)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: flight_tracker config [flags] print")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 || fs.Arg(0) != "print" {
+		fs.Usage()
+		return errors.New("config: expected print")
+	}
+
+	cfg, err := loadConfig(cf)
+	if err != nil {
+		return err
+	}
+
+	out, err := cfg.Redacted().YAML()
+	if err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	_, err = os.Stdout.Write(out)
+	return err
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/northeastloon/flight_tracker/internal/config"
+	"github.com/northeastloon/flight_tracker/internal/provider/openskytest"
+)
+
+// runFakeOpenSky serves a fake OpenSky states endpoint for development, from
+// a capture or, by default, the traffic simulator.
+func runFakeOpenSky(ctx context.Context, args []string) error {
+	fs := newEnvFlags("fake-opensky")
+	addr := fs.String("addr", "FAKE_OPENSKY_ADDR", "127.0.0.1:8081", "listen address")
+	capturePath := fs.String("capture", "FAKE_OPENSKY_CAPTURE", "", "serve the OpenSky responses of this capture file or directory instead of simulated traffic")
+	aircraft := fs.Int("aircraft", "SIMULATOR_AIRCRAFT", 100, "number of simulated aircraft")
+	seed := fs.Int("seed", "SIMULATOR_SEED", 1, "seed of the simulated traffic")
+	airports := fs.String("airports", "SIMULATOR_AIRPORTS", "", "airports to simulate, as for simulator.airports")
+	credits := fs.Int("credits", "FAKE_OPENSKY_CREDITS", -1, "API credits to hand out before answering 429; negative disables rate limiting")
+	retryAfter := fs.Int("retry-after", "FAKE_OPENSKY_RETRY_AFTER", 60, "seconds a rate-limited client is asked to wait")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: flight_tracker fake-opensky [flags]")
+		fmt.Fprintln(fs.Output(), "\nPoint the tracker at it with -opensky-url http://<addr>/api/states/all.")
+		fmt.Fprintln(fs.Output())
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return errors.New("fake-opensky: unexpected arguments")
+	}
+
+	var opts []openskytest.Option
+	if *capturePath != "" {
+		snapshots, err := openskytest.LoadCapture(*capturePath)
+		if err != nil {
+			return err
+		}
+		slog.Info("Serving captured snapshots", "path", *capturePath, "snapshots", len(snapshots))
+		opts = append(opts, openskytest.WithSnapshots(snapshots...))
+	} else {
+		sim, err := newSimulator(config.Simulator{Aircraft: *aircraft, Seed: *seed, Airports: *airports})
+		if err != nil {
+			return err
+		}
+		opts = append(opts, openskytest.WithProvider(sim))
+	}
+	if *credits >= 0 {
+		opts = append(opts, openskytest.WithRateLimit(*credits, time.Duration(*retryAfter)*time.Second))
+	}
+
+	l, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", *addr, err)
+	}
+	srv := openskytest.NewUnstartedServer(opts...)
+	srv.Listener.Close()
+	srv.Listener = l
+	srv.Start()
+	defer srv.Close()
+
+	slog.Info("Fake OpenSky listening", "url", srv.StatesURL())
+	<-ctx.Done()
+	return nil
+}
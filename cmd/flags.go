@@ -0,0 +1,100 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// envFlags wraps a flag.FlagSet so every flag can fall back to an environment
+// variable. Precedence is flag, then environment, then the default.
+type envFlags struct {
+	*flag.FlagSet
+	errs []error
+}
+
+func newEnvFlags(name string) *envFlags {
+	return &envFlags{FlagSet: flag.NewFlagSet(name, flag.ContinueOnError)}
+}
+
+func (f *envFlags) usage(usage, env string) string {
+	if env == "" {
+		return usage
+	}
+	return fmt.Sprintf("%s (env %s)", usage, env)
+}
+
+func (f *envFlags) envValue(env string) (string, bool) {
+	if env == "" {
+		return "", false
+	}
+	return os.LookupEnv(env)
+}
+
+func (f *envFlags) String(name, env, def, usage string) *string {
+	if v, ok := f.envValue(env); ok {
+		def = v
+	}
+	return f.FlagSet.String(name, def, f.usage(usage, env))
+}
+
+func (f *envFlags) Int(name, env string, def int, usage string) *int {
+	if v, ok := f.envValue(env); ok {
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			f.errs = append(f.errs, fmt.Errorf("invalid %s: %w", env, err))
+		} else {
+			def = i
+		}
+	}
+	return f.FlagSet.Int(name, def, f.usage(usage, env))
+}
+
+func (f *envFlags) Float64(name, env string, def float64, usage string) *float64 {
+	if v, ok := f.envValue(env); ok {
+		x, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			f.errs = append(f.errs, fmt.Errorf("invalid %s: %w", env, err))
+		} else {
+			def = x
+		}
+	}
+	return f.FlagSet.Float64(name, def, f.usage(usage, env))
+}
+
+func (f *envFlags) Bool(name, env string, def bool, usage string) *bool {
+	if v, ok := f.envValue(env); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			f.errs = append(f.errs, fmt.Errorf("invalid %s: %w", env, err))
+		} else {
+			def = b
+		}
+	}
+	return f.FlagSet.Bool(name, def, f.usage(usage, env))
+}
+
+func (f *envFlags) Duration(name, env string, def time.Duration, usage string) *time.Duration {
+	if v, ok := f.envValue(env); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			f.errs = append(f.errs, fmt.Errorf("invalid %s: %w", env, err))
+		} else {
+			def = d
+		}
+	}
+	return f.FlagSet.Duration(name, def, f.usage(usage, env))
+}
+
+// Parse parses args and reports the first invalid environment value.
+func (f *envFlags) Parse(args []string) error {
+	if err := f.FlagSet.Parse(args); err != nil {
+		return err
+	}
+	if len(f.errs) > 0 {
+		return f.errs[0]
+	}
+	return nil
+}
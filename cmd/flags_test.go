@@ -0,0 +1,100 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEnvFlags(t *testing.T) {
+	t.Setenv("TEST_NAME", "from-env")
+	t.Setenv("TEST_COUNT", "7")
+	t.Setenv("TEST_RATIO", "0.25")
+	t.Setenv("TEST_VERBOSE", "true")
+	t.Setenv("TEST_WAIT", "2m")
+
+	fs := newEnvFlags("test")
+	name := fs.String("name", "TEST_NAME", "default", "a name")
+	count := fs.Int("count", "TEST_COUNT", 1, "a count")
+	ratio := fs.Float64("ratio", "TEST_RATIO", 1, "a ratio")
+	verbose := fs.Bool("verbose", "TEST_VERBOSE", false, "verbose output")
+	wait := fs.Duration("wait", "TEST_WAIT", time.Second, "a wait")
+	plain := fs.String("plain", "", "default", "no environment variable")
+
+	// flags win over the environment, which wins over the defaults
+	if err := fs.Parse([]string{"-count", "9"}); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if *name != "from-env" || *count != 9 || *ratio != 0.25 || !*verbose || *wait != 2*time.Minute || *plain != "default" {
+		t.Errorf("got name %q, count %d, ratio %v, verbose %v, wait %v, plain %q",
+			*name, *count, *ratio, *verbose, *wait, *plain)
+	}
+
+	if usage := fs.Lookup("count").Usage; usage != "a count (env TEST_COUNT)" {
+		t.Errorf("usage = %q", usage)
+	}
+	if usage := fs.Lookup("plain").Usage; usage != "no environment variable" {
+		t.Errorf("usage without env = %q", usage)
+	}
+}
+
+func TestEnvFlagsRejectInvalidEnvironment(t *testing.T) {
+	t.Setenv("TEST_COUNT", "many")
+
+	fs := newEnvFlags("test")
+	count := fs.Int("count", "TEST_COUNT", 3, "a count")
+	err := fs.Parse(nil)
+	if err == nil || !strings.Contains(err.Error(), "TEST_COUNT") {
+		t.Fatalf("Parse = %v, want an error naming TEST_COUNT", err)
+	}
+	if *count != 3 {
+		t.Errorf("count = %d, want the default", *count)
+	}
+}
+
+// TestSubcommandFlagsHaveEnv checks that every flag a subcommand registers
+// through envFlags names its environment variable.
+func TestSubcommandFlagsHaveEnv(t *testing.T) {
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	for _, path := range files {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) != 4 {
+				return true
+			}
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			if recv, ok := sel.X.(*ast.Ident); !ok || recv.Name != "fs" {
+				return true
+			}
+			name, ok := call.Args[0].(*ast.BasicLit)
+			env, ok2 := call.Args[1].(*ast.BasicLit)
+			if !ok || !ok2 || name.Kind != token.STRING {
+				return true
+			}
+			if v, _ := strconv.Unquote(env.Value); v == "" {
+				flag, _ := strconv.Unquote(name.Value)
+				t.Errorf("%s: flag -%s has no environment variable", fset.Position(call.Pos()), flag)
+			}
+			return true
+		})
+	}
+}
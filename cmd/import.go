@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/northeastloon/flight_tracker/internal/config"
+	"github.com/northeastloon/flight_tracker/internal/domain"
+	storage "github.com/northeastloon/flight_tracker/internal/postgres"
+	"github.com/northeastloon/flight_tracker/internal/provider"
+)
+
+// importProgressEvery is how often import reports progress.
+const importProgressEvery = 2 * time.Second
+
+// runImport loads OpenSky historical state-vector dumps into the store.
+func runImport(ctx context.Context, args []string) error {
+	fs := newEnvFlags("import")
+	batch := fs.Int("batch", "IMPORT_BATCH", 20000, "rows per import transaction")
+	cf := config.BindFlags(fs.FlagSet)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: flight_tracker import [flags] FILE...")
+		fmt.Fprintln(fs.Output(), "\nFILE is an OpenSky states_YYYY-MM-DD-HH.csv.tar archive or the CSV inside it,")
+		fmt.Fprintln(fs.Output(), "optionally gzipped. Rows already stored are skipped, so imports can be repeated.")
+		fmt.Fprint(fs.Output(), "Imported rows are kept past the retention window that prunes live telemetry.\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("import: expected at least one file")
+	}
+	if *batch < 1 {
+		return errors.New("import: -batch must be at least 1")
+	}
+
+	cfg, cleanup, err := setup(ctx, cf)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	db, err := openDatabase(cfg.Database, storage.WithFlightTimeout(cfg.Ingest.FlightTimeout))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := requireMigrations(ctx, db); err != nil {
+		return err
+	}
+
+	var total importStats
+	for _, path := range fs.Args() {
+		stats, err := importFile(ctx, db, path, *batch)
+		total.add(stats)
+		if err != nil {
+			return fmt.Errorf("import %s: %w", path, err)
+		}
+	}
+
+	if fs.NArg() > 1 {
+		fmt.Fprintf(os.Stderr, "total: %s\n", total)
+	}
+	return nil
+}
+
+type importStats struct {
+	read     int
+	inserted int
+	rejected int
+	elapsed  time.Duration
+}
+
+func (s *importStats) add(o importStats) {
+	s.read += o.read
+	s.inserted += o.inserted
+	s.rejected += o.rejected
+	s.elapsed += o.elapsed
+}
+
+func (s importStats) String() string {
+	rate := 0.0
+	if s.elapsed > 0 {
+		rate = float64(s.read) / s.elapsed.Seconds()
+	}
+	return fmt.Sprintf("%d rows read, %d new, %d already stored, %d rejected in %s (%.0f rows/s)",
+		s.read, s.inserted, s.read-s.inserted, s.rejected, s.elapsed.Round(time.Second), rate)
+}
+
+// importFile streams one dump into the store in batches, reporting progress
+// by the share of the file read.
+func importFile(ctx context.Context, db *storage.Database, path string, batchSize int) (importStats, error) {
+	var stats importStats
+	start := time.Now()
+
+	f, err := os.Open(path)
+	if err != nil {
+		return stats, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return stats, err
+	}
+	cr := &countingReader{r: f}
+
+	lastReport := start
+	report := func(final bool) {
+		pct := 100.0
+		if size := info.Size(); size > 0 {
+			pct = 100 * float64(cr.n.Load()) / float64(size)
+		}
+		if final {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", path, stats)
+			return
+		}
+		fmt.Fprintf(os.Stderr, "%s: %5.1f%%  %d rows read, %d new\n", path, pct, stats.read, stats.inserted)
+	}
+
+	batch := make([]domain.Telemetry, 0, batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		domain.FillAddressCountries(batch)
+		n, err := db.ImportTelemetry(ctx, batch)
+		if err != nil {
+			return err
+		}
+		stats.inserted += n
+		batch = batch[:0]
+
+		if now := time.Now(); now.Sub(lastReport) >= importProgressEvery {
+			lastReport = now
+			report(false)
+		}
+		return nil
+	}
+
+	rejected, err := provider.ReadOpenSkyHistory(cr, func(t domain.Telemetry) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		stats.read++
+		batch = append(batch, t)
+		if len(batch) < batchSize {
+			return nil
+		}
+		return flush()
+	})
+	stats.rejected = rejected
+	if err == nil {
+		err = flush()
+	}
+	stats.elapsed = time.Since(start)
+	if err != nil {
+		return stats, err
+	}
+
+	report(true)
+	return stats, nil
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
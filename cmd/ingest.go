@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/northeastloon/flight_tracker/internal/capture"
+	"github.com/northeastloon/flight_tracker/internal/config"
+	"github.com/northeastloon/flight_tracker/internal/domain"
+	storage "github.com/northeastloon/flight_tracker/internal/postgres"
+	"github.com/northeastloon/flight_tracker/internal/provider"
+	"github.com/northeastloon/flight_tracker/internal/spill"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"golang.org/x/sync/errgroup"
+)
+
+// newFeeds builds the configured providers, fused when there is more
+// than one, recording their responses when capture is enabled. The returned
+// function closes the capture file.
+func newFeeds(cfg config.Config) (domain.FlightDataProvider, func(), error) {
+	var clientOpts []provider.Option
+	closeCapture := func() {}
+	if cfg.Capture.Dir != "" {
+		rec, err := capture.NewWriter(cfg.Capture.Dir,
+			capture.WithMaxBytes(int64(cfg.Capture.MaxSizeMB)<<20),
+			capture.WithMaxAge(cfg.Capture.MaxAge),
+			capture.WithMaxFiles(cfg.Capture.MaxFiles),
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		slog.Info("Recording provider responses", "dir", cfg.Capture.Dir)
+		clientOpts = append(clientOpts, provider.WithRecorder(rec))
+		closeCapture = func() {
+			if err := rec.Close(); err != nil {
+				slog.Error("failed to close capture file", slog.Any("err", err))
+			}
+		}
+	}
+
+	var feeds []domain.Feed
+	if cfg.OpenSky.Enabled {
+		opts := append([]provider.Option{
+			provider.WithBaseURL(cfg.OpenSky.BaseURL),
+			provider.WithQueryParam("extended", strconv.FormatBool(cfg.OpenSky.Extended)),
+		}, clientOpts...)
+		feeds = append(feeds, domain.Feed{Name: "opensky", Provider: provider.NewOpenSkyClient(opts...)})
+	}
+	readsbFeeds, err := cfg.Readsb.List()
+	if err != nil {
+		closeCapture()
+		return nil, nil, err
+	}
+	for _, f := range readsbFeeds {
+		feeds = append(feeds, domain.Feed{Name: f.Name, Provider: provider.NewReadsbClient(f.Name, f.URL, clientOpts...)})
+	}
+	if cfg.Simulator.Enabled {
+		sim, err := newSimulator(cfg.Simulator)
+		if err != nil {
+			closeCapture()
+			return nil, nil, err
+		}
+		feeds = append(feeds, domain.Feed{Name: "simulator", Provider: sim})
+	}
+
+	// a single feed needs no fusion
+	if len(feeds) == 1 {
+		return feeds[0].Provider, closeCapture, nil
+	}
+	return domain.NewFusedProvider(feeds...), closeCapture, nil
+}
+
+// newSimulator builds the synthetic traffic provider.
+func newSimulator(cfg config.Simulator) (*provider.SimulatorProvider, error) {
+	airports, err := provider.ParseSimAirports(cfg.Airports)
+	if err != nil {
+		return nil, fmt.Errorf("simulator.airports: %w", err)
+	}
+
+	sim, err := provider.NewSimulatorProvider(
+		provider.WithSimulatorSeed(uint64(cfg.Seed)),
+		provider.WithSimulatorAircraft(cfg.Aircraft),
+		provider.WithSimulatorAirports(airports),
+	)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("Simulating traffic", "aircraft", cfg.Aircraft, "airports", len(airports), "seed", cfg.Seed)
+	return sim, nil
+}
+
+// newIngestService builds the ingestion service shared by the ingest and all
+// subcommands around fetcher. The returned function closes the spill file.
+func newIngestService(cfg config.Config, db *storage.Database, fetcher domain.FlightDataProvider, extra ...domain.ServiceOption) (*domain.FlightDataService, func(), error) {
+	opts := []domain.ServiceOption{
+		domain.WithShutdownGrace(cfg.Ingest.ShutdownGrace),
+		domain.WithQueue(cfg.Ingest.QueueSize, cfg.Ingest.StoreWorkers),
+	}
+	if ttl := cfg.Ingest.LeaseTTL; ttl > 0 {
+		lease := db.NewLease(storage.IngestLeaseName, cfg.Ingest.Replica(), ttl)
+		opts = append(opts, domain.WithLeaderLease(lease, ttl))
+	}
+
+	closeSpill := func() {}
+	if cfg.Ingest.SpillPath != "" {
+		buf, err := spill.Open[[]domain.Telemetry](cfg.Ingest.SpillPath,
+			spill.WithMaxBytes(int64(cfg.Ingest.SpillMaxMB)<<20),
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		opts = append(opts, domain.WithSpill(buf))
+		closeSpill = func() {
+			if err := buf.Close(); err != nil {
+				slog.Error("failed to close spill file", slog.Any("err", err))
+			}
+		}
+	}
+
+	opts = append(opts, extra...)
+
+	return domain.NewFlightDataService(fetcher, db, opts...), closeSpill, nil
+}
+
+// runIngest runs the ingestion worker without the API, from the live feeds
+// or, with -replay, from recorded responses.
+func runIngest(ctx context.Context, args []string) error {
+	fs := newEnvFlags("ingest")
+	once := fs.Bool("once", "INGEST_ONCE", false, "run a single ingest cycle and exit")
+	replay := fs.String("replay", "INGEST_REPLAY", "", "replay the capture file or directory instead of fetching live")
+	speed := fs.Float64("replay-speed", "INGEST_REPLAY_SPEED", 1, "replay speed as a multiple of real time; 0 replays as fast as possible")
+	shift := fs.Bool("replay-shift", "INGEST_REPLAY_SHIFT", false, "shift replayed timestamps so the capture appears to start now")
+	cf := config.BindFlags(fs.FlagSet)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, cleanup, err := setup(ctx, cf)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	db, err := openDatabase(cfg.Database, storage.WithFlightTimeout(cfg.Ingest.FlightTimeout))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := requireMigrations(ctx, db); err != nil {
+		return err
+	}
+
+	var fetcher domain.FlightDataProvider
+	var opts []domain.ServiceOption
+	if *replay != "" {
+		rp, err := newReplay(*replay, *speed, *shift)
+		if err != nil {
+			return err
+		}
+
+		// stop once the capture runs out; queued snapshots are still stored
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		go func() {
+			select {
+			case <-rp.Done():
+				slog.Info("Replay finished")
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		// A replay waits for the store rather than spilling: one worker keeps
+		// snapshots in capture order, and the live spill file is left alone.
+		fetcher = rp
+		cfg.Ingest.SpillPath = ""
+		opts = append(opts,
+			domain.WithClock(rp),
+			domain.WithQueue(cfg.Ingest.QueueSize, 1),
+			domain.WithBackpressure(),
+		)
+	} else {
+		feeds, closeFeeds, err := newFeeds(cfg)
+		if err != nil {
+			return err
+		}
+		defer closeFeeds()
+		fetcher = feeds
+	}
+
+	fds, closeSpill, err := newIngestService(cfg, db, fetcher, opts...)
+	if err != nil {
+		return err
+	}
+	defer closeSpill()
+
+	if *once {
+		if err := fds.IngestData(ctx); err != nil {
+			return fmt.Errorf("ingest failed: %w", err)
+		}
+		return nil
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+
+	g.Go(func() error {
+		if err := fds.StartIngestionLoop(gctx, cfg.Ingest.Schedule()); err != nil {
+			return fmt.Errorf("ingestion loop failed: %w", err)
+		}
+		return nil
+	})
+
+	if cfg.Ingest.MetricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.Handler())
+		mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})
+		srv := &http.Server{Addr: cfg.Ingest.MetricsAddr, Handler: mux}
+		serveHTTP(gctx, g, srv, srv.ListenAndServe)
+	}
+
+	return g.Wait()
+}
+
+// newReplay opens a capture for replay.
+func newReplay(path string, speed float64, shift bool) (*provider.ReplayProvider, error) {
+	reader, err := capture.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []provider.ReplayOption{provider.WithReplaySpeed(speed)}
+	if shift {
+		opts = append(opts, provider.WithTimeShift())
+	}
+
+	rp, err := provider.NewReplayProvider(reader, opts...)
+	if err != nil {
+		reader.Close()
+		return nil, fmt.Errorf("failed to start replay: %w", err)
+	}
+	slog.Info("Replaying capture", "path", path, "speed", speed)
+	return rp, nil
+}
@@ -2,62 +2,139 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
 	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
-	"github.com/joho/godotenv"
-	"github.com/northeastloon/flight_tracker/internal/domain"
+	"github.com/northeastloon/flight_tracker/internal/config"
 	storage "github.com/northeastloon/flight_tracker/internal/postgres"
-	"github.com/northeastloon/flight_tracker/internal/provider"
 	"github.com/northeastloon/flight_tracker/internal/server"
+	"golang.org/x/sync/errgroup"
 )
 
-func Run() error {
+// shutdownTimeout bounds how long HTTP draining and trace flushing may take
+// once a shutdown has started.
+const shutdownTimeout = 15 * time.Second
 
-	// load environmental vars
-	if err := godotenv.Load(); err != nil {
-		log.Printf("Warning: .env file not found")
+type command struct {
+	name  string
+	usage string
+	run   func(ctx context.Context, args []string) error
+}
+
+var commands = []command{
+	{"all", "migrate, ingest and serve in one process (default)", runAll},
+	{"serve", "serve the API and web UI only", runServe},
+	{"ingest", "run the ingestion worker only (-once for a single cycle, -replay to replay a capture)", runIngest},
+	{"migrate", "apply, revert or list schema migrations", runMigrate},
+	{"query", "print telemetry matching filter flags", runQuery},
+	{"export", "write telemetry matching filter flags to a file", runExport},
+	{"import", "load OpenSky historical state-vector dumps", runImport},
+	{"airports", "load the OurAirports airport and runway CSVs", runAirports},
+	{"airlines", "load the OpenFlights airline table used to decode callsigns", runAirlines},
+	{"registry", "load or periodically reload the OpenSky aircraft database", runRegistry},
+	{"fake-opensky", "serve a fake OpenSky API from simulated or captured traffic", runFakeOpenSky},
+	{"config", "print the effective configuration with secrets redacted", runConfig},
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: flight_tracker <command> [flags]\n\ncommands:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", c.name, c.usage)
 	}
+	fmt.Fprintln(os.Stderr, "\nrun `flight_tracker <command> -h` for command flags")
+}
+
+func Run(args []string) error {
+
+	// stop on SIGINT/SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	name := "all"
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+
+	for _, c := range commands {
+		if c.name == name {
+			return c.run(ctx, args)
+		}
+	}
+
+	usage()
+	return fmt.Errorf("unknown command %q", name)
+}
 
-	// initialise db
-	db, err := storage.NewDatabase()
+// runAll migrates, ingests and serves in a single process.
+func runAll(ctx context.Context, args []string) error {
+	fs := newEnvFlags("all")
+	cf := config.BindFlags(fs.FlagSet)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, cleanup, err := setup(ctx, cf)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	db, err := openDatabase(cfg.Database, storage.WithFlightTimeout(cfg.Ingest.FlightTimeout))
 	if err != nil {
-		return fmt.Errorf("failed to connect to the database: %w", err)
+		return err
 	}
+	defer db.Close()
 
 	if err := db.MigrateDB(); err != nil {
 		return fmt.Errorf("failed to migrate database: %w", err)
 	}
 
-	//initialise fetcher(s)
-	OpenSkyClient := provider.NewOpenSkyClient(provider.WithQueryParam("extended", "true"))
-
-	//start ingest service
-	ctx := context.Background()
-	fds := domain.NewFlightDataService(OpenSkyClient, db)
+	feeds, closeFeeds, err := newFeeds(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeFeeds()
 
-	go fds.StartIngestionLoop(ctx, 1000)
+	fds, closeSpill, err := newIngestService(cfg, db, feeds)
+	if err != nil {
+		return err
+	}
+	defer closeSpill()
 
-	//initialise server
-	server, err := server.NewServer(db)
+	srv, err := newServer(cfg, db, server.WithIngestStatus(fds))
 	if err != nil {
 		return fmt.Errorf("failed to initialise server: %w", err)
 	}
 
-	if err := server.Echo.Start(":8080"); err != nil {
-		return fmt.Errorf("failed to start server: %w", err)
-	}
+	// run components; the first one to fail shuts down the others
+	g, gctx := errgroup.WithContext(ctx)
+
+	g.Go(func() error {
+		if err := fds.StartIngestionLoop(gctx, cfg.Ingest.Schedule()); err != nil {
+			return fmt.Errorf("ingestion loop failed: %w", err)
+		}
+		return nil
+	})
 
-	return nil
+	serveHTTP(gctx, g, srv.Echo, func() error { return srv.Echo.Start(cfg.HTTP.Addr) })
 
+	return g.Wait()
 }
 
 func main() {
 
-	if err := Run(); err != nil {
+	if err := Run(os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
 		slog.Error("run error", slog.Any("err", err))
-		log.Panic()
+		os.Exit(1)
 	}
 
 }
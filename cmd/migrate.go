@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/northeastloon/flight_tracker/internal/config"
+)
+
+// runMigrate applies, reverts or lists schema migrations.
+func runMigrate(ctx context.Context, args []string) error {
+	fs := newEnvFlags("migrate")
+	steps := fs.Int("steps", "MIGRATE_STEPS", 1, "number of migrations to revert with down")
+	cf := config.BindFlags(fs.FlagSet)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: flight_tracker migrate [-steps N] up|down|status")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("migrate: expected exactly one of up, down or status")
+	}
+
+	cfg, cleanup, err := setup(ctx, cf)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	db, err := openDatabase(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch fs.Arg(0) {
+	case "up":
+		return db.MigrateUp(ctx)
+	case "down":
+		if *steps < 1 {
+			return errors.New("migrate: -steps must be at least 1")
+		}
+		return db.MigrateDown(ctx, *steps)
+	case "status":
+		states, err := db.MigrationStatus(ctx)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range states {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, applied)
+		}
+		return w.Flush()
+	default:
+		fs.Usage()
+		return fmt.Errorf("migrate: unknown action %q", fs.Arg(0))
+	}
+}
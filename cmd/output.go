@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/northeastloon/flight_tracker/internal/domain"
+	"github.com/northeastloon/flight_tracker/internal/server"
+)
+
+// Output formats for query and export.
+const (
+	formatTable  = "table"
+	formatJSON   = "json"
+	formatNDJSON = "ndjson"
+	formatCSV    = "csv"
+)
+
+// responseRecords converts telemetry into the same v1 records the API
+// returns, so CLI output and API responses never disagree.
+func responseRecords(telemetry []domain.Telemetry, units string) []any {
+	records := make([]any, 0, len(telemetry))
+	for _, t := range telemetry {
+		if units == server.UnitsAviation {
+			records = append(records, server.NewTelemetryAviationV1(t))
+		} else {
+			records = append(records, server.NewTelemetryV1(t))
+		}
+	}
+	return records
+}
+
+func writeTelemetry(w io.Writer, format string, telemetry []domain.Telemetry, units string) error {
+	switch format {
+	case formatTable:
+		return writeTable(w, telemetry, units)
+	case formatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(responseRecords(telemetry, units))
+	case formatNDJSON:
+		enc := json.NewEncoder(w)
+		for _, r := range responseRecords(telemetry, units) {
+			if err := enc.Encode(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	case formatCSV:
+		return writeCSV(w, responseRecords(telemetry, units))
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+}
+
+func writeTable(w io.Writer, telemetry []domain.Telemetry, units string) error {
+	altUnit, speedUnit := "m", "m/s"
+	altScale, speedScale := 1.0, 1.0
+	if units == server.UnitsAviation {
+		altUnit, speedUnit = "ft", "kt"
+		altScale, speedScale = domain.MetresToFeet, domain.MpsToKnots
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "ICAO24\tSOURCE\tCALLSIGN\tCOUNTRY\tLAST CONTACT\tLAT\tLON\tALT (%s)\tSPEED (%s)\tTRACK\tSQUAWK\n", altUnit, speedUnit)
+	for _, t := range telemetry {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			t.ICAO24,
+			t.Source,
+			strings.TrimSpace(deref(t.Callsign)),
+			t.OriginCountry,
+			t.LastContact.UTC().Format("2006-01-02 15:04:05"),
+			formatFloat(t.Latitude, 4, 1),
+			formatFloat(t.Longitude, 4, 1),
+			formatFloat(t.BaroAltitude, 0, altScale),
+			formatFloat(t.Velocity, 0, speedScale),
+			formatFloat(t.TrueTrack, 0, 1),
+			deref(t.Squawk),
+		)
+	}
+	return tw.Flush()
+}
+
+// writeCSV writes records with one column per JSON field, in declaration
+// order, so the header matches the API field names.
+func writeCSV(w io.Writer, records []any) error {
+	cw := csv.NewWriter(w)
+	for i, r := range records {
+		names, values := csvFields(reflect.ValueOf(r))
+		if i == 0 {
+			if err := cw.Write(names); err != nil {
+				return err
+			}
+		}
+		if err := cw.Write(values); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func csvFields(v reflect.Value) (names, values []string) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			n, vals := csvFields(v.Field(i))
+			names = append(names, n...)
+			values = append(values, vals...)
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		names = append(names, name)
+		values = append(values, csvValue(v.Field(i)))
+	}
+	return names, values
+}
+
+func csvValue(v reflect.Value) string {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	case reflect.Map:
+		if v.IsNil() {
+			return ""
+		}
+		b, err := json.Marshal(v.Interface())
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	case reflect.Slice:
+		parts := make([]string, v.Len())
+		for i := range parts {
+			parts[i] = csvValue(v.Index(i))
+		}
+		return strings.Join(parts, ";")
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func formatFloat(f *float64, digits int, scale float64) string {
+	if f == nil {
+		return "-"
+	}
+	return strconv.FormatFloat(*f*scale, 'f', digits, 64)
+}
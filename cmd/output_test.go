@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/northeastloon/flight_tracker/internal/domain"
+	"github.com/northeastloon/flight_tracker/internal/server"
+)
+
+func outputTelemetry() []domain.Telemetry {
+	callsign, alt, speed := "EIN12", 1000.0, 100.0
+	return []domain.Telemetry{{
+		Source:        "opensky",
+		Sources:       []string{"opensky", "readsb"},
+		ICAO24:        "4ca7b4",
+		Callsign:      &callsign,
+		OriginCountry: "Ireland",
+		LastContact:   time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+		BaroAltitude:  &alt,
+		Velocity:      &speed,
+		Extras:        map[string]any{"alert": true},
+	}}
+}
+
+func TestWriteCSV(t *testing.T) {
+	for _, units := range []string{server.UnitsMetric, server.UnitsAviation} {
+		var buf bytes.Buffer
+		if err := writeTelemetry(&buf, formatCSV, outputTelemetry(), units); err != nil {
+			t.Fatalf("%s: writeTelemetry: %v", units, err)
+		}
+		rows, err := csv.NewReader(&buf).ReadAll()
+		if err != nil {
+			t.Fatalf("%s: output is not CSV: %v", units, err)
+		}
+		if len(rows) != 2 {
+			t.Fatalf("%s: got %d rows, want a header and one record", units, len(rows))
+		}
+
+		record := map[string]string{}
+		for i, name := range rows[0] {
+			record[name] = rows[1][i]
+		}
+		want := map[string]string{
+			"icao24":    "4ca7b4",
+			"sources":   "opensky;readsb",
+			"callsign":  "EIN12",
+			"latitude":  "",
+			"extras":    `{"alert":true}`,
+			"on_ground": "false",
+		}
+		if units == server.UnitsAviation {
+			want["baro_altitude_ft"], want["velocity_kt"] = "3280.8398950131236", "194.38444924406048"
+		} else {
+			want["baro_altitude_m"], want["velocity_mps"] = "1000", "100"
+		}
+		for name, v := range want {
+			if got, ok := record[name]; !ok || got != v {
+				t.Errorf("%s: column %s = %q (present %v), want %q", units, name, got, ok, v)
+			}
+		}
+	}
+}
+
+func TestWriteTableConvertsUnits(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeTelemetry(&buf, formatTable, outputTelemetry(), server.UnitsAviation); err != nil {
+		t.Fatalf("writeTelemetry: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "ALT (ft)") || !strings.Contains(lines[0], "SPEED (kt)") {
+		t.Fatalf("table = %q", buf.String())
+	}
+	if !strings.Contains(lines[1], " 3281 ") || !strings.Contains(lines[1], " 194 ") {
+		t.Errorf("row = %q, want 3281 ft and 194 kt", lines[1])
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/northeastloon/flight_tracker/internal/config"
+	"github.com/northeastloon/flight_tracker/internal/domain"
+	"github.com/northeastloon/flight_tracker/internal/server"
+)
+
+// filterFlags mirror the /api/v1/telemetry query parameters. They are passed
+// through server.ParseTelemetryQuery so the CLI validates exactly like the API.
+type filterFlags struct {
+	values map[string]*string
+}
+
+var filterParams = []struct{ name, usage string }{
+	{"source", "feed the state was taken from, e.g. opensky"},
+	{"icao24", "ICAO 24-bit address (6 hex digits)"},
+	{"callsign", "exact callsign"},
+	{"origin_country", "exact origin country"},
+	{"squawk", "transponder code (4 octal digits)"},
+	{"category", "aircraft category (0-20)"},
+	{"typecode", "ICAO aircraft type designator from the registry, e.g. A388"},
+	{"operator", "ICAO operator designator from the registry, e.g. BAW"},
+	{"airline", "ICAO airline designator of the callsign, e.g. BAW"},
+	{"lat", "latitude of the search centre"},
+	{"lon", "longitude of the search centre"},
+	{"radius_km", "search radius in kilometres"},
+	{"from", "earliest last_contact (RFC 3339)"},
+	{"to", "latest last_contact (RFC 3339)"},
+	{"latest", "only the newest row per aircraft (true/false)"},
+	{"units", "metric or aviation"},
+}
+
+func addFilterFlags(fs *envFlags) filterFlags {
+	f := filterFlags{values: make(map[string]*string)}
+	for _, p := range filterParams {
+		f.values[p.name] = fs.FlagSet.String(strings.ReplaceAll(p.name, "_", "-"), "", p.usage)
+	}
+	return f
+}
+
+func (f filterFlags) parse() (*server.TelemetryQuery, error) {
+	values := url.Values{}
+	for name, v := range f.values {
+		if *v != "" {
+			values.Set(name, *v)
+		}
+	}
+
+	q, err := server.ParseTelemetryQuery(values)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter: %w", err)
+	}
+	return q, nil
+}
+
+// runQuery prints telemetry matching the filter flags.
+func runQuery(ctx context.Context, args []string) error {
+	fs := newEnvFlags("query")
+	format := fs.String("format", "QUERY_FORMAT", formatTable, "output format: table, json or csv")
+	filter := addFilterFlags(fs)
+	cf := config.BindFlags(fs.FlagSet)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	switch *format {
+	case formatTable, formatJSON, formatCSV:
+	default:
+		return fmt.Errorf("query: unknown format %q", *format)
+	}
+
+	q, err := filter.parse()
+	if err != nil {
+		return err
+	}
+
+	telemetry, err := fetchTelemetry(ctx, cf, q)
+	if err != nil {
+		return err
+	}
+
+	return writeTelemetry(os.Stdout, *format, telemetry, q.Units)
+}
+
+// runExport writes telemetry matching the filter flags to a file, gzipped
+// when the name ends in .gz.
+func runExport(ctx context.Context, args []string) error {
+	fs := newEnvFlags("export")
+	format := fs.String("format", "EXPORT_FORMAT", formatNDJSON, "output format: ndjson or csv")
+	out := fs.String("out", "EXPORT_OUT", "-", "output file, - for stdout")
+	filter := addFilterFlags(fs)
+	cf := config.BindFlags(fs.FlagSet)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	switch *format {
+	case formatNDJSON, formatCSV:
+	default:
+		return fmt.Errorf("export: unknown format %q", *format)
+	}
+
+	q, err := filter.parse()
+	if err != nil {
+		return err
+	}
+
+	telemetry, err := fetchTelemetry(ctx, cf, q)
+	if err != nil {
+		return err
+	}
+
+	if err := writeExport(*out, *format, telemetry, q.Units); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "exported %d rows\n", len(telemetry))
+	return nil
+}
+
+func fetchTelemetry(ctx context.Context, cf *config.Flags, q *server.TelemetryQuery) ([]domain.Telemetry, error) {
+	cfg, cleanup, err := setup(ctx, cf)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	db, err := openDatabase(cfg.Database)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	return db.GetTelemetry(ctx, q.Filter)
+}
+
+func writeExport(path, format string, telemetry []domain.Telemetry, units string) (err error) {
+	var w io.Writer = os.Stdout
+	if path != "-" {
+		file, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", path, err)
+		}
+		defer func() {
+			if cerr := file.Close(); err == nil {
+				err = cerr
+			}
+		}()
+		w = file
+	}
+
+	buf := bufio.NewWriter(w)
+	defer func() {
+		if ferr := buf.Flush(); err == nil {
+			err = ferr
+		}
+	}()
+	w = buf
+
+	if strings.HasSuffix(path, ".gz") {
+		gz := gzip.NewWriter(buf)
+		defer func() {
+			if cerr := gz.Close(); err == nil {
+				err = cerr
+			}
+		}()
+		w = gz
+	}
+
+	return writeTelemetry(w, format, telemetry, units)
+}
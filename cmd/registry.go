@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/northeastloon/flight_tracker/internal/config"
+	storage "github.com/northeastloon/flight_tracker/internal/postgres"
+	"github.com/northeastloon/flight_tracker/internal/provider"
+)
+
+// registryDownloadTimeout bounds a single download of the aircraft database.
+const registryDownloadTimeout = 10 * time.Minute
+
+// runRegistry loads the OpenSky aircraft database into the aircraft registry,
+// once or periodically.
+func runRegistry(ctx context.Context, args []string) error {
+	fs := newEnvFlags("registry")
+	dryRun := fs.Bool("dry-run", "REGISTRY_DRY_RUN", false, "report the changes without applying them")
+	every := fs.Duration("every", "REGISTRY_EVERY", 0, "reload at this interval instead of once, e.g. 24h")
+	cf := config.BindFlags(fs.FlagSet)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: flight_tracker registry [-dry-run] [-every DURATION] FILE|URL")
+		fmt.Fprintln(fs.Output(), "\nBrings the aircraft registry in line with an OpenSky aircraftDatabase.csv")
+		fmt.Fprintln(fs.Output(), "(https://opensky-network.org/datasets/metadata/), optionally gzipped, and")
+		fmt.Fprintln(fs.Output(), "reports how many aircraft were added, changed and removed. A URL is downloaded")
+		fmt.Fprint(fs.Output(), "again on every reload.\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("registry: expected exactly one file or URL")
+	}
+	if *every < 0 || (*every > 0 && *every < time.Minute) {
+		return errors.New("registry: -every must be at least 1m")
+	}
+
+	cfg, cleanup, err := setup(ctx, cf)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	db, err := openDatabase(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := requireMigrations(ctx, db); err != nil {
+		return err
+	}
+
+	if *every == 0 {
+		return reloadRegistry(ctx, db, fs.Arg(0), !*dryRun)
+	}
+
+	ticker := time.NewTicker(*every)
+	defer ticker.Stop()
+	for {
+		// a failed reload keeps the previous registry; try again next time
+		if err := reloadRegistry(ctx, db, fs.Arg(0), !*dryRun); err != nil {
+			slog.Error("registry reload failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func reloadRegistry(ctx context.Context, db *storage.Database, source string, apply bool) error {
+	start := time.Now()
+
+	r, err := openRegistrySource(ctx, source)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	aircraft, rejected, err := provider.ReadAircraftDatabase(r)
+	if err != nil {
+		return fmt.Errorf("registry %s: %w", source, err)
+	}
+
+	diff, err := db.ReloadAircraft(ctx, aircraft, apply)
+	if err != nil {
+		return err
+	}
+
+	verb := "applied"
+	if !apply {
+		verb = "not applied (dry run)"
+	}
+	fmt.Fprintf(os.Stderr, "%d aircraft read, %d rows rejected: %d added, %d changed, %d removed, %d unchanged, %s in %s\n",
+		len(aircraft), rejected, diff.Added, diff.Changed, diff.Removed, diff.Unchanged, verb,
+		time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// openRegistrySource opens a local file or downloads an http(s) URL.
+func openRegistrySource(ctx context.Context, source string) (io.ReadCloser, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return os.Open(source)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, registryDownloadTimeout)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to create registry request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to download registry: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("failed to download registry: %s", resp.Status)
+	}
+	return cancelOnClose{resp.Body, cancel}, nil
+}
+
+// cancelOnClose releases a download's context along with its body.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/northeastloon/flight_tracker/internal/config"
+	storage "github.com/northeastloon/flight_tracker/internal/postgres"
+	"github.com/northeastloon/flight_tracker/internal/server"
+	"golang.org/x/sync/errgroup"
+)
+
+// runServe serves the API and web UI without ingesting.
+func runServe(ctx context.Context, args []string) error {
+	fs := newEnvFlags("serve")
+	cf := config.BindFlags(fs.FlagSet)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, cleanup, err := setup(ctx, cf)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	db, err := openDatabase(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	srv, err := newServer(cfg, db)
+	if err != nil {
+		return fmt.Errorf("failed to initialise server: %w", err)
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	serveHTTP(gctx, g, srv.Echo, func() error { return srv.Echo.Start(cfg.HTTP.Addr) })
+
+	return g.Wait()
+}
+
+// newServer builds the API server from the HTTP settings.
+func newServer(cfg config.Config, db *storage.Database, opts ...server.Option) (*server.Server, error) {
+	opts = append([]server.Option{
+		server.WithStaleAfter(cfg.HTTP.StaleAfter),
+		server.WithRequestTimeout(cfg.HTTP.RequestTimeout),
+		server.WithReplicaID(cfg.Ingest.Replica()),
+	}, opts...)
+	return server.NewServer(db, opts...)
+}
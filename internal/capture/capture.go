@@ -0,0 +1,307 @@
+// Package capture records raw provider responses to rotating gzip files and
+// reads them back, so ingestion can be replayed without a network.
+package capture
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults for rotating capture files.
+const (
+	DefaultMaxBytes = 64 << 20
+	DefaultMaxAge   = time.Hour
+	DefaultMaxFiles = 168
+
+	fileSuffix = ".ndjson.gz"
+)
+
+// Record is one provider response as it came off the wire.
+type Record struct {
+	Time   time.Time       `json:"time"`   // when the fetch started
+	Source string          `json:"source"` // feed name, e.g. "opensky"
+	Format string          `json:"format"` // response format, which selects the parser on replay
+	Status int             `json:"status"`
+	Body   json.RawMessage `json:"body,omitempty"` // the response when it is JSON
+	Text   string          `json:"text,omitempty"` // the response when it is not
+}
+
+// NewRecord builds a record, keeping body as raw JSON when it is valid so the
+// capture stays readable and compresses well.
+func NewRecord(at time.Time, source, format string, status int, body []byte) Record {
+	r := Record{Time: at.UTC(), Source: source, Format: format, Status: status}
+	if json.Valid(body) {
+		r.Body = json.RawMessage(body)
+	} else {
+		r.Text = string(body)
+	}
+	return r
+}
+
+// Bytes returns the response body.
+func (r Record) Bytes() []byte {
+	if r.Body != nil {
+		return r.Body
+	}
+	return []byte(r.Text)
+}
+
+// Writer appends records to gzip-compressed JSON-lines files in a directory,
+// starting a new file when the current one reaches its size or age limit and
+// deleting the oldest files beyond the retention cap.
+// Each record is flushed as it is written, so a file cut short by a crash is
+// readable up to its last complete record.
+type Writer struct {
+	mu       sync.Mutex
+	dir      string
+	maxBytes int64
+	maxAge   time.Duration
+	maxFiles int
+
+	f       *os.File
+	gz      *gzip.Writer
+	written int64 // uncompressed bytes in the current file
+	opened  time.Time
+}
+
+type Option func(w *Writer)
+
+// WithMaxBytes starts a new file once the current one holds n uncompressed
+// bytes.
+func WithMaxBytes(n int64) Option {
+	return func(w *Writer) {
+		w.maxBytes = n
+	}
+}
+
+// WithMaxAge starts a new file once the current one is older than d.
+func WithMaxAge(d time.Duration) Option {
+	return func(w *Writer) {
+		w.maxAge = d
+	}
+}
+
+// WithMaxFiles keeps at most n capture files in the directory, deleting the
+// oldest when a new one is started. 0 keeps every file.
+func WithMaxFiles(n int) Option {
+	return func(w *Writer) {
+		w.maxFiles = n
+	}
+}
+
+// NewWriter creates dir if needed. Files are opened lazily on the first
+// record.
+func NewWriter(dir string, opts ...Option) (*Writer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create capture directory: %w", err)
+	}
+
+	w := &Writer{dir: dir, maxBytes: DefaultMaxBytes, maxAge: DefaultMaxAge, maxFiles: DefaultMaxFiles}
+	for _, o := range opts {
+		o(w)
+	}
+	return w, nil
+}
+
+// Write appends rec, rotating first if the current file is full or too old.
+func (w *Writer) Write(rec Record) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to encode capture record: %w", err)
+	}
+	line = append(line, '\n')
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.f != nil && (w.written >= w.maxBytes || time.Since(w.opened) >= w.maxAge) {
+		if err := w.closeFile(); err != nil {
+			return err
+		}
+	}
+	if w.f == nil {
+		if err := w.openFile(); err != nil {
+			return err
+		}
+	}
+
+	if _, err := w.gz.Write(line); err != nil {
+		return fmt.Errorf("failed to write capture record: %w", err)
+	}
+	if err := w.gz.Flush(); err != nil {
+		return fmt.Errorf("failed to flush capture file: %w", err)
+	}
+	w.written += int64(len(line))
+
+	return nil
+}
+
+func (w *Writer) openFile() error {
+	now := time.Now().UTC()
+	name := "capture-" + now.Format("20060102T150405.000Z") + fileSuffix
+
+	f, err := os.OpenFile(filepath.Join(w.dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create capture file: %w", err)
+	}
+
+	w.f = f
+	w.gz = gzip.NewWriter(f)
+	w.written = 0
+	w.opened = now
+	return w.prune()
+}
+
+// prune deletes the oldest capture files beyond the retention cap. Names
+// sort in the order the files were written, and the current file is always
+// the newest.
+func (w *Writer) prune() error {
+	if w.maxFiles <= 0 {
+		return nil
+	}
+	files, err := filepath.Glob(filepath.Join(w.dir, "*"+fileSuffix))
+	if err != nil {
+		return fmt.Errorf("failed to list captures: %w", err)
+	}
+	slices.Sort(files)
+	for len(files) > w.maxFiles {
+		if err := os.Remove(files[0]); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to delete old capture file: %w", err)
+		}
+		files = files[1:]
+	}
+	return nil
+}
+
+func (w *Writer) closeFile() error {
+	if w.f == nil {
+		return nil
+	}
+	err := errors.Join(w.gz.Close(), w.f.Close())
+	w.f, w.gz = nil, nil
+	if err != nil {
+		return fmt.Errorf("failed to close capture file: %w", err)
+	}
+	return nil
+}
+
+// Close finishes the current file.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.closeFile()
+}
+
+// Reader reads records from capture files in order.
+type Reader struct {
+	files []string
+
+	f   *os.File
+	gz  *gzip.Reader
+	buf *bufio.Reader
+}
+
+// Open reads the capture files at paths. A directory stands for every
+// capture file in it; files are read in name order, which is the order they
+// were written in.
+func Open(paths ...string) (*Reader, error) {
+	var files []string
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open capture: %w", err)
+		}
+		if !info.IsDir() {
+			files = append(files, p)
+			continue
+		}
+
+		matches, err := filepath.Glob(filepath.Join(p, "*"+fileSuffix))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list captures: %w", err)
+		}
+		slices.Sort(matches)
+		files = append(files, matches...)
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no capture files in %s", strings.Join(paths, ", "))
+	}
+
+	return &Reader{files: files}, nil
+}
+
+// Next returns the next record, or io.EOF after the last one. A file that
+// ends part way through a record, as one being written when a recorder
+// crashed does, is read up to its last complete record.
+func (r *Reader) Next() (Record, error) {
+	for {
+		if r.buf == nil {
+			if len(r.files) == 0 {
+				return Record{}, io.EOF
+			}
+			if err := r.openNext(); err != nil {
+				return Record{}, err
+			}
+		}
+
+		line, err := r.buf.ReadBytes('\n')
+		if err == nil {
+			var rec Record
+			if err := json.Unmarshal(line, &rec); err != nil {
+				return Record{}, fmt.Errorf("failed to decode capture record in %s: %w", r.f.Name(), err)
+			}
+			return rec, nil
+		}
+		if !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return Record{}, fmt.Errorf("failed to read capture file %s: %w", r.f.Name(), err)
+		}
+
+		if err := r.closeFile(); err != nil {
+			return Record{}, err
+		}
+	}
+}
+
+func (r *Reader) openNext() error {
+	name := r.files[0]
+	r.files = r.files[1:]
+
+	f, err := os.Open(name)
+	if err != nil {
+		return fmt.Errorf("failed to open capture file: %w", err)
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to read capture file %s: %w", name, err)
+	}
+
+	r.f, r.gz, r.buf = f, gz, bufio.NewReaderSize(gz, 1<<20)
+	return nil
+}
+
+func (r *Reader) closeFile() error {
+	err := r.f.Close()
+	r.f, r.gz, r.buf = nil, nil, nil
+	return err
+}
+
+// Close releases the file being read.
+func (r *Reader) Close() error {
+	if r.f == nil {
+		return nil
+	}
+	return r.closeFile()
+}
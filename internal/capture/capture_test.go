@@ -0,0 +1,135 @@
+package capture_test
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/northeastloon/flight_tracker/internal/capture"
+)
+
+var start = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func testRecords() []capture.Record {
+	return []capture.Record{
+		capture.NewRecord(start, "opensky", "opensky", 200, []byte(`{"time":1,"states":[]}`)),
+		capture.NewRecord(start.Add(time.Second), "home", "readsb", 200, []byte(`{"now":1,"aircraft":[]}`)),
+		capture.NewRecord(start.Add(2*time.Second), "opensky", "opensky", 503, []byte("Service Unavailable")),
+	}
+}
+
+// writeAll writes recs, pausing between them so rotated files get distinct
+// names.
+func writeAll(t *testing.T, w *capture.Writer, recs []capture.Record) {
+	t.Helper()
+	for _, rec := range recs {
+		if err := w.Write(rec); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+func readAll(t *testing.T, paths ...string) []capture.Record {
+	t.Helper()
+	r, err := capture.Open(paths...)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer r.Close()
+
+	var out []capture.Record
+	for {
+		rec, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return out
+		}
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		out = append(out, rec)
+	}
+}
+
+func captureFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "*.ndjson.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestCaptureRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  []capture.Option
+		files int
+		kept  int // newest records left after pruning
+	}{
+		{"one file", nil, 1, 3},
+		{"rotated", []capture.Option{capture.WithMaxBytes(1)}, 3, 3},
+		{"pruned", []capture.Option{capture.WithMaxBytes(1), capture.WithMaxFiles(2)}, 2, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			w, err := capture.NewWriter(dir, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := testRecords()
+			writeAll(t, w, want)
+
+			if files := captureFiles(t, dir); len(files) != tt.files {
+				t.Fatalf("got %d capture files, want %d", len(files), tt.files)
+			}
+
+			want = want[len(want)-tt.kept:]
+			got := readAll(t, dir)
+			if len(got) != len(want) {
+				t.Fatalf("read %d records, want %d", len(got), len(want))
+			}
+			for i := range want {
+				if !got[i].Time.Equal(want[i].Time) || got[i].Source != want[i].Source ||
+					got[i].Status != want[i].Status || string(got[i].Bytes()) != string(want[i].Bytes()) {
+					t.Errorf("record %d = %+v, want %+v", i, got[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCaptureReadsTruncatedFile(t *testing.T) {
+	dir := t.TempDir()
+	w, err := capture.NewWriter(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeAll(t, w, testRecords())
+
+	// cut the file off part way through its last record, as a crash would
+	files := captureFiles(t, dir)
+	info, err := os.Stat(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(files[0], info.Size()-30); err != nil {
+		t.Fatal(err)
+	}
+
+	got := readAll(t, files[0])
+	if len(got) == 0 || len(got) >= len(testRecords()) {
+		t.Fatalf("read %d records from a truncated file of %d", len(got), len(testRecords()))
+	}
+	if got[0].Source != "opensky" {
+		t.Errorf("first record = %+v", got[0])
+	}
+}
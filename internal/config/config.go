@@ -0,0 +1,500 @@
+// Package config loads the tracker's settings. Values are layered: built-in
+// defaults, then a YAML file, then environment variables, then command line
+// flags, each overriding the one before.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/northeastloon/flight_tracker/internal/capture"
+	"github.com/northeastloon/flight_tracker/internal/domain"
+	"github.com/northeastloon/flight_tracker/internal/spill"
+	"gopkg.in/yaml.v3"
+)
+
+// Every leaf field carries a yaml key, an env variable, a flag name and a
+// usage string. Fields tagged secret:"true" are redacted by Redacted.
+type Config struct {
+	Database  Database  `yaml:"database"`
+	HTTP      HTTP      `yaml:"http"`
+	Ingest    Ingest    `yaml:"ingest"`
+	OpenSky   OpenSky   `yaml:"opensky"`
+	Readsb    Readsb    `yaml:"readsb"`
+	Simulator Simulator `yaml:"simulator"`
+	Capture   Capture   `yaml:"capture"`
+	Tracing   Tracing   `yaml:"tracing"`
+}
+
+type Database struct {
+	URL      string `yaml:"url" env:"DATABASE_URL" flag:"database-url" usage:"postgres connection URL; overrides the individual settings" secret:"true"`
+	Host     string `yaml:"host" env:"POSTGRES_HOST" flag:"db-host" usage:"postgres host"`
+	Port     int    `yaml:"port" env:"POSTGRES_PORT" flag:"db-port" usage:"postgres port"`
+	Name     string `yaml:"name" env:"DB_NAME" flag:"db-name" usage:"database name"`
+	User     string `yaml:"user" env:"POSTGRES_ADMIN_USER" flag:"db-user" usage:"database user"`
+	Password string `yaml:"password" env:"POSTGRES_ADMIN_PASSWORD" flag:"db-password" usage:"database password" secret:"true"`
+	SSLMode  string `yaml:"sslmode" env:"SSL_MODE" flag:"db-sslmode" usage:"postgres sslmode"`
+
+	MaxConns        int           `yaml:"max_conns" env:"DB_MAX_CONNS" flag:"db-max-conns" usage:"maximum pool size"`
+	MinConns        int           `yaml:"min_conns" env:"DB_MIN_CONNS" flag:"db-min-conns" usage:"connections kept open when idle"`
+	MaxConnLifetime time.Duration `yaml:"max_conn_lifetime" env:"DB_MAX_CONN_LIFETIME" flag:"db-max-conn-lifetime" usage:"recycle connections older than this"`
+	MaxConnIdleTime time.Duration `yaml:"max_conn_idle_time" env:"DB_MAX_CONN_IDLE_TIME" flag:"db-max-conn-idle-time" usage:"close connections idle for longer than this"`
+}
+
+type HTTP struct {
+	Addr           string        `yaml:"addr" env:"HTTP_ADDR" flag:"addr" usage:"HTTP listen address"`
+	RequestTimeout time.Duration `yaml:"request_timeout" env:"HTTP_REQUEST_TIMEOUT" flag:"request-timeout" usage:"per-request handler timeout"`
+	StaleAfter     time.Duration `yaml:"stale_after" env:"INGEST_STALE_AFTER" flag:"stale-after" usage:"fail /readyz when ingestion has not succeeded for this long"`
+}
+
+type Ingest struct {
+	Interval      time.Duration `yaml:"interval" env:"INGEST_INTERVAL" flag:"interval" usage:"time between ingest cycle starts"`
+	BusyInterval  time.Duration `yaml:"busy_interval" env:"INGEST_BUSY_INTERVAL" flag:"busy-interval" usage:"time between cycle starts during busy hours; 0 uses interval"`
+	BusyHours     string        `yaml:"busy_hours" env:"INGEST_BUSY_HOURS" flag:"busy-hours" usage:"UTC hours using busy_interval, as start-end (e.g. 6-22); empty disables"`
+	MaxInterval   time.Duration `yaml:"max_interval" env:"INGEST_MAX_INTERVAL" flag:"max-interval" usage:"longest interval the rate-limit budget may stretch to"`
+	Jitter        float64       `yaml:"jitter" env:"INGEST_JITTER" flag:"jitter" usage:"fraction of the interval randomly added or removed"`
+	ShutdownGrace time.Duration `yaml:"shutdown_grace" env:"INGEST_SHUTDOWN_GRACE" flag:"shutdown-grace" usage:"time an in-flight ingest may take to finish on shutdown"`
+	MetricsAddr   string        `yaml:"metrics_addr" env:"METRICS_ADDR" flag:"metrics-addr" usage:"address serving /metrics and /healthz for the ingest worker; empty disables it"`
+	QueueSize     int           `yaml:"queue_size" env:"INGEST_QUEUE_SIZE" flag:"queue-size" usage:"fetched snapshots that may wait to be stored"`
+	StoreWorkers  int           `yaml:"store_workers" env:"INGEST_STORE_WORKERS" flag:"store-workers" usage:"goroutines storing queued snapshots"`
+	SpillPath     string        `yaml:"spill_path" env:"INGEST_SPILL_PATH" flag:"spill-path" usage:"file buffering snapshots while the database is unavailable; empty drops them"`
+	SpillMaxMB    int           `yaml:"spill_max_mb" env:"INGEST_SPILL_MAX_MB" flag:"spill-max-mb" usage:"size cap of the spill file, beyond which the oldest snapshots are evicted; 0 removes the cap"`
+	LeaseTTL      time.Duration `yaml:"lease_ttl" env:"INGEST_LEASE_TTL" flag:"lease-ttl" usage:"how long the ingest leader lease lasts without renewal; 0 disables leader election"`
+	ReplicaID     string        `yaml:"replica_id" env:"REPLICA_ID" flag:"replica-id" usage:"name this replica uses as lease holder; defaults to hostname-pid"`
+	FlightTimeout time.Duration `yaml:"flight_timeout" env:"INGEST_FLIGHT_TIMEOUT" flag:"flight-timeout" usage:"how long an aircraft may go unseen before its flight is closed as lost"`
+}
+
+type OpenSky struct {
+	Enabled  bool   `yaml:"enabled" env:"OPENSKY_ENABLED" flag:"opensky" usage:"ingest from the OpenSky API"`
+	BaseURL  string `yaml:"base_url" env:"OPENSKY_BASE_URL" flag:"opensky-url" usage:"OpenSky states endpoint"`
+	Extended bool   `yaml:"extended" env:"OPENSKY_EXTENDED" flag:"extended" usage:"request aircraft categories from OpenSky"`
+}
+
+// Readsb lists local receivers serving readsb, tar1090 or dump1090-fa
+// aircraft.json. Each feed's name becomes the source of its telemetry.
+type Readsb struct {
+	Feeds string `yaml:"feeds" env:"READSB_FEEDS" flag:"readsb-feeds" usage:"comma-separated name=url pairs of aircraft.json endpoints, e.g. roof=http://pi.local/tar1090/data/aircraft.json"`
+}
+
+// ReadsbFeed is one entry of Readsb.Feeds.
+type ReadsbFeed struct {
+	Name string
+	URL  string
+}
+
+// Simulator generates synthetic traffic, for demos and load tests, as the
+// source "simulator".
+type Simulator struct {
+	Enabled  bool   `yaml:"enabled" env:"SIMULATOR_ENABLED" flag:"simulator" usage:"ingest synthetic traffic from the built-in simulator"`
+	Aircraft int    `yaml:"aircraft" env:"SIMULATOR_AIRCRAFT" flag:"simulator-aircraft" usage:"number of simulated aircraft"`
+	Seed     int    `yaml:"seed" env:"SIMULATOR_SEED" flag:"simulator-seed" usage:"seed of the simulated traffic; the same seed flies the same routes"`
+	Airports string `yaml:"airports" env:"SIMULATOR_AIRPORTS" flag:"simulator-airports" usage:"comma-separated ICAO codes of built-in airports or CODE=lat:lon entries to fly between; empty uses every built-in airport"`
+}
+
+// Capture records raw provider responses for replay with ingest -replay.
+type Capture struct {
+	Dir       string        `yaml:"dir" env:"CAPTURE_DIR" flag:"capture-dir" usage:"record every raw provider response into rotating gzip files in this directory; empty disables recording"`
+	MaxSizeMB int           `yaml:"max_size_mb" env:"CAPTURE_MAX_SIZE_MB" flag:"capture-max-size-mb" usage:"start a new capture file after this many uncompressed megabytes"`
+	MaxAge    time.Duration `yaml:"max_age" env:"CAPTURE_MAX_AGE" flag:"capture-max-age" usage:"start a new capture file once the current one is this old"`
+	MaxFiles  int           `yaml:"max_files" env:"CAPTURE_MAX_FILES" flag:"capture-max-files" usage:"keep at most this many capture files, deleting the oldest; 0 keeps them all"`
+}
+
+type Tracing struct {
+	Exporter string `yaml:"exporter" env:"OTEL_TRACES_EXPORTER" flag:"trace-exporter" usage:"trace exporter: none, otlp or stdout, which prints spans to stderr"`
+}
+
+// Default returns the built-in settings.
+func Default() Config {
+	return Config{
+		Database: Database{
+			Host:            "localhost",
+			Port:            5432,
+			Name:            "postgres",
+			User:            "postgres",
+			SSLMode:         "disable",
+			MaxConns:        10,
+			MinConns:        0,
+			MaxConnLifetime: time.Hour,
+			MaxConnIdleTime: 30 * time.Minute,
+		},
+		HTTP: HTTP{
+			Addr:           ":8080",
+			RequestTimeout: 15 * time.Second,
+			StaleAfter:     5 * time.Minute,
+		},
+		Ingest: Ingest{
+			Interval:      time.Minute,
+			MaxInterval:   15 * time.Minute,
+			Jitter:        0.1,
+			ShutdownGrace: 10 * time.Second,
+			MetricsAddr:   ":9090",
+			QueueSize:     domain.DefaultQueueSize,
+			StoreWorkers:  domain.DefaultStoreWorkers,
+			SpillPath:     "data/ingest-spill.ndjson",
+			SpillMaxMB:    spill.DefaultMaxBytes >> 20,
+			LeaseTTL:      15 * time.Second,
+			FlightTimeout: domain.DefaultFlightTimeout,
+		},
+		OpenSky: OpenSky{
+			Enabled:  true,
+			BaseURL:  "https://opensky-network.org/api/states/all",
+			Extended: true,
+		},
+		Simulator: Simulator{
+			Aircraft: 100,
+			Seed:     1,
+		},
+		Capture: Capture{
+			MaxSizeMB: 64,
+			MaxAge:    time.Hour,
+			MaxFiles:  capture.DefaultMaxFiles,
+		},
+		Tracing: Tracing{
+			Exporter: "none",
+		},
+	}
+}
+
+// Flags holds the command line layer until Load applies it.
+type Flags struct {
+	fs   *flag.FlagSet
+	file *string
+	raw  map[string]*string
+}
+
+// BindFlags registers -config and one flag per setting on fs. Flags are
+// registered as strings so Load can tell which ones were given explicitly.
+func BindFlags(fs *flag.FlagSet) *Flags {
+	f := &Flags{
+		fs:   fs,
+		file: fs.String("config", os.Getenv("CONFIG_FILE"), "YAML config file (env CONFIG_FILE)"),
+		raw:  make(map[string]*string),
+	}
+
+	defaults := Default()
+	walk(reflect.ValueOf(&defaults).Elem(), func(field reflect.StructField, v reflect.Value) {
+		name := field.Tag.Get("flag")
+		usage := fmt.Sprintf("%s (env %s, default %s)", field.Tag.Get("usage"), field.Tag.Get("env"), formatValue(v))
+		f.raw[name] = fs.String(name, "", usage)
+	})
+
+	return f
+}
+
+// Load builds a Config from defaults, the config file, the environment and
+// the flags that were set, then validates it. flags may be nil.
+func Load(flags *Flags) (Config, error) {
+	cfg := Default()
+
+	path := os.Getenv("CONFIG_FILE")
+	if flags != nil {
+		path = *flags.file
+	}
+	if path != "" {
+		if err := loadFile(path, &cfg); err != nil {
+			return cfg, err
+		}
+	}
+
+	var errs []error
+	set := func(field reflect.StructField, v reflect.Value, raw, source string) {
+		if err := setValue(v, raw); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", source, err))
+		}
+	}
+
+	walk(reflect.ValueOf(&cfg).Elem(), func(field reflect.StructField, v reflect.Value) {
+		env := field.Tag.Get("env")
+		if raw, ok := os.LookupEnv(env); ok {
+			set(field, v, raw, env)
+		}
+	})
+
+	if flags != nil {
+		explicit := make(map[string]bool)
+		flags.fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+		walk(reflect.ValueOf(&cfg).Elem(), func(field reflect.StructField, v reflect.Value) {
+			name := field.Tag.Get("flag")
+			if explicit[name] {
+				set(field, v, *flags.raw[name], "-"+name)
+			}
+		})
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return cfg, err
+	}
+
+	return cfg, cfg.Validate()
+}
+
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// Validate reports every invalid setting.
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	if c.Database.URL != "" {
+		u, err := url.Parse(c.Database.URL)
+		check(err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql"),
+			"database.url must be a postgres:// URL")
+	} else {
+		check(c.Database.Host != "", "database.host is required when database.url is not set")
+		check(c.Database.Port > 0 && c.Database.Port < 65536, "database.port must be between 1 and 65535")
+		check(c.Database.Name != "", "database.name is required when database.url is not set")
+	}
+	check(c.Database.MaxConns > 0, "database.max_conns must be positive")
+	check(c.Database.MinConns >= 0 && c.Database.MinConns <= c.Database.MaxConns,
+		"database.min_conns must be between 0 and database.max_conns")
+	check(c.Database.MaxConnLifetime > 0, "database.max_conn_lifetime must be positive")
+	check(c.Database.MaxConnIdleTime > 0, "database.max_conn_idle_time must be positive")
+
+	check(c.HTTP.Addr != "", "http.addr is required")
+	check(c.HTTP.RequestTimeout > 0, "http.request_timeout must be positive")
+	check(c.HTTP.StaleAfter > 0, "http.stale_after must be positive")
+
+	check(c.Ingest.Interval >= time.Second, "ingest.interval must be at least 1s")
+	check(c.Ingest.BusyInterval == 0 || c.Ingest.BusyInterval >= time.Second, "ingest.busy_interval must be 0 or at least 1s")
+	_, _, err := c.Ingest.busyWindow()
+	check(err == nil, "ingest.busy_hours must be start-end with hours from 0 to 23")
+	check(c.Ingest.MaxInterval >= c.Ingest.Interval && c.Ingest.MaxInterval >= c.Ingest.BusyInterval,
+		"ingest.max_interval must not be shorter than ingest.interval or ingest.busy_interval")
+	check(c.Ingest.Jitter >= 0 && c.Ingest.Jitter < 1, "ingest.jitter must be at least 0 and less than 1")
+	check(c.Ingest.ShutdownGrace >= 0, "ingest.shutdown_grace must not be negative")
+	check(c.Ingest.QueueSize > 0, "ingest.queue_size must be positive")
+	check(c.Ingest.SpillMaxMB >= 0, "ingest.spill_max_mb must not be negative")
+	check(c.Ingest.StoreWorkers > 0, "ingest.store_workers must be positive")
+	check(c.Ingest.LeaseTTL == 0 || c.Ingest.LeaseTTL >= time.Second, "ingest.lease_ttl must be 0 or at least 1s")
+	check(c.Ingest.FlightTimeout >= time.Minute, "ingest.flight_timeout must be at least 1m")
+
+	u, err := url.Parse(c.OpenSky.BaseURL)
+	check(err == nil && u.Scheme != "" && u.Host != "", "opensky.base_url must be an absolute URL")
+
+	feeds, err := c.Readsb.List()
+	check(err == nil, "readsb.feeds: %v", err)
+	check(c.OpenSky.Enabled || len(feeds) > 0 || c.Simulator.Enabled,
+		"at least one feed must be enabled: opensky.enabled, readsb.feeds or simulator.enabled")
+	check(c.Simulator.Aircraft > 0, "simulator.aircraft must be positive")
+
+	check(c.Capture.MaxSizeMB > 0, "capture.max_size_mb must be positive")
+	check(c.Capture.MaxAge > 0, "capture.max_age must be positive")
+	check(c.Capture.MaxFiles >= 0, "capture.max_files must not be negative")
+
+	switch c.Tracing.Exporter {
+	case "", "none", "otlp", "stdout":
+	default:
+		check(false, "tracing.exporter must be none, otlp or stdout")
+	}
+
+	return errors.Join(errs...)
+}
+
+// ConnString returns the postgres connection string: the URL when one is
+// set, otherwise a keyword/value string built from the individual settings,
+// quoted so that values may hold spaces, quotes or backslashes.
+func (d Database) ConnString() string {
+	if d.URL != "" {
+		return d.URL
+	}
+	return fmt.Sprintf(
+		"host=%s port=%d dbname=%s user=%s password=%s sslmode=%s",
+		connValue(d.Host), d.Port, connValue(d.Name), connValue(d.User), connValue(d.Password), connValue(d.SSLMode),
+	)
+}
+
+var connValueEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+// connValue quotes v as a keyword/value connection string value.
+func connValue(v string) string {
+	return "'" + connValueEscaper.Replace(v) + "'"
+}
+
+// Schedule returns the ingestion loop's schedule.
+func (i Ingest) Schedule() domain.Schedule {
+	start, end, _ := i.busyWindow()
+	return domain.Schedule{
+		Interval:     i.Interval,
+		BusyInterval: i.BusyInterval,
+		BusyStart:    start,
+		BusyEnd:      end,
+		MaxInterval:  i.MaxInterval,
+		Jitter:       i.Jitter,
+	}
+}
+
+// busyWindow parses BusyHours. An empty value yields an empty window.
+func (i Ingest) busyWindow() (start, end int, err error) {
+	if i.BusyHours == "" {
+		return 0, 0, nil
+	}
+	from, to, ok := strings.Cut(i.BusyHours, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("missing '-' in %q", i.BusyHours)
+	}
+	if start, err = strconv.Atoi(strings.TrimSpace(from)); err != nil {
+		return 0, 0, err
+	}
+	if end, err = strconv.Atoi(strings.TrimSpace(to)); err != nil {
+		return 0, 0, err
+	}
+	if start < 0 || start > 23 || end < 0 || end > 23 {
+		return 0, 0, fmt.Errorf("hours out of range in %q", i.BusyHours)
+	}
+	return start, end, nil
+}
+
+// feedNamePattern matches the source names the API accepts.
+var feedNamePattern = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// List parses Feeds. Names must be unique, lower-case and distinct from the
+// built-in providers.
+func (r Readsb) List() ([]ReadsbFeed, error) {
+	var feeds []ReadsbFeed
+	seen := map[string]bool{"opensky": true, "simulator": true}
+
+	for _, entry := range strings.Split(r.Feeds, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, raw, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("missing '=' in %q", entry)
+		}
+		name = strings.TrimSpace(name)
+		if !feedNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("feed name %q must be 1-32 lower-case letters, digits, - or _", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("feed name %q is used twice", name)
+		}
+		seen[name] = true
+
+		u, err := url.Parse(strings.TrimSpace(raw))
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("feed %s must have an absolute URL", name)
+		}
+		feeds = append(feeds, ReadsbFeed{Name: name, URL: u.String()})
+	}
+
+	return feeds, nil
+}
+
+// Replica returns the configured replica name, or hostname-pid when none is
+// set, which is unique among replicas sharing a database.
+func (i Ingest) Replica() string {
+	if i.ReplicaID != "" {
+		return i.ReplicaID
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+// Redacted returns a copy of c with secrets masked, for display.
+func (c Config) Redacted() Config {
+	walk(reflect.ValueOf(&c).Elem(), func(field reflect.StructField, v reflect.Value) {
+		if field.Tag.Get("secret") != "true" || v.String() == "" {
+			return
+		}
+		if u, err := url.Parse(v.String()); err == nil && u.User != nil {
+			v.SetString(u.Redacted())
+			return
+		}
+		v.SetString("REDACTED")
+	})
+	return c
+}
+
+// YAML renders c in the config file format.
+func (c Config) YAML() ([]byte, error) {
+	return yaml.Marshal(c)
+}
+
+// walk calls fn for every leaf field of the struct v.
+func walk(v reflect.Value, fn func(field reflect.StructField, v reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Type.Kind() == reflect.Struct && field.Type != reflect.TypeOf(time.Duration(0)) {
+			walk(v.Field(i), fn)
+			continue
+		}
+		fn(field, v.Field(i))
+	}
+}
+
+func setValue(v reflect.Value, raw string) error {
+	switch {
+	case v.Type() == reflect.TypeOf(time.Duration(0)):
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(raw)
+	case v.Kind() == reflect.Int:
+		i, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(i))
+	case v.Kind() == reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported config type %s", v.Type())
+	}
+	return nil
+}
+
+func formatValue(v reflect.Value) string {
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		return time.Duration(v.Int()).String()
+	}
+	if v.Kind() == reflect.String && v.String() == "" {
+		return `""`
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,135 @@
+package config_test
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/northeastloon/flight_tracker/internal/config"
+)
+
+func writeConfig(t *testing.T, yaml string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func load(t *testing.T, args ...string) (config.Config, error) {
+	t.Helper()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	flags := config.BindFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	return config.Load(flags)
+}
+
+func TestLoadLayers(t *testing.T) {
+	path := writeConfig(t, `
+database:
+  host: file-host
+  user: file-user
+  port: 6000
+ingest:
+  interval: 2m
+`)
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("POSTGRES_HOST", "") // restored after the test
+	os.Unsetenv("POSTGRES_HOST")
+	t.Setenv("POSTGRES_ADMIN_USER", "env-user")
+	t.Setenv("POSTGRES_PORT", "6500")
+
+	cfg, err := load(t, "-config", path, "-db-port", "7000")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	tests := []struct {
+		setting   string
+		got, want any
+	}{
+		{"database.name (default)", cfg.Database.Name, config.Default().Database.Name},
+		{"database.host (file)", cfg.Database.Host, "file-host"},
+		{"ingest.interval (file)", cfg.Ingest.Interval, 2 * time.Minute},
+		{"database.user (env over file)", cfg.Database.User, "env-user"},
+		{"database.port (flag over env and file)", cfg.Database.Port, 7000},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.setting, tt.got, tt.want)
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		file string
+		args []string
+		want string
+	}{
+		{name: "unknown file key", file: "database:\n  hots: x\n", want: "hots"},
+		{name: "bad env value", env: map[string]string{"INGEST_INTERVAL": "soon"}, want: "INGEST_INTERVAL"},
+		{name: "bad flag value", args: []string{"-db-port", "high"}, want: "-db-port"},
+		{name: "invalid setting", args: []string{"-queue-size", "0"}, want: "ingest.queue_size"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			args := tt.args
+			if tt.file != "" {
+				args = append([]string{"-config", writeConfig(t, tt.file)}, args...)
+			}
+			_, err := load(t, args...)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load error = %v, want one mentioning %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestConnStringQuotesValues(t *testing.T) {
+	db := config.Default().Database
+	db.Password = `p@ss word'\ x=1`
+	db.User = "flight tracker"
+
+	parsed, err := pgconn.ParseConfig(db.ConnString())
+	if err != nil {
+		t.Fatalf("ParseConfig(%q): %v", db.ConnString(), err)
+	}
+	if parsed.Password != db.Password || parsed.User != db.User || parsed.Database != db.Name {
+		t.Errorf("ConnString round-trips to user %q, password %q, database %q", parsed.User, parsed.Password, parsed.Database)
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := config.Default()
+	cfg.Database.Password = "secret"
+	cfg.Database.URL = "postgres://user:secret@db/flights"
+
+	out, err := cfg.Redacted().YAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(out), "secret") {
+		t.Errorf("redacted config still holds the secret:\n%s", out)
+	}
+	if cfg.Database.Password != "secret" {
+		t.Error("Redacted modified the original config")
+	}
+}
@@ -0,0 +1,66 @@
+package domain_test
+
+import (
+	"testing"
+
+	"github.com/northeastloon/flight_tracker/internal/domain"
+	"github.com/northeastloon/flight_tracker/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestDecodeAddress(t *testing.T) {
+	tests := []struct {
+		icao24, country, registration string
+		allocated                     bool
+	}{
+		{"a00001", "United States", "N1", true},
+		{"a00002", "United States", "N1A", true},
+		{"a00003", "United States", "N1AA", true},
+		{"a835af", "United States", "N628TS", true},
+		{"adf7c7", "United States", "N99999", true},
+		{"c00001", "Canada", "C-FAAA", true},
+		{"c044a9", "Canada", "C-GAAA", true},
+		{"4ca7b4", "Ireland", "", true},
+		{"789123", "Hong Kong", "", true}, // nested in the block of China
+		{"7a0000", "China", "", true},
+		{"000001", "", "", false},
+		{"ABCDEZ", "", "", false},
+	}
+
+	for _, tt := range tests {
+		info, ok := domain.DecodeAddress(tt.icao24)
+		if ok != tt.allocated || info.Country != tt.country {
+			t.Errorf("%s: got %q, %v; want %q, %v", tt.icao24, info.Country, ok, tt.country, tt.allocated)
+		}
+		registration := ""
+		if info.Registration != nil {
+			registration = *info.Registration
+		}
+		if registration != tt.registration {
+			t.Errorf("%s: registration %q, want %q", tt.icao24, registration, tt.registration)
+		}
+	}
+}
+
+func TestFillAddressCountries(t *testing.T) {
+	const source = "address-test"
+	unallocated := metrics.UnallocatedAddresses.WithLabelValues(source)
+	before := testutil.ToFloat64(unallocated)
+
+	data := []domain.Telemetry{
+		{Source: source, ICAO24: "4ca7b4"},
+		{Source: source, ICAO24: "4ca7b5", OriginCountry: "Kingdom of the Netherlands"},
+		{Source: source, ICAO24: "000001"},
+		{Source: source, ICAO24: "abcdez"},
+	}
+	domain.FillAddressCountries(data)
+
+	for i, want := range []string{"Ireland", "Kingdom of the Netherlands", "", ""} {
+		if data[i].OriginCountry != want {
+			t.Errorf("%s: origin country %q, want %q", data[i].ICAO24, data[i].OriginCountry, want)
+		}
+	}
+	if got := testutil.ToFloat64(unallocated) - before; got != 2 {
+		t.Errorf("counted %v unallocated addresses, want 2", got)
+	}
+}
@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"sort"
+	"time"
+)
+
+// AircraftSummary aggregates the telemetry of a single aircraft over a time
+// window.
+type AircraftSummary struct {
+	ICAO24      string
+	Latest      Telemetry
+	Track       []Telemetry // oldest first
+	FirstSeen   *time.Time  // oldest fix of the track; nil when it is empty
+	LastSeen    *time.Time  // newest fix of the track; nil when it is empty
+	MaxAltitude *float64    // metres; barometric, geometric when baro is missing
+	MaxVelocity *float64    // m/s
+	Distance    float64     // metres flown along the track
+}
+
+// SummarizeAircraft builds an AircraftSummary from the newest known state of an
+// aircraft and its track history. The track may be in any order.
+func SummarizeAircraft(latest Telemetry, track []Telemetry) AircraftSummary {
+	sorted := make([]Telemetry, len(track))
+	copy(sorted, track)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].LastContact.Before(sorted[j].LastContact)
+	})
+
+	summary := AircraftSummary{
+		ICAO24: latest.ICAO24,
+		Latest: latest,
+		Track:  sorted,
+	}
+
+	if len(sorted) == 0 {
+		return summary
+	}
+
+	first, last := sorted[0].LastContact, sorted[len(sorted)-1].LastContact
+	summary.FirstSeen, summary.LastSeen = &first, &last
+
+	var prev *Telemetry
+	for i := range sorted {
+		t := &sorted[i]
+
+		alt := t.BaroAltitude
+		if alt == nil {
+			alt = t.GeoAltitude
+		}
+		summary.MaxAltitude = maxOf(summary.MaxAltitude, alt)
+		summary.MaxVelocity = maxOf(summary.MaxVelocity, t.Velocity)
+
+		if t.Latitude == nil || t.Longitude == nil {
+			continue
+		}
+		if prev != nil {
+			summary.Distance += GreatCircleDistance(*prev.Latitude, *prev.Longitude, *t.Latitude, *t.Longitude)
+		}
+		prev = t
+	}
+
+	return summary
+}
+
+func maxOf(current, v *float64) *float64 {
+	if v == nil {
+		return current
+	}
+	if current == nil || *v > *current {
+		m := *v
+		return &m
+	}
+	return current
+}
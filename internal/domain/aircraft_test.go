@@ -0,0 +1,60 @@
+package domain_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/northeastloon/flight_tracker/internal/domain"
+)
+
+func TestSummarizeAircraft(t *testing.T) {
+	// an out-of-order track of a climbing aircraft, with a fix that has no
+	// position and one that only reports geometric altitude
+	noPosition := state(1, false, "")
+	noPosition.Latitude, noPosition.Longitude = nil, nil
+	speed := 120.0
+	noPosition.Velocity = &speed
+	geoOnly := state(3, false, "")
+	geo := 2000.0
+	geoOnly.BaroAltitude, geoOnly.GeoAltitude = nil, &geo
+	track := []domain.Telemetry{state(2, false, ""), geoOnly, state(0, true, ""), noPosition}
+	latest := state(3, false, "EIN1")
+
+	s := domain.SummarizeAircraft(latest, track)
+	if s.ICAO24 != "4ca7b4" || s.Latest.Callsign == nil || len(s.Track) != 4 {
+		t.Fatalf("summary = %+v", s)
+	}
+	for i := 1; i < len(s.Track); i++ {
+		if s.Track[i].LastContact.Before(s.Track[i-1].LastContact) {
+			t.Fatalf("track not sorted oldest first: %v before %v", s.Track[i-1].LastContact, s.Track[i].LastContact)
+		}
+	}
+	if s.FirstSeen == nil || !s.FirstSeen.Equal(flightStart) || s.LastSeen == nil || !s.LastSeen.Equal(flightStart.Add(3*time.Minute)) {
+		t.Errorf("seen from %v to %v, want minutes 0 to 3", s.FirstSeen, s.LastSeen)
+	}
+	if s.MaxAltitude == nil || *s.MaxAltitude != 2000 || s.MaxVelocity == nil || *s.MaxVelocity != 120 {
+		t.Errorf("max altitude %v, max velocity %v; want 2000 and 120", s.MaxAltitude, s.MaxVelocity)
+	}
+	// three hundredths of a degree of latitude, skipping the fix without a
+	// position
+	want := 0.03 * domain.EarthRadiusMeters * math.Pi / 180
+	if math.Abs(s.Distance-want) > 1e-6 {
+		t.Errorf("distance = %v, want %v", s.Distance, want)
+	}
+
+	// the input track is left as it was
+	if !track[0].LastContact.Equal(flightStart.Add(2 * time.Minute)) {
+		t.Error("SummarizeAircraft reordered its input")
+	}
+}
+
+func TestSummarizeAircraftWithoutTrack(t *testing.T) {
+	s := domain.SummarizeAircraft(state(5, false, "EIN1"), nil)
+	if s.FirstSeen != nil || s.LastSeen != nil {
+		t.Errorf("seen from %v to %v, want unknown without a track", s.FirstSeen, s.LastSeen)
+	}
+	if s.MaxAltitude != nil || s.MaxVelocity != nil || s.Distance != 0 || len(s.Track) != 0 {
+		t.Errorf("summary without a track = %+v", s)
+	}
+}
@@ -0,0 +1,76 @@
+package domain
+
+// Airport types used by OurAirports.
+const (
+	AirportLarge       = "large_airport"
+	AirportMedium      = "medium_airport"
+	AirportSmall       = "small_airport"
+	AirportHeliport    = "heliport"
+	AirportSeaplane    = "seaplane_base"
+	AirportBalloonport = "balloonport"
+	AirportClosed      = "closed"
+)
+
+// AirportTypes lists every airport type, largest first.
+var AirportTypes = []string{
+	AirportLarge, AirportMedium, AirportSmall,
+	AirportHeliport, AirportSeaplane, AirportBalloonport, AirportClosed,
+}
+
+// A flight's departure and arrival airports are the nearest airports of one
+// of InferredAirportTypes within ApproachRadius of its departure and last
+// fixes, when the fix was on the ground or at most ApproachHeight above the airport.
+const (
+	ApproachRadius = 10_000.0 // metres
+	ApproachHeight = 1_000.0  // metres
+)
+
+// InferredAirportTypes are the airport types flights are matched to.
+var InferredAirportTypes = []string{AirportLarge, AirportMedium, AirportSmall}
+
+// Airport is an aerodrome from the OurAirports database.
+type Airport struct {
+	Ident            string // OurAirports identifier, the ICAO code where there is one
+	Type             string // one of AirportTypes
+	Name             string
+	ICAOCode         *string
+	IATACode         *string
+	Latitude         float64
+	Longitude        float64
+	Elevation        *float64 // metres
+	Country          string   // ISO 3166-1 alpha-2
+	Region           string   // ISO 3166-2
+	Municipality     *string
+	ScheduledService bool
+	Runways          []Runway
+	Distance         *float64 // metres from the point searched for, when found by position
+}
+
+// Runway is a runway of an Airport.
+type Runway struct {
+	Length  *float64 // metres
+	Width   *float64 // metres
+	Surface *string
+	Lighted bool
+	Closed  bool
+	LowEnd  RunwayEnd
+	HighEnd RunwayEnd
+}
+
+// RunwayEnd is one threshold of a runway.
+type RunwayEnd struct {
+	Ident     *string // e.g. 09L
+	Latitude  *float64
+	Longitude *float64
+	Elevation *float64 // metres
+	Heading   *float64 // degrees true
+}
+
+// NearestAirportFilter selects the airports closest to a point.
+type NearestAirportFilter struct {
+	Latitude  float64
+	Longitude float64
+	Radius    float64  // kilometres; 0 means no limit
+	Types     []string // nil means every type but closed
+	Limit     int
+}
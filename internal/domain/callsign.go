@@ -0,0 +1,80 @@
+package domain
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Airline is an entry of the airline table, keyed by ICAO designator.
+type Airline struct {
+	ICAO      string // ICAO airline designator, e.g. BAW
+	IATA      *string
+	Name      string
+	Country   *string
+	Telephony *string // radiotelephony callsign, e.g. SPEEDBIRD
+}
+
+// airlineCallsign matches ICAO airline callsigns: a three-letter designator
+// followed by a flight identifier of a digit and up to three letters or
+// digits. The airline_designator SQL function applies the same pattern.
+var airlineCallsign = regexp.MustCompile(`^([A-Z]{3})([0-9][0-9A-Z]{0,3})$`)
+
+// NormalizeCallsign trims the padding transponders send and upper-cases a
+// callsign, returning nil when nothing is left.
+func NormalizeCallsign(callsign *string) *string {
+	if callsign == nil {
+		return nil
+	}
+	cs := strings.ToUpper(strings.TrimSpace(*callsign))
+	if cs == "" {
+		return nil
+	}
+	return &cs
+}
+
+// SplitCallsign splits a normalized airline callsign such as BAW123A into
+// its airline designator and flight identifier. It reports false for
+// callsigns that are not airline callsigns, such as registration marks.
+func SplitCallsign(callsign string) (designator, flight string, ok bool) {
+	m := airlineCallsign.FindStringSubmatch(callsign)
+	if m == nil {
+		return "", "", false
+	}
+	return m[1], m[2], true
+}
+
+// numericFlight matches flight identifiers that carry a flight number: digits
+// and at most one trailing letter, such as 123 or 123A. Others, such as the
+// alphanumeric 9LF, are identifiers chosen to avoid similar callsigns and
+// say nothing about the number the flight is sold under.
+var numericFlight = regexp.MustCompile(`^([0-9]+)[A-Z]?$`)
+
+// FlightNumber returns the IATA-style flight number of an airline callsign,
+// e.g. BA123 for BAW123A, or nil when the airline has no IATA code or the
+// flight identifier is not numeric.
+func FlightNumber(airline *Airline, callsign *string) *string {
+	if airline == nil || airline.IATA == nil || callsign == nil {
+		return nil
+	}
+	_, flight, ok := SplitCallsign(*callsign)
+	if !ok {
+		return nil
+	}
+	m := numericFlight.FindStringSubmatch(flight)
+	if m == nil {
+		return nil
+	}
+	digits := strings.TrimLeft(m[1], "0")
+	if digits == "" {
+		digits = "0"
+	}
+	number := *airline.IATA + digits
+	return &number
+}
+
+// normalizeCallsigns normalizes the callsigns of a snapshot in place.
+func normalizeCallsigns(data []Telemetry) {
+	for i := range data {
+		data[i].Callsign = NormalizeCallsign(data[i].Callsign)
+	}
+}
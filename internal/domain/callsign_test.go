@@ -0,0 +1,49 @@
+package domain_test
+
+import (
+	"testing"
+
+	"github.com/northeastloon/flight_tracker/internal/domain"
+)
+
+func TestCallsignDecoding(t *testing.T) {
+	ba := "BA"
+	britishAirways := &domain.Airline{ICAO: "BAW", IATA: &ba, Name: "British Airways"}
+
+	tests := []struct {
+		raw, normalized, designator, flight, number string
+	}{
+		{"baw123a ", "BAW123A", "BAW", "123A", "BA123"},
+		{"BAW0042", "BAW0042", "BAW", "0042", "BA42"},
+		{"BAW9LF  ", "BAW9LF", "BAW", "9LF", ""},
+		{"BAW12AB", "BAW12AB", "BAW", "12AB", ""},
+		{"GABCD", "GABCD", "", "", ""},
+		{"N628TS", "N628TS", "", "", ""},
+		{"   ", "", "", "", ""},
+	}
+
+	for _, tt := range tests {
+		cs := domain.NormalizeCallsign(&tt.raw)
+		normalized := ""
+		if cs != nil {
+			normalized = *cs
+		}
+		if normalized != tt.normalized {
+			t.Errorf("NormalizeCallsign(%q) = %q, want %q", tt.raw, normalized, tt.normalized)
+			continue
+		}
+
+		designator, flight, _ := domain.SplitCallsign(normalized)
+		if designator != tt.designator || flight != tt.flight {
+			t.Errorf("SplitCallsign(%q) = %q, %q; want %q, %q", normalized, designator, flight, tt.designator, tt.flight)
+		}
+
+		number := ""
+		if n := domain.FlightNumber(britishAirways, cs); n != nil {
+			number = *n
+		}
+		if number != tt.number {
+			t.Errorf("FlightNumber(%q) = %q, want %q", normalized, number, tt.number)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package domain
+
+import "time"
+
+// Clock is the ingestion loop's source of time. Replay substitutes one that
+// follows the capture being replayed, so schedules and status timestamps
+// match the recorded run.
+type Clock interface {
+	Now() time.Time
+	// After delivers the time on the returned channel once d has passed.
+	After(d time.Duration) <-chan time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// WithClock makes the ingestion loop read and wait on c instead of the
+// system clock.
+func WithClock(c Clock) ServiceOption {
+	return func(o *serviceOptions) {
+		o.clock = c
+	}
+}
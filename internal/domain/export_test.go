@@ -0,0 +1,22 @@
+package domain
+
+import "time"
+
+// Scheduler exposes the ingestion scheduler to the external tests.
+type Scheduler = scheduler
+
+// NewScheduler returns a scheduler whose jitter source always returns
+// jitter.
+func NewScheduler(s Schedule, jitter float64) *Scheduler {
+	sc := newScheduler(s)
+	sc.jitter = func() float64 { return jitter }
+	return sc
+}
+
+func (s *scheduler) Interval(start, now time.Time, rl RateLimit, haveRL bool) time.Duration {
+	return s.interval(start, now, rl, haveRL)
+}
+
+func (s Schedule) Busy(t time.Time) bool {
+	return s.busy(t)
+}
@@ -0,0 +1,224 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+)
+
+// DefaultFlightTimeout is how long an aircraft may go unseen before its
+// flight is closed as lost.
+const DefaultFlightTimeout = 15 * time.Minute
+
+// Flight statuses.
+const (
+	FlightActive = "active" // airborne when last seen
+	FlightLanded = "landed" // closed by a transition to the ground
+	FlightLost   = "lost"   // closed because the aircraft went unseen too long
+)
+
+// Flight is one airborne period of an aircraft, derived from its telemetry.
+type Flight struct {
+	ID            string // icao24 and the time it was first seen airborne
+	ICAO24        string
+	Callsign      *string  // most recent callsign
+	Callsigns     []string // every callsign used, in order of first use
+	Airline       *Airline // airline of the callsign, when the store knows it
+	OriginCountry string
+	Category      int
+	Status        string
+	FirstSeen     time.Time
+	LastSeen      time.Time
+	// Takeoff is set when the aircraft was seen on the ground just before,
+	// Landing when it was seen to land.
+	Takeoff *time.Time
+	Landing *time.Time
+	// The first and last fixes with a position, and their altitudes.
+	StartLatitude  *float64
+	StartLongitude *float64
+	StartAltitude  *float64
+	EndLatitude    *float64
+	EndLongitude   *float64
+	EndAltitude    *float64
+	// The fix the departure airport is inferred from: the first fix with a
+	// position and an altitude, unless the aircraft was seen above
+	// ApproachHeight before it, in which case it stays the first fix with a
+	// position.
+	DepartureLatitude  *float64
+	DepartureLongitude *float64
+	DepartureAltitude  *float64
+	// Airports inferred from the departure and last fixes, by ident; nil
+	// when they were not near one.
+	DepartureAirport *string
+	ArrivalAirport   *string
+	MaxAltitude      *float64 // metres; barometric, geometric when baro is missing
+	MaxVelocity      *float64 // m/s
+	Distance         float64  // metres flown along the track
+	Observations     int
+}
+
+// FlightTrack is the segmentation state of one aircraft, carried from one
+// snapshot to the next.
+type FlightTrack struct {
+	ICAO24   string
+	LastSeen time.Time
+	OnGround bool
+	Flight   *Flight // the open flight; nil while on the ground or unseen
+}
+
+// FlightFilter selects flights.
+type FlightFilter struct {
+	ICAO24        *string
+	Callsign      *string // matches any callsign the flight used
+	OriginCountry *string
+	Category      *int
+	Status        *string
+	Departure     *string    // departure airport ident
+	Arrival       *string    // arrival airport ident
+	TypeCode      *string    // registry ICAO type designator
+	Operator      *string    // registry ICAO airline designator
+	Airline       *string    // ICAO airline designator of the callsign
+	From          *time.Time // flights last seen at or after
+	To            *time.Time // flights first seen at or before
+	Limit         int        // 0 means no limit
+}
+
+// FlightBuilder segments telemetry into flights. A flight opens when an
+// aircraft takes off, or when it is first seen airborne, and closes when it
+// lands or has been unseen for longer than Timeout. Callsign changes while
+// airborne, common when a flight is renumbered by ATC, do not split it.
+type FlightBuilder struct {
+	Timeout time.Duration // 0 uses DefaultFlightTimeout
+}
+
+// TimeoutOrDefault returns the signal-loss timeout in effect.
+func (b FlightBuilder) TimeoutOrDefault() time.Duration {
+	if b.Timeout > 0 {
+		return b.Timeout
+	}
+	return DefaultFlightTimeout
+}
+
+// Advance feeds one aircraft's new states into its track. States no newer
+// than the track are ignored. It returns the updated track and every flight
+// the states opened, extended or closed.
+func (b FlightBuilder) Advance(track FlightTrack, states []Telemetry) (FlightTrack, []Flight) {
+	sorted := slices.Clone(states)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].LastContact.Before(sorted[j].LastContact)
+	})
+
+	var touched []*Flight
+	touch := func(f *Flight) {
+		if len(touched) == 0 || touched[len(touched)-1] != f {
+			touched = append(touched, f)
+		}
+	}
+
+	for _, s := range sorted {
+		if !s.LastContact.After(track.LastSeen) {
+			continue
+		}
+		recent := !track.LastSeen.IsZero() && s.LastContact.Sub(track.LastSeen) <= b.TimeoutOrDefault()
+
+		if track.Flight != nil && !recent {
+			track.Flight.Status = FlightLost
+			touch(track.Flight)
+			track.Flight = nil
+		}
+
+		switch {
+		case s.OnGround && track.Flight != nil:
+			f := track.Flight
+			f.observe(s)
+			landing := s.LastContact
+			f.Landing = &landing
+			f.Status = FlightLanded
+			touch(f)
+			track.Flight = nil
+
+		case !s.OnGround:
+			if track.Flight == nil {
+				track.Flight = newFlight(s)
+				if recent && track.OnGround {
+					takeoff := s.LastContact
+					track.Flight.Takeoff = &takeoff
+				}
+			}
+			track.Flight.observe(s)
+			touch(track.Flight)
+		}
+
+		track.ICAO24 = s.ICAO24
+		track.LastSeen = s.LastContact
+		track.OnGround = s.OnGround
+	}
+
+	flights := make([]Flight, 0, len(touched))
+	for _, f := range touched {
+		flights = append(flights, *f)
+	}
+	return track, flights
+}
+
+// FlightID names the flight of icao24 first seen airborne at firstSeen.
+func FlightID(icao24 string, firstSeen time.Time) string {
+	return fmt.Sprintf("%s-%s", icao24, firstSeen.UTC().Format("20060102T150405Z"))
+}
+
+func newFlight(s Telemetry) *Flight {
+	return &Flight{
+		ID:        FlightID(s.ICAO24, s.LastContact),
+		ICAO24:    s.ICAO24,
+		Status:    FlightActive,
+		FirstSeen: s.LastContact,
+	}
+}
+
+// observe adds a state to the flight's summary.
+func (f *Flight) observe(s Telemetry) {
+	f.LastSeen = s.LastContact
+	f.Observations++
+
+	if cs := NormalizeCallsign(s.Callsign); cs != nil {
+		f.Callsign = cs
+		if !slices.Contains(f.Callsigns, *cs) {
+			f.Callsigns = append(f.Callsigns, *cs)
+		}
+	}
+	if s.OriginCountry != "" {
+		f.OriginCountry = s.OriginCountry
+	}
+	if s.Category != 0 {
+		f.Category = s.Category
+	}
+
+	alt := s.BaroAltitude
+	if alt == nil {
+		alt = s.GeoAltitude
+	}
+	climbed := f.MaxAltitude != nil && *f.MaxAltitude > ApproachHeight
+	f.MaxAltitude = maxOf(f.MaxAltitude, alt)
+	f.MaxVelocity = maxOf(f.MaxVelocity, s.Velocity)
+
+	if s.Latitude == nil || s.Longitude == nil {
+		return
+	}
+	lat, lon := *s.Latitude, *s.Longitude
+	if f.EndLatitude != nil && f.EndLongitude != nil {
+		f.Distance += GreatCircleDistance(*f.EndLatitude, *f.EndLongitude, lat, lon)
+	}
+	if f.StartLatitude == nil {
+		f.StartLatitude, f.StartLongitude, f.StartAltitude = &lat, &lon, alt
+	}
+	// a fix without an altitude cannot be matched to an airport, so the
+	// departure fix moves on to the first with one while the aircraft may
+	// still be climbing out
+	if f.DepartureLatitude == nil || (f.DepartureAltitude == nil && alt != nil && !climbed) {
+		depLat, depLon := lat, lon
+		f.DepartureLatitude, f.DepartureLongitude, f.DepartureAltitude = &depLat, &depLon, alt
+	}
+	endLat, endLon := lat, lon
+	f.EndLatitude, f.EndLongitude, f.EndAltitude = &endLat, &endLon, alt
+}
@@ -0,0 +1,144 @@
+package domain_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/northeastloon/flight_tracker/internal/domain"
+)
+
+var flightStart = time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+
+// state is a state of aircraft 4ca7b4 at minute m, at latitude 53 plus m
+// hundredths of a degree.
+func state(m int, onGround bool, callsign string) domain.Telemetry {
+	lat, lon, alt := 53+float64(m)/100, -6.0, float64(m)*300
+	t := domain.Telemetry{
+		ICAO24:        "4ca7b4",
+		OriginCountry: "Ireland",
+		LastContact:   flightStart.Add(time.Duration(m) * time.Minute),
+		OnGround:      onGround,
+		Latitude:      &lat,
+		Longitude:     &lon,
+	}
+	if !onGround {
+		t.BaroAltitude = &alt
+	}
+	if callsign != "" {
+		cs := callsign + " "
+		t.Callsign = &cs
+	}
+	return t
+}
+
+func TestFlightBuilderSegmentsFlights(t *testing.T) {
+	b := domain.FlightBuilder{Timeout: 10 * time.Minute}
+
+	track, flights := b.Advance(domain.FlightTrack{}, []domain.Telemetry{
+		state(2, false, "RYR12"), // out of order
+		state(0, true, "RYR12"),
+		state(1, false, "RYR12"),
+		state(1, false, "RYR12"), // repeated
+	})
+	if len(flights) != 1 || track.Flight == nil {
+		t.Fatalf("got %d flights and open flight %v, want one open flight", len(flights), track.Flight)
+	}
+	f := flights[0]
+	if f.Status != domain.FlightActive || f.Observations != 2 {
+		t.Errorf("status %q with %d observations, want active with 2", f.Status, f.Observations)
+	}
+	if f.Takeoff == nil || !f.Takeoff.Equal(state(1, false, "").LastContact) {
+		t.Errorf("takeoff = %v, want minute 1", f.Takeoff)
+	}
+	if f.ID != "4ca7b4-20260301T080100Z" {
+		t.Errorf("ID = %q", f.ID)
+	}
+
+	// a renumbered flight keeps its identity, then lands
+	track, flights = b.Advance(track, []domain.Telemetry{
+		state(3, false, "RYR12X"),
+		state(4, true, "RYR12X"),
+		state(5, true, "RYR12X"),
+	})
+	if len(flights) != 1 || track.Flight != nil {
+		t.Fatalf("got %d flights and open flight %v, want one closed flight", len(flights), track.Flight)
+	}
+	f = flights[0]
+	if f.ID != "4ca7b4-20260301T080100Z" || f.Status != domain.FlightLanded {
+		t.Errorf("flight %s is %s, want the same flight landed", f.ID, f.Status)
+	}
+	if f.Landing == nil || !f.Landing.Equal(state(4, true, "").LastContact) {
+		t.Errorf("landing = %v, want minute 4", f.Landing)
+	}
+	if *f.Callsign != "RYR12X" || !slices.Equal(f.Callsigns, []string{"RYR12", "RYR12X"}) {
+		t.Errorf("callsigns = %q %v", *f.Callsign, f.Callsigns)
+	}
+	if *f.MaxAltitude != 900 || *f.StartLatitude != 53.01 || *f.EndLatitude != 53.04 {
+		t.Errorf("summary = alt %v, start %v, end %v", *f.MaxAltitude, *f.StartLatitude, *f.EndLatitude)
+	}
+	if f.Distance < 3000 || f.Distance > 3500 {
+		t.Errorf("distance = %v, want about 3.3km", f.Distance)
+	}
+}
+
+func TestFlightBuilderClosesLostFlights(t *testing.T) {
+	b := domain.FlightBuilder{Timeout: 10 * time.Minute}
+
+	track, _ := b.Advance(domain.FlightTrack{}, []domain.Telemetry{state(0, false, "EIN1")})
+	track, flights := b.Advance(track, []domain.Telemetry{state(30, false, "EIN1")})
+
+	if len(flights) != 2 {
+		t.Fatalf("got %d flights, want the lost one and a new one", len(flights))
+	}
+	if flights[0].Status != domain.FlightLost || flights[0].Observations != 1 {
+		t.Errorf("first flight = %+v, want lost after one observation", flights[0])
+	}
+	if flights[1].Status != domain.FlightActive || flights[1].Takeoff != nil {
+		t.Errorf("second flight = %+v, want active without a takeoff", flights[1])
+	}
+	if track.Flight == nil || track.Flight.ID != flights[1].ID {
+		t.Errorf("open flight = %v, want %s", track.Flight, flights[1].ID)
+	}
+}
+
+func TestFlightBuilderDepartureFix(t *testing.T) {
+	// fix is an airborne state at minute m, without an altitude when alt is
+	// negative
+	fix := func(m int, alt float64) domain.Telemetry {
+		s := state(m, false, "")
+		s.BaroAltitude = nil
+		if alt >= 0 {
+			s.BaroAltitude = &alt
+		}
+		return s
+	}
+
+	tests := []struct {
+		name   string
+		states []domain.Telemetry
+		minute int // of the departure fix
+	}{
+		{"low first fix", []domain.Telemetry{fix(0, 300), fix(1, 600)}, 0},
+		{"first fix without altitude", []domain.Telemetry{fix(0, -1), fix(1, 400), fix(2, 800)}, 1},
+		{"high airport", []domain.Telemetry{fix(0, -1), fix(1, 1800)}, 1},
+		{"seen high first", []domain.Telemetry{fix(0, 2000), fix(1, 500)}, 0},
+		{"climbed before any altitude", []domain.Telemetry{fix(0, -1), fix(1, 1500), fix(2, 300)}, 1},
+		{"never an altitude", []domain.Telemetry{fix(0, -1), fix(1, -1)}, 0},
+	}
+
+	for _, tt := range tests {
+		_, flights := domain.FlightBuilder{}.Advance(domain.FlightTrack{}, tt.states)
+		if len(flights) != 1 {
+			t.Fatalf("%s: got %d flights, want 1", tt.name, len(flights))
+		}
+		f, want := flights[0], tt.states[tt.minute]
+		if f.DepartureLatitude == nil || *f.DepartureLatitude != *want.Latitude {
+			t.Errorf("%s: departure latitude = %v, want minute %d", tt.name, f.DepartureLatitude, tt.minute)
+		}
+		if (f.DepartureAltitude == nil) != (want.BaroAltitude == nil) ||
+			(f.DepartureAltitude != nil && *f.DepartureAltitude != *want.BaroAltitude) {
+			t.Errorf("%s: departure altitude = %v, want %v", tt.name, f.DepartureAltitude, want.BaroAltitude)
+		}
+	}
+}
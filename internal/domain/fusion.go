@@ -0,0 +1,265 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/northeastloon/flight_tracker/internal/metrics"
+)
+
+// fusionTieWindow is how close two position timestamps must be for the more
+// accurate position source to win over the fresher one.
+const fusionTieWindow = 2 * time.Second
+
+// positionRank orders position_source values from most to least accurate:
+// ADS-B and FLARM report GPS positions, ASTERIX and TIS-B are radar-derived
+// and MLAT is a multilateration estimate.
+var positionRank = map[int]int{
+	0: 0, // ADS-B
+	3: 1, // FLARM
+	1: 2, // ASTERIX
+	4: 3, // TIS-B
+	2: 4, // MLAT
+}
+
+// Feed is one provider taking part in fusion. Name matches the Source the
+// provider sets on its telemetry.
+type Feed struct {
+	Name     string
+	Provider FlightDataProvider
+}
+
+// FusedProvider fetches several feeds concurrently and merges their
+// snapshots into one, with a single state per aircraft.
+type FusedProvider struct {
+	feeds []Feed
+
+	mu      sync.Mutex
+	backoff map[string]time.Time // feed name -> earliest next fetch
+}
+
+var (
+	_ FlightDataProvider = (*FusedProvider)(nil)
+	_ RateLimitReporter  = (*FusedProvider)(nil)
+)
+
+func NewFusedProvider(feeds ...Feed) *FusedProvider {
+	return &FusedProvider{
+		feeds:   feeds,
+		backoff: make(map[string]time.Time),
+	}
+}
+
+// FetchTelemetry fetches every feed and fuses the results. A feed that fails
+// is logged and left out; an error is returned only when no feed succeeded.
+// A feed that asked to back off is skipped until its retry-after has passed,
+// so one rate-limited feed does not hold back the others.
+func (f *FusedProvider) FetchTelemetry(ctx context.Context) ([]Telemetry, error) {
+	type result struct {
+		data []Telemetry
+		err  error
+	}
+
+	now := time.Now()
+	results := make([]*result, len(f.feeds))
+	var wg sync.WaitGroup
+
+	for i, feed := range f.feeds {
+		if f.backingOff(feed.Name, now) {
+			continue
+		}
+
+		results[i] = &result{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data, err := feed.Provider.FetchTelemetry(ctx)
+			*results[i] = result{data: data, err: err}
+			f.observeRetryAfter(feed)
+		}()
+	}
+	wg.Wait()
+
+	var snapshots [][]Telemetry
+	var errs []error
+	for i, r := range results {
+		if r == nil {
+			continue
+		}
+		if r.err != nil {
+			slog.Warn("Feed fetch failed", "feed", f.feeds[i].Name, "error", r.err)
+			errs = append(errs, fmt.Errorf("%s: %w", f.feeds[i].Name, r.err))
+			continue
+		}
+		snapshots = append(snapshots, r.data)
+	}
+
+	if len(snapshots) == 0 {
+		if len(errs) == 0 {
+			return nil, errors.New("every feed is backing off")
+		}
+		return nil, errors.Join(errs...)
+	}
+
+	return FuseTelemetry(snapshots...), nil
+}
+
+func (f *FusedProvider) backingOff(name string, now time.Time) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return now.Before(f.backoff[name])
+}
+
+func (f *FusedProvider) observeRetryAfter(feed Feed) {
+	r, ok := feed.Provider.(RateLimitReporter)
+	if !ok {
+		return
+	}
+	rl, ok := r.RateLimit()
+	if !ok || rl.RetryAfter <= 0 {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.backoff[feed.Name] = time.Now().Add(rl.RetryAfter)
+}
+
+// RateLimit reports the budget of the feed with the fewest credits left, so
+// the schedule is paced by the tightest feed. Retry-after is handled per feed
+// by FetchTelemetry and is not passed on.
+func (f *FusedProvider) RateLimit() (RateLimit, bool) {
+	var tightest RateLimit
+	found := false
+
+	for _, feed := range f.feeds {
+		r, ok := feed.Provider.(RateLimitReporter)
+		if !ok {
+			continue
+		}
+		rl, ok := r.RateLimit()
+		if !ok || rl.Remaining < 0 {
+			continue
+		}
+		if !found || rl.Remaining < tightest.Remaining {
+			tightest = RateLimit{Remaining: rl.Remaining, ResetAt: rl.ResetAt}
+			found = true
+		}
+	}
+
+	return tightest, found
+}
+
+// FuseTelemetry merges snapshots from different feeds into one state per
+// aircraft. The state with the freshest position wins; positions reported
+// within fusionTieWindow of each other are decided by position source
+// accuracy, then by last contact. Identity fields the winner lacks are filled
+// in from the other reports, and Sources lists every feed that saw the
+// aircraft.
+func FuseTelemetry(snapshots ...[]Telemetry) []Telemetry {
+	byAircraft := make(map[string][]Telemetry)
+	var order []string
+
+	for _, snapshot := range snapshots {
+		for _, t := range snapshot {
+			if _, ok := byAircraft[t.ICAO24]; !ok {
+				order = append(order, t.ICAO24)
+			}
+			byAircraft[t.ICAO24] = append(byAircraft[t.ICAO24], t)
+		}
+	}
+
+	fused := make([]Telemetry, 0, len(order))
+	for _, icao24 := range order {
+		fused = append(fused, fuseAircraft(byAircraft[icao24]))
+	}
+
+	return fused
+}
+
+func fuseAircraft(reports []Telemetry) Telemetry {
+	best := reports[0]
+	for _, r := range reports[1:] {
+		if betterFix(r, best) {
+			best = r
+		}
+	}
+
+	var sources []string
+	for _, r := range reports {
+		if !slices.Contains(sources, r.Source) {
+			sources = append(sources, r.Source)
+		}
+
+		if best.Callsign == nil {
+			best.Callsign = r.Callsign
+		}
+		if best.Squawk == nil {
+			best.Squawk = r.Squawk
+		}
+		if best.OriginCountry == "" {
+			best.OriginCountry = r.OriginCountry
+		}
+		if best.Category == 0 {
+			best.Category = r.Category
+		}
+	}
+	slices.Sort(sources)
+	best.Sources = sources
+
+	for _, source := range sources {
+		outcome := "superseded"
+		if source == best.Source {
+			outcome = "selected"
+		}
+		metrics.FusedStates.WithLabelValues(source, outcome).Inc()
+	}
+
+	return best
+}
+
+// betterFix reports whether a should be preferred over b.
+func betterFix(a, b Telemetry) bool {
+	aPos := a.Latitude != nil && a.Longitude != nil
+	bPos := b.Latitude != nil && b.Longitude != nil
+	if aPos != bPos {
+		return aPos
+	}
+
+	aTime, bTime := fixTime(a), fixTime(b)
+	if d := aTime.Sub(bTime); d > fusionTieWindow || d < -fusionTieWindow {
+		return d > 0
+	}
+
+	if ra, rb := rank(a.PositionSource), rank(b.PositionSource); ra != rb {
+		return ra < rb
+	}
+
+	if !a.LastContact.Equal(b.LastContact) {
+		return a.LastContact.After(b.LastContact)
+	}
+
+	// deterministic choice between otherwise equal reports
+	return a.Source < b.Source
+}
+
+// fixTime is when the position was measured, or the last contact when the
+// provider does not report it separately.
+func fixTime(t Telemetry) time.Time {
+	if t.TimePosition != nil {
+		return *t.TimePosition
+	}
+	return t.LastContact
+}
+
+func rank(positionSource int) int {
+	if r, ok := positionRank[positionSource]; ok {
+		return r
+	}
+	return len(positionRank)
+}
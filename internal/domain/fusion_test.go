@@ -0,0 +1,92 @@
+package domain_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/northeastloon/flight_tracker/internal/domain"
+)
+
+var fusionTime = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// report is a state of aircraft 4ca7b4 from source, with a position measured
+// fix seconds after fusionTime, last heard one second later.
+func report(source string, fix int, positionSource int) domain.Telemetry {
+	lat, lon := 53.0, -6.0
+	at := fusionTime.Add(time.Duration(fix) * time.Second)
+	return domain.Telemetry{
+		Source:         source,
+		ICAO24:         "4ca7b4",
+		TimePosition:   &at,
+		LastContact:    at.Add(time.Second),
+		Latitude:       &lat,
+		Longitude:      &lon,
+		PositionSource: positionSource,
+	}
+}
+
+func TestFuseTelemetryPicksBestFix(t *testing.T) {
+	noPosition := report("b", 10, 0)
+	noPosition.Latitude, noPosition.Longitude = nil, nil
+	laterContact := report("b", 0, 0)
+	laterContact.LastContact = laterContact.LastContact.Add(time.Second)
+	noFixTime := report("b", 0, 0)
+	noFixTime.TimePosition = nil
+	noFixTime.LastContact = fusionTime.Add(5 * time.Second)
+
+	tests := []struct {
+		name string
+		a, b domain.Telemetry
+		want string // source of the winner
+	}{
+		{"position beats none", report("a", 0, 2), noPosition, "a"},
+		{"fresher fix beyond the tie window", report("a", 0, 0), report("b", 3, 2), "b"},
+		{"ADS-B beats MLAT within the window", report("a", 0, 0), report("b", 2, 2), "a"},
+		{"FLARM beats ASTERIX", report("a", 1, 1), report("b", 0, 3), "b"},
+		{"ASTERIX beats TIS-B", report("a", 0, 4), report("b", 0, 1), "b"},
+		{"TIS-B beats MLAT", report("a", 1, 2), report("b", 0, 4), "b"},
+		{"unknown source ranks last", report("a", 0, 9), report("b", 0, 2), "b"},
+		{"later contact breaks a rank tie", report("a", 0, 0), laterContact, "b"},
+		{"last contact stands in for the fix time", report("a", 0, 0), noFixTime, "b"},
+		{"source name breaks a full tie", report("b", 0, 0), report("a", 0, 0), "a"},
+	}
+
+	for _, tt := range tests {
+		// the winner does not depend on the order of the feeds
+		for _, pair := range [][2]domain.Telemetry{{tt.a, tt.b}, {tt.b, tt.a}} {
+			fused := domain.FuseTelemetry([]domain.Telemetry{pair[0]}, []domain.Telemetry{pair[1]})
+			if len(fused) != 1 {
+				t.Fatalf("%s: fused %d states, want 1", tt.name, len(fused))
+			}
+			if fused[0].Source != tt.want {
+				t.Errorf("%s: %s won with %s first, want %s", tt.name, fused[0].Source, pair[0].Source, tt.want)
+			}
+		}
+	}
+}
+
+func TestFuseTelemetryMergesReports(t *testing.T) {
+	callsign, squawk := "EIN12", "7000"
+	adsb := report("readsb", 0, 0)
+	mlat := report("opensky", -30, 2)
+	mlat.Callsign, mlat.Squawk, mlat.OriginCountry, mlat.Category = &callsign, &squawk, "Ireland", 4
+	other := report("opensky", 0, 0)
+	other.ICAO24 = "3c6444"
+
+	fused := domain.FuseTelemetry([]domain.Telemetry{mlat, other}, []domain.Telemetry{adsb})
+	if len(fused) != 2 || fused[0].ICAO24 != "4ca7b4" || fused[1].ICAO24 != "3c6444" {
+		t.Fatalf("fused = %+v, want one state per aircraft in order of first report", fused)
+	}
+
+	f := fused[0]
+	if f.Source != "readsb" || !slices.Equal(f.Sources, []string{"opensky", "readsb"}) {
+		t.Errorf("state from %s seen by %v, want readsb seen by opensky and readsb", f.Source, f.Sources)
+	}
+	if f.Callsign == nil || *f.Callsign != "EIN12" || f.Squawk == nil || *f.Squawk != "7000" || f.OriginCountry != "Ireland" || f.Category != 4 {
+		t.Errorf("identity not filled in from the superseded report: %+v", f)
+	}
+	if !slices.Equal(fused[1].Sources, []string{"opensky"}) {
+		t.Errorf("single report sources = %v", fused[1].Sources)
+	}
+}
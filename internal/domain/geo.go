@@ -0,0 +1,53 @@
+package domain
+
+import "math"
+
+// EarthRadiusMeters is the mean earth radius used for great-circle maths.
+const EarthRadiusMeters = 6371008.8
+
+// GreatCircleDistance returns the haversine distance in metres between two
+// WGS-84 points given in decimal degrees.
+func GreatCircleDistance(lat1, lon1, lat2, lon2 float64) float64 {
+	φ1 := lat1 * math.Pi / 180
+	φ2 := lat2 * math.Pi / 180
+	Δφ := (lat2 - lat1) * math.Pi / 180
+	Δλ := (lon2 - lon1) * math.Pi / 180
+
+	a := math.Sin(Δφ/2)*math.Sin(Δφ/2) +
+		math.Cos(φ1)*math.Cos(φ2)*math.Sin(Δλ/2)*math.Sin(Δλ/2)
+
+	return 2 * EarthRadiusMeters * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+// InitialBearing returns the true course in degrees, from 0 up to 360, at the
+// start of the great circle from the first point to the second.
+func InitialBearing(lat1, lon1, lat2, lon2 float64) float64 {
+	φ1 := lat1 * math.Pi / 180
+	φ2 := lat2 * math.Pi / 180
+	Δλ := (lon2 - lon1) * math.Pi / 180
+
+	y := math.Sin(Δλ) * math.Cos(φ2)
+	x := math.Cos(φ1)*math.Sin(φ2) - math.Sin(φ1)*math.Cos(φ2)*math.Cos(Δλ)
+
+	return math.Mod(math.Atan2(y, x)*180/math.Pi+360, 360)
+}
+
+// IntermediatePoint returns the point the given fraction of the way along the
+// great circle from the first point to the second.
+func IntermediatePoint(lat1, lon1, lat2, lon2, fraction float64) (lat, lon float64) {
+	φ1, λ1 := lat1*math.Pi/180, lon1*math.Pi/180
+	φ2, λ2 := lat2*math.Pi/180, lon2*math.Pi/180
+
+	δ := GreatCircleDistance(lat1, lon1, lat2, lon2) / EarthRadiusMeters
+	if δ == 0 {
+		return lat1, lon1
+	}
+	a := math.Sin((1-fraction)*δ) / math.Sin(δ)
+	b := math.Sin(fraction*δ) / math.Sin(δ)
+
+	x := a*math.Cos(φ1)*math.Cos(λ1) + b*math.Cos(φ2)*math.Cos(λ2)
+	y := a*math.Cos(φ1)*math.Sin(λ1) + b*math.Cos(φ2)*math.Sin(λ2)
+	z := a*math.Sin(φ1) + b*math.Sin(φ2)
+
+	return math.Atan2(z, math.Hypot(x, y)) * 180 / math.Pi, math.Atan2(y, x) * 180 / math.Pi
+}
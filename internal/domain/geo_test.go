@@ -0,0 +1,41 @@
+package domain_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/northeastloon/flight_tracker/internal/domain"
+)
+
+func TestGreatCircleDistance(t *testing.T) {
+	degree := domain.EarthRadiusMeters * math.Pi / 180
+
+	tests := []struct {
+		name                   string
+		lat1, lon1, lat2, lon2 float64
+		want                   float64 // metres
+	}{
+		{"same point", 53.4, -6.3, 53.4, -6.3, 0},
+		{"one degree of latitude", 10, 20, 11, 20, degree},
+		{"one degree along the equator", 0, 20, 0, 21, degree},
+		{"across the antimeridian", 0, 179, 0, -179, 2 * degree},
+		{"quarter of the equator", 0, 0, 0, 90, 90 * degree},
+		{"pole to pole", 90, 0, -90, 0, 180 * degree},
+		{"antipodes", 30, 45, -30, -135, 180 * degree},
+		{"Dublin to Heathrow", 53.4213, -6.2701, 51.4700, -0.4543, 449_000},
+	}
+
+	for _, tt := range tests {
+		tolerance := 1e-6 * max(tt.want, 1)
+		if tt.name == "Dublin to Heathrow" {
+			tolerance = 1000
+		}
+		got := domain.GreatCircleDistance(tt.lat1, tt.lon1, tt.lat2, tt.lon2)
+		if math.Abs(got-tt.want) > tolerance {
+			t.Errorf("%s: distance = %.1f m, want %.1f", tt.name, got, tt.want)
+		}
+		if back := domain.GreatCircleDistance(tt.lat2, tt.lon2, tt.lat1, tt.lon1); math.Abs(back-got) > 1e-6 {
+			t.Errorf("%s: distance back = %.1f m, want %.1f", tt.name, back, got)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package domain_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/northeastloon/flight_tracker/internal/domain"
+	"github.com/northeastloon/flight_tracker/internal/provider"
+	"github.com/northeastloon/flight_tracker/internal/provider/openskytest"
+)
+
+type memoryStore struct {
+	mu        sync.Mutex
+	snapshots [][]domain.Telemetry
+}
+
+func (m *memoryStore) StoreTelemetry(ctx context.Context, data []domain.Telemetry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.snapshots = append(m.snapshots, data)
+	return nil
+}
+
+func (m *memoryStore) GetTelemetry(ctx context.Context, filter *domain.TelemetryFilter) ([]domain.Telemetry, error) {
+	return nil, nil
+}
+
+func (m *memoryStore) stored() [][]domain.Telemetry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([][]domain.Telemetry(nil), m.snapshots...)
+}
+
+// fastSchedule runs cycles every 10ms; MaxInterval stops the credit budget
+// from spreading them out until midnight.
+var fastSchedule = domain.Schedule{Interval: 10 * time.Millisecond, MaxInterval: 20 * time.Millisecond}
+
+// runLoop runs the ingestion loop until done reports true, then stops it and
+// waits for it to return.
+func runLoop(t *testing.T, s *domain.FlightDataService, done func() bool) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- s.StartIngestionLoop(ctx, fastSchedule) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			cancel()
+			<-errc
+			t.Fatal("timed out waiting for the ingestion loop")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	cancel()
+	if err := <-errc; err != nil {
+		t.Fatalf("StartIngestionLoop: %v", err)
+	}
+}
+
+func newSimulatedServer(t *testing.T, opts ...openskytest.Option) *openskytest.Server {
+	t.Helper()
+	sim, err := provider.NewSimulatorProvider(provider.WithSimulatorAircraft(20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := openskytest.NewServer(append([]openskytest.Option{openskytest.WithProvider(sim)}, opts...)...)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestIngestionLoopRecoversFromFaults(t *testing.T) {
+	srv := newSimulatedServer(t, openskytest.WithRateLimit(4000, time.Second))
+	srv.Inject(
+		openskytest.Fault{}, // the first cycle succeeds
+		openskytest.FaultUnavailable,
+		openskytest.FaultTruncated,
+		openskytest.FaultEmpty,
+	)
+
+	store := &memoryStore{}
+	client := provider.NewOpenSkyClient(provider.WithBaseURL(srv.StatesURL()))
+	svc := domain.NewFlightDataService(client, store)
+
+	runLoop(t, svc, func() bool { return len(store.stored()) >= 3 })
+
+	requests := srv.Requests()
+	if len(requests) < 6 {
+		t.Fatalf("got %d requests, want at least 6", len(requests))
+	}
+	if requests[1].Status != http.StatusServiceUnavailable {
+		t.Errorf("second request status = %d, want 503", requests[1].Status)
+	}
+
+	for i, snapshot := range store.stored() {
+		if len(snapshot) == 0 {
+			t.Fatalf("snapshot %d is empty", i)
+		}
+		for _, tel := range snapshot {
+			if tel.Source != "opensky" || tel.ICAO24 == "" || tel.LastContact.IsZero() {
+				t.Fatalf("snapshot %d holds %+v", i, tel)
+			}
+		}
+	}
+
+	st := svc.Status()
+	if st.ConsecutiveFail != 0 || st.LastSuccess.IsZero() || st.LastError == "" {
+		t.Errorf("status = %+v, want a success after earlier errors", st)
+	}
+	if st.RateLimitRemaining == nil || *st.RateLimitRemaining >= 4000 {
+		t.Errorf("RateLimitRemaining = %v, want the credits left", st.RateLimitRemaining)
+	}
+}
+
+func TestIngestionLoopHonoursRetryAfter(t *testing.T) {
+	const retryAfter = 300 * time.Millisecond
+	srv := newSimulatedServer(t, openskytest.WithRateLimit(4000, retryAfter))
+	srv.Inject(openskytest.Fault{}, openskytest.FaultTooManyRequests)
+
+	store := &memoryStore{}
+	client := provider.NewOpenSkyClient(provider.WithBaseURL(srv.StatesURL()))
+	svc := domain.NewFlightDataService(client, store)
+
+	runLoop(t, svc, func() bool { return len(srv.Requests()) >= 4 })
+
+	requests := srv.Requests()
+	if requests[1].Status != http.StatusTooManyRequests {
+		t.Fatalf("second request status = %d, want 429", requests[1].Status)
+	}
+	if gap := requests[2].Time.Sub(requests[1].Time); gap < retryAfter {
+		t.Errorf("retried after %v, want at least %v", gap, retryAfter)
+	}
+	if gap := requests[3].Time.Sub(requests[2].Time); gap >= retryAfter {
+		t.Errorf("kept backing off for %v after a successful request", gap)
+	}
+	if len(store.stored()) < 2 {
+		t.Errorf("stored %d snapshots, want at least 2", len(store.stored()))
+	}
+}
+
+// countingProvider returns snapshots holding one aircraft whose address
+// counts the fetches.
+type countingProvider struct {
+	mu sync.Mutex
+	n  int
+}
+
+func (p *countingProvider) FetchTelemetry(ctx context.Context) ([]domain.Telemetry, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.n++
+	return []domain.Telemetry{{Source: "count", ICAO24: fmt.Sprintf("%06x", p.n), LastContact: time.Now()}}, nil
+}
+
+// slowStore takes a while over every snapshot, so fetching outpaces it.
+type slowStore struct{ memoryStore }
+
+func (s *slowStore) StoreTelemetry(ctx context.Context, data []domain.Telemetry) error {
+	time.Sleep(5 * time.Millisecond)
+	return s.memoryStore.StoreTelemetry(ctx, data)
+}
+
+func TestIngestionLoopBackpressure(t *testing.T) {
+	store := &slowStore{}
+	svc := domain.NewFlightDataService(&countingProvider{}, store,
+		domain.WithQueue(1, 1),
+		domain.WithBackpressure(),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		errc <- svc.StartIngestionLoop(ctx, domain.Schedule{Interval: time.Microsecond, MaxInterval: time.Microsecond})
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(store.stored()) < 10 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-errc; err != nil {
+		t.Fatalf("StartIngestionLoop: %v", err)
+	}
+
+	// nothing is dropped, and snapshots are stored in the order fetched
+	for i, snapshot := range store.stored() {
+		if want := fmt.Sprintf("%06x", i+1); snapshot[0].ICAO24 != want {
+			t.Fatalf("snapshot %d holds %s, want %s", i, snapshot[0].ICAO24, want)
+		}
+	}
+	if len(store.stored()) < 10 {
+		t.Fatalf("stored %d snapshots, want at least 10", len(store.stored()))
+	}
+}
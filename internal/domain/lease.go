@@ -0,0 +1,69 @@
+package domain
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// LeaderLease is held by at most one replica at a time. Only the holder
+// ingests, so replicas sharing a database do not fetch the same snapshot.
+type LeaderLease interface {
+	// TryAcquire takes or renews the lease and reports whether this replica
+	// holds it.
+	TryAcquire(ctx context.Context) (bool, error)
+	// Release gives the lease up so another replica can take over at once.
+	Release(ctx context.Context) error
+}
+
+// LeaseStatus describes the current holder of a lease.
+type LeaseStatus struct {
+	Name       string
+	Holder     string
+	AcquiredAt time.Time
+	RenewedAt  time.Time
+	ExpiresAt  time.Time
+	Valid      bool // false once ExpiresAt has passed
+}
+
+// renewLease makes one attempt to take or renew the lease and publishes the
+// outcome in the ingest status. A replica that cannot reach the database
+// stops counting itself leader, since its lease may expire in the meantime.
+func (s *FlightDataService) renewLease(ctx context.Context) {
+	leader, err := s.lease.TryAcquire(ctx)
+	if err != nil && ctx.Err() == nil {
+		slog.Error("Error renewing ingest lease", "error", err)
+	}
+
+	s.status.update(func(st *IngestStatus) {
+		if leader && !st.Leader {
+			slog.Info("Acquired ingest lease")
+		} else if !leader && st.Leader {
+			slog.Warn("Lost ingest lease")
+		}
+		st.Leader = leader
+	})
+}
+
+// keepLease renews the lease every renewEvery until ctx is cancelled, then
+// releases it.
+func (s *FlightDataService) keepLease(ctx context.Context, renewEvery time.Duration) {
+	ticker := time.NewTicker(renewEvery)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.renewLease(ctx)
+		case <-ctx.Done():
+			s.status.update(func(st *IngestStatus) { st.Leader = false })
+
+			releaseCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), renewEvery)
+			defer cancel()
+			if err := s.lease.Release(releaseCtx); err != nil {
+				slog.Error("Error releasing ingest lease", "error", err)
+			}
+			return
+		}
+	}
+}
@@ -4,10 +4,19 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/northeastloon/flight_tracker/internal/metrics"
+	"github.com/northeastloon/flight_tracker/internal/tracing"
+	"go.opentelemetry.io/otel/codes"
 )
 
+// Telemetry is one aircraft state vector, normalized from any provider.
 type Telemetry struct {
+	Source         string   // provider that reported the state, e.g. "opensky"
+	Sources        []string // every provider that saw the aircraft in the same fused snapshot; nil means just Source
 	ICAO24         string
 	Callsign       *string
 	OriginCountry  string
@@ -26,83 +35,367 @@ type Telemetry struct {
 	SPI            bool
 	PositionSource int
 	Category       int
+
+	// Provider-specific fields with no normalized equivalent, stored as-is.
+	Extras map[string]any
+
+	// Human readable labels resolved from the lookup tables.
+	PositionSourceLabel *string
+	CategoryLabel       *string
+
+	// Registry is the aircraft's registry entry, when the store knows it.
+	Registry *AircraftInfo
+
+	// Airline is the airline of the callsign, when the store knows it.
+	Airline *Airline
 }
 
 type TelemetryFilter struct {
+	Source        *string
 	ICAO24        *string
 	Callsign      *string
 	OriginCountry *string
 	TimePosition  *time.Time
-	LastContact   *time.Time
+	From          *time.Time // last_contact lower bound, inclusive
+	To            *time.Time // last_contact upper bound, inclusive
 	Squawk        *string
 	Category      *int
-	Position      *struct {
-		Latitude  float64
-		Longitude float64
-		Radius    float64 // in kilometers
-	}
-	Latest *bool
+	TypeCode      *string // registry ICAO type designator
+	Operator      *string // registry ICAO airline designator
+	Airline       *string // ICAO airline designator of the callsign
+	Position      *PositionFilter
+	Latest        *bool
+}
+
+type PositionFilter struct {
+	Latitude  float64
+	Longitude float64
+	Radius    float64 // in kilometers
 }
 
-type FlightDataProvider[T any] interface {
-	FetchTelemetry(ctx context.Context) (T, error)
+// FlightDataProvider fetches a snapshot of aircraft states, normalized into
+// Telemetry with Source set.
+type FlightDataProvider interface {
+	FetchTelemetry(ctx context.Context) ([]Telemetry, error)
 }
 
-type FlightDataStore[T any] interface {
-	StoreTelemetry(ctx context.Context, data T) error
+// FlightDataStore persists normalized snapshots from any provider.
+type FlightDataStore interface {
+	StoreTelemetry(ctx context.Context, data []Telemetry) error
 	GetTelemetry(ctx context.Context, filter *TelemetryFilter) ([]Telemetry, error)
 }
 
-type FlightDataService[T any] struct {
-	provider FlightDataProvider[T]
-	store    FlightDataStore[T]
+type FlightDataService struct {
+	provider      FlightDataProvider
+	store         FlightDataStore
+	status        ingestTracker
+	shutdownGrace time.Duration
+	lease         LeaderLease
+	leaseTTL      time.Duration
+	queue         chan []Telemetry
+	storeWorkers  int
+	spill         SpillBuffer  // nil drops snapshots that cannot be stored
+	pending       atomic.Int64 // snapshots queued or being stored
+	backpressure  bool
+	clock         Clock
+}
+
+// DefaultShutdownGrace is how long an in-flight ingest may keep running after
+// the ingestion loop's context is cancelled.
+const DefaultShutdownGrace = 10 * time.Second
+
+type serviceOptions struct {
+	shutdownGrace time.Duration
+	lease         LeaderLease
+	leaseTTL      time.Duration
+	queueSize     int
+	storeWorkers  int
+	spill         SpillBuffer
+	backpressure  bool
+	clock         Clock
+}
+
+type ServiceOption func(o *serviceOptions)
+
+// WithShutdownGrace sets how long an in-flight ingest may keep running to
+// commit or roll back once the ingestion loop is asked to stop.
+func WithShutdownGrace(d time.Duration) ServiceOption {
+	return func(o *serviceOptions) {
+		o.shutdownGrace = d
+	}
+}
+
+// WithLeaderLease makes the ingestion loop ingest only while lease is held.
+// The lease is renewed three times per ttl, so a replica that dies is
+// replaced within about ttl.
+func WithLeaderLease(lease LeaderLease, ttl time.Duration) ServiceOption {
+	return func(o *serviceOptions) {
+		o.lease = lease
+		o.leaseTTL = ttl
+	}
+}
+
+func NewFlightDataService(provider FlightDataProvider, store FlightDataStore, opts ...ServiceOption) *FlightDataService {
+	o := serviceOptions{
+		shutdownGrace: DefaultShutdownGrace,
+		queueSize:     DefaultQueueSize,
+		storeWorkers:  DefaultStoreWorkers,
+		clock:         systemClock{},
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	s := &FlightDataService{
+		provider:      provider,
+		store:         store,
+		shutdownGrace: o.shutdownGrace,
+		lease:         o.lease,
+		leaseTTL:      o.leaseTTL,
+		queue:         make(chan []Telemetry, max(o.queueSize, 1)),
+		storeWorkers:  max(o.storeWorkers, 1),
+		spill:         o.spill,
+		backpressure:  o.backpressure,
+		clock:         o.clock,
+	}
+	s.status.update(func(st *IngestStatus) { st.Leader = o.lease == nil })
+
+	return s
 }
 
-func NewFlightDataService[T any](provider FlightDataProvider[T], store FlightDataStore[T]) *FlightDataService[T] {
-	return &FlightDataService[T]{
-		provider: provider,
-		store:    store,
+// IngestData fetches one snapshot and stores it before returning.
+func (s *FlightDataService) IngestData(ctx context.Context) (err error) {
+	ctx, span := tracing.Tracer("ingest").Start(ctx, "ingest.once")
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	data, err := s.fetchSnapshot(ctx)
+	if err != nil {
+		return err
 	}
+
+	return s.storeSnapshot(ctx, data)
 }
 
-func (s *FlightDataService[T]) IngestData(ctx context.Context) error {
+// fetchSnapshot retrieves one snapshot from the provider and records the
+// attempt.
+func (s *FlightDataService) fetchSnapshot(ctx context.Context) ([]Telemetry, error) {
+	started := time.Now()
+	s.status.update(func(st *IngestStatus) { st.LastAttempt = s.clock.Now() })
+
 	data, err := s.provider.FetchTelemetry(ctx)
+	fetchDuration := time.Since(started)
 	if err != nil {
-		return fmt.Errorf("failed to fetch telemetry: %w", err)
+		err = fmt.Errorf("failed to fetch telemetry: %w", err)
+		s.recordFailure(err, func(st *IngestStatus) { st.FetchDuration = fetchDuration })
+		return data, err
 	}
 
+	s.status.update(func(st *IngestStatus) { st.FetchDuration = fetchDuration })
+
+	normalizeCallsigns(data)
+	FillAddressCountries(data)
+	return data, nil
+}
+
+// storeSnapshot writes one snapshot and records the outcome.
+func (s *FlightDataService) storeSnapshot(ctx context.Context, data []Telemetry) error {
+	started := time.Now()
+
 	if err := s.store.StoreTelemetry(ctx, data); err != nil {
-		return fmt.Errorf("failed to store telemetry: %w", err)
+		err = fmt.Errorf("failed to store telemetry: %w", err)
+		s.recordFailure(err, func(st *IngestStatus) { st.StoreDuration = time.Since(started) })
+		return err
 	}
 
+	s.status.update(func(st *IngestStatus) {
+		st.LastSuccess = s.clock.Now()
+		st.StoreDuration = time.Since(started)
+		st.RowsLastIngest = len(data)
+		st.ConsecutiveFail = 0
+	})
+
 	return nil
 }
 
-func (s *FlightDataService[T]) StartIngestionLoop(ctx context.Context, runsPerDay int) error {
-	if runsPerDay <= 0 {
-		return fmt.Errorf("runsPerDay must be a positive integer")
+func (s *FlightDataService) recordFailure(err error, fn func(st *IngestStatus)) {
+	s.status.update(func(st *IngestStatus) {
+		st.LastError = err.Error()
+		st.LastErrorAt = s.clock.Now()
+		st.ConsecutiveFail++
+		fn(st)
+	})
+}
+
+// Status returns a snapshot of the ingestion loop's progress.
+func (s *FlightDataService) Status() IngestStatus {
+	st := s.status.get()
+	st.QueueDepth = len(s.queue)
+	st.QueueCapacity = cap(s.queue)
+	if s.spill != nil {
+		st.SpillSnapshots = s.spill.Len()
+		st.SpillBytes = s.spill.Size()
 	}
+	return st
+}
 
-	interval := time.Duration(1440/runsPerDay) * time.Minute
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
+// StartIngestionLoop fetches immediately and then on the schedule until ctx
+// is cancelled. Fetched snapshots are queued for the store workers, so a slow
+// store does not delay fetching; snapshots that cannot be queued or stored go
+// to the spill buffer and are replayed once the store recovers.
+//
+// Fetches never overlap: the next start is planned when a fetch finishes, and
+// one that overruns its interval is followed immediately by the next rather
+// than by a backlog of missed ticks. When ctx is cancelled, an in-flight
+// fetch and the queued snapshots are given the shutdown grace period to be
+// stored before their context is cancelled. With a leader lease, fetches are
+// skipped while another replica holds it. The loop returns nil after a clean
+// stop.
+func (s *FlightDataService) StartIngestionLoop(ctx context.Context, schedule Schedule) error {
+	if schedule.Interval <= 0 {
+		return fmt.Errorf("ingestion interval must be positive")
+	}
+	sched := newScheduler(schedule)
 
-	// Run the first ingestion immediately
-	if err := s.IngestData(ctx); err != nil {
-		// Log the error but continue the loop
-		slog.Error("Error during initial data ingestion", "error", err)
+	if s.lease != nil {
+		s.renewLease(ctx)
+
+		leaseCtx, stopLease := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			s.keepLease(leaseCtx, s.leaseTTL/3)
+		}()
+		defer func() {
+			stopLease()
+			<-done
+		}()
+	}
+
+	storeCtx, cancelStore := s.graceContext(ctx)
+	defer cancelStore()
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.runStoreWorkers(storeCtx, stop)
+	}()
+	if s.spill != nil {
+		s.reportSpill()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.replaySpill(storeCtx, stop)
+		}()
 	}
+	defer func() {
+		close(stop)
+		wg.Wait()
+	}()
+
+	// Run the first ingestion immediately
+	wait := s.clock.After(0)
 
 	for {
 		select {
-		case <-ticker.C:
-			if err := s.IngestData(ctx); err != nil {
-				// Log the error but continue the loop
-				slog.Error("Error during data ingestion", "error", err)
-			}
+		case <-wait:
 		case <-ctx.Done():
-			fmt.Println("Stopping ingestion loop due to context cancellation.")
-			return ctx.Err()
+			slog.Info("Stopping ingestion loop due to context cancellation")
+			return nil
 		}
+
+		start := s.clock.Now()
+		if err := s.ingestCycle(ctx); err != nil {
+			// Log the error but continue the loop
+			slog.Error("Error during data ingestion", "error", err)
+		}
+
+		now := s.clock.Now()
+		rl, haveRL := s.rateLimit()
+		interval := sched.interval(start, now, rl, haveRL)
+		delay := interval - now.Sub(start)
+		if delay < 0 {
+			slog.Warn("Ingestion cycle overran its interval, starting the next one now",
+				"elapsed", now.Sub(start), "interval", interval)
+			delay = 0
+		}
+
+		s.status.update(func(st *IngestStatus) {
+			st.Interval = interval
+			st.NextRun = now.Add(delay)
+			st.RateLimitRemaining = nil
+			if haveRL && rl.Remaining >= 0 {
+				remaining := rl.Remaining
+				st.RateLimitRemaining = &remaining
+			}
+		})
+		metrics.IngestInterval.Set(interval.Seconds())
+
+		wait = s.clock.After(delay)
+	}
+}
+
+// rateLimit returns the provider's request budget if it reports one.
+func (s *FlightDataService) rateLimit() (RateLimit, bool) {
+	if r, ok := s.provider.(RateLimitReporter); ok {
+		return r.RateLimit()
+	}
+	return RateLimit{}, false
+}
+
+// ingestCycle fetches one snapshot and queues it for storing, if this
+// replica holds the lease or runs without one.
+func (s *FlightDataService) ingestCycle(ctx context.Context) (err error) {
+	if ctx.Err() != nil || !s.status.get().Leader {
+		return nil
+	}
+
+	fetchCtx, cancel := s.graceContext(ctx)
+	defer cancel()
+
+	fetchCtx, span := tracing.Tracer("ingest").Start(fetchCtx, "ingest.cycle")
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	data, err := s.fetchSnapshot(fetchCtx)
+	if err != nil {
+		return err
+	}
+
+	s.enqueue(fetchCtx, data)
+	return nil
+}
+
+// graceContext returns a context that outlives ctx by the shutdown grace
+// period, so a store transaction can commit or roll back cleanly instead of
+// being cut off mid-statement.
+func (s *FlightDataService) graceContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	graceCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+
+	stop := context.AfterFunc(ctx, func() {
+		timer := time.NewTimer(s.shutdownGrace)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			cancel()
+		case <-graceCtx.Done():
+		}
+	})
+
+	return graceCtx, func() {
+		stop()
+		cancel()
 	}
 }
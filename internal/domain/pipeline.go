@@ -0,0 +1,208 @@
+package domain
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/northeastloon/flight_tracker/internal/metrics"
+)
+
+// Defaults for the queue between fetching and storing.
+const (
+	DefaultQueueSize    = 16
+	DefaultStoreWorkers = 2
+
+	// spillReplayEvery is how often spilled snapshots are retried.
+	spillReplayEvery = 5 * time.Second
+)
+
+// SpillBuffer holds snapshots on local disk while the store is unavailable
+// or the queue is full.
+type SpillBuffer interface {
+	Append(data []Telemetry) error
+	// Replay calls fn for each buffered snapshot, oldest first, consuming
+	// those for which fn returns nil and stopping at the first error.
+	Replay(fn func([]Telemetry) error) error
+	Len() int
+	Size() int64
+}
+
+// WithQueue sets the number of fetched snapshots that may wait to be stored
+// and the number of workers storing them.
+func WithQueue(size, workers int) ServiceOption {
+	return func(o *serviceOptions) {
+		o.queueSize = size
+		o.storeWorkers = workers
+	}
+}
+
+// WithSpill buffers snapshots in buf when they cannot be stored or queued.
+// Without a spill buffer such snapshots are dropped.
+//
+// Spilled snapshots are replayed in the order they were fetched: once one is
+// spilled, newer snapshots follow it through the spill until it drains, and
+// a queued snapshot that fails to store is retried in place rather than
+// spilled behind newer ones.
+func WithSpill(buf SpillBuffer) ServiceOption {
+	return func(o *serviceOptions) {
+		o.spill = buf
+	}
+}
+
+// WithBackpressure makes fetching wait for room in the queue instead of
+// spilling snapshots when it is full. Replays use it, since they can fetch
+// far faster than the store keeps up and have no live data to fall behind on.
+func WithBackpressure() ServiceOption {
+	return func(o *serviceOptions) {
+		o.backpressure = true
+	}
+}
+
+// enqueue hands a fetched snapshot to the store workers. When the queue is
+// full it spills the snapshot or, with backpressure, waits for room until ctx
+// is done. While the spill holds snapshots, new ones go straight behind
+// them, so the queue only ever holds snapshots older than those spilled.
+func (s *FlightDataService) enqueue(ctx context.Context, data []Telemetry) {
+	defer func() { metrics.IngestQueueDepth.Set(float64(len(s.queue))) }()
+
+	if s.spill != nil && s.spill.Len() > 0 {
+		s.spillSnapshot(data, "spill_pending")
+		return
+	}
+
+	s.pending.Add(1)
+	if s.backpressure {
+		select {
+		case s.queue <- data:
+			return
+		case <-ctx.Done():
+		}
+	} else {
+		select {
+		case s.queue <- data:
+			return
+		default:
+			slog.Warn("Ingest queue full", "capacity", cap(s.queue))
+		}
+	}
+	s.pending.Add(-1)
+	s.spillSnapshot(data, "queue_full")
+}
+
+// runStoreWorkers stores queued snapshots until stop is closed, then drains
+// what is left in the queue and returns.
+func (s *FlightDataService) runStoreWorkers(ctx context.Context, stop <-chan struct{}) {
+	var wg sync.WaitGroup
+	for range s.storeWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case data := <-s.queue:
+					s.handleSnapshot(ctx, data)
+				case <-stop:
+					for {
+						select {
+						case data := <-s.queue:
+							s.handleSnapshot(ctx, data)
+						default:
+							return
+						}
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// handleSnapshot stores a queued snapshot. With a spill buffer, a failed
+// store is retried until it succeeds: snapshots fetched meanwhile are
+// spilled, so spilling this one would put it behind newer ones. Only once
+// ctx is cancelled on shutdown is it spilled regardless.
+func (s *FlightDataService) handleSnapshot(ctx context.Context, data []Telemetry) {
+	defer s.pending.Add(-1)
+	metrics.IngestQueueDepth.Set(float64(len(s.queue)))
+
+	for {
+		err := s.storeSnapshot(ctx, data)
+		if err == nil {
+			return
+		}
+		slog.Error("Error storing snapshot", "error", err)
+		if s.spill == nil {
+			s.spillSnapshot(data, "store_failed")
+			return
+		}
+
+		select {
+		case <-time.After(spillReplayEvery):
+		case <-ctx.Done():
+			s.spillSnapshot(data, "store_failed")
+			return
+		}
+	}
+}
+
+// spillSnapshot appends data to the spill buffer, or drops it when there is
+// none or the append fails.
+func (s *FlightDataService) spillSnapshot(data []Telemetry, reason string) {
+	if s.spill == nil {
+		slog.Warn("Dropping snapshot", "reason", reason)
+		metrics.IngestDroppedSnapshots.WithLabelValues(reason).Inc()
+		return
+	}
+
+	if err := s.spill.Append(data); err != nil {
+		slog.Error("Dropping snapshot that could not be spilled", "reason", reason, "error", err)
+		metrics.IngestDroppedSnapshots.WithLabelValues(reason).Inc()
+		return
+	}
+	s.reportSpill()
+}
+
+// replaySpill retries spilled snapshots, oldest first, until stop is closed.
+// A replay waits until the older, queued snapshots are stored, stops at the
+// first failed store and resumes on the next tick. Nothing is queued while
+// a spilled snapshot waits, and the last one stays counted until it is
+// stored, so replayed and queued snapshots are never stored out of order.
+func (s *FlightDataService) replaySpill(ctx context.Context, stop <-chan struct{}) {
+	ticker := time.NewTicker(spillReplayEvery)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+
+		if s.spill.Len() == 0 || s.pending.Load() > 0 {
+			continue
+		}
+
+		replayed := 0
+		err := s.spill.Replay(func(data []Telemetry) error {
+			if err := s.storeSnapshot(ctx, data); err != nil {
+				return err
+			}
+			replayed++
+			return nil
+		})
+		if replayed > 0 {
+			slog.Info("Replayed spilled snapshots", "count", replayed, "remaining", s.spill.Len())
+		}
+		if err != nil {
+			slog.Warn("Spill replay paused", "error", err, "remaining", s.spill.Len())
+		}
+		s.reportSpill()
+	}
+}
+
+func (s *FlightDataService) reportSpill() {
+	metrics.IngestSpillSnapshots.Set(float64(s.spill.Len()))
+	metrics.IngestSpillBytes.Set(float64(s.spill.Size()))
+}
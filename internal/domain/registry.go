@@ -0,0 +1,89 @@
+package domain
+
+import "sort"
+
+// AircraftInfo is an aircraft's entry in the registry database. Fields are
+// nil when the database does not know them.
+type AircraftInfo struct {
+	ICAO24       string
+	Registration *string
+	Manufacturer *string
+	Model        *string
+	TypeCode     *string // ICAO type designator, e.g. A388
+	Operator     *string
+	OperatorICAO *string // ICAO airline designator, e.g. BAW
+	Owner        *string
+}
+
+// Known reports whether any field besides ICAO24 is set.
+func (a AircraftInfo) Known() bool {
+	for _, f := range []*string{a.Registration, a.Manufacturer, a.Model, a.TypeCode, a.Operator, a.OperatorICAO, a.Owner} {
+		if f != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// RegistryDiff counts how a registry reload changed the stored aircraft.
+type RegistryDiff struct {
+	Added     int64
+	Changed   int64
+	Removed   int64
+	Unchanged int64
+}
+
+// DiffRegistry compares a reload of the registry with the stored aircraft.
+// It returns the records that are new or differ from their stored entry, the
+// addresses of stored aircraft missing from the reload and the counts of
+// each. A later record for the same address replaces an earlier one.
+func DiffRegistry(stored map[string]AircraftInfo, records []AircraftInfo) ([]AircraftInfo, []string, RegistryDiff) {
+	var diff RegistryDiff
+
+	reload := make(map[string]AircraftInfo, len(records))
+	var order []string
+	for _, r := range records {
+		if _, dup := reload[r.ICAO24]; !dup {
+			order = append(order, r.ICAO24)
+		}
+		reload[r.ICAO24] = r
+	}
+
+	var upsert []AircraftInfo
+	for _, icao24 := range order {
+		r := reload[icao24]
+		s, ok := stored[icao24]
+		switch {
+		case !ok:
+			diff.Added++
+		case !sameAircraft(s, r):
+			diff.Changed++
+		default:
+			diff.Unchanged++
+			continue
+		}
+		upsert = append(upsert, r)
+	}
+
+	var remove []string
+	for icao24 := range stored {
+		if _, ok := reload[icao24]; !ok {
+			remove = append(remove, icao24)
+		}
+	}
+	sort.Strings(remove)
+	diff.Removed = int64(len(remove))
+
+	return upsert, remove, diff
+}
+
+func sameAircraft(a, b AircraftInfo) bool {
+	x := []*string{a.Registration, a.Manufacturer, a.Model, a.TypeCode, a.Operator, a.OperatorICAO, a.Owner}
+	y := []*string{b.Registration, b.Manufacturer, b.Model, b.TypeCode, b.Operator, b.OperatorICAO, b.Owner}
+	for i := range x {
+		if (x[i] == nil) != (y[i] == nil) || (x[i] != nil && *x[i] != *y[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,65 @@
+package domain_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/northeastloon/flight_tracker/internal/domain"
+)
+
+func aircraftInfo(icao24, registration string, typeCode *string) domain.AircraftInfo {
+	return domain.AircraftInfo{ICAO24: icao24, Registration: &registration, TypeCode: typeCode}
+}
+
+func TestDiffRegistry(t *testing.T) {
+	a388, b744 := "A388", "B744"
+	stored := map[string]domain.AircraftInfo{
+		"4007f6": aircraftInfo("4007f6", "G-XLEA", &a388),
+		"400a0b": aircraftInfo("400a0b", "G-CIVA", &b744),
+		"4ca7b4": aircraftInfo("4ca7b4", "EI-DCL", nil),
+		"3c6444": aircraftInfo("3c6444", "D-AIMA", &a388),
+		"a00001": aircraftInfo("a00001", "N1", nil),
+	}
+
+	owned := aircraftInfo("400a0b", "G-CIVA", &b744)
+	owner := "British Airways Plc"
+	owned.Owner = &owner
+
+	records := []domain.AircraftInfo{
+		aircraftInfo("4007f6", "G-XLEA", &a388), // unchanged
+		aircraftInfo("4ca7b4", "EI-DCL", &b744), // type learned
+		aircraftInfo("3c6444", "D-AIMA", nil),   // type forgotten
+		aircraftInfo("abc123", "N12345", nil),   // added
+		aircraftInfo("a00001", "N2", nil),       // re-registered, then
+		aircraftInfo("a00001", "N1", nil),       // back as stored
+		owned,                                   // owner learned
+	}
+
+	upsert, remove, diff := domain.DiffRegistry(stored, records)
+
+	want := domain.RegistryDiff{Added: 1, Changed: 3, Removed: 0, Unchanged: 2}
+	if diff != want {
+		t.Errorf("diff = %+v, want %+v", diff, want)
+	}
+	var written []string
+	for _, a := range upsert {
+		written = append(written, a.ICAO24)
+	}
+	if !slices.Equal(written, []string{"4ca7b4", "3c6444", "abc123", "400a0b"}) {
+		t.Errorf("upserted %v, want the changed and added aircraft in record order", written)
+	}
+	if len(remove) != 0 {
+		t.Errorf("removed %v, want none", remove)
+	}
+
+	// an aircraft dropped from the reload is removed, and an empty stored
+	// registry takes every record as new
+	_, remove, diff = domain.DiffRegistry(stored, records[:4])
+	if !slices.Equal(remove, []string{"400a0b", "a00001"}) || diff.Removed != 2 {
+		t.Errorf("removed %v (%d), want 400a0b and a00001", remove, diff.Removed)
+	}
+	upsert, _, diff = domain.DiffRegistry(nil, records)
+	if diff != (domain.RegistryDiff{Added: 6}) || len(upsert) != 6 {
+		t.Errorf("first load diff = %+v with %d writes, want 6 added", diff, len(upsert))
+	}
+}
@@ -0,0 +1,107 @@
+package domain
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// RateLimit is a provider's report of its remaining request budget.
+type RateLimit struct {
+	Remaining  int           // credits left; negative when unknown
+	ResetAt    time.Time     // when Remaining is replenished; zero when unknown
+	RetryAfter time.Duration // back-off demanded by the last rate-limited request
+}
+
+// RateLimitReporter is implemented by providers that report their request
+// budget. The scheduler uses it to slow down before credits run out.
+type RateLimitReporter interface {
+	RateLimit() (RateLimit, bool)
+}
+
+// Schedule controls how often the ingestion loop runs. Cycles never overlap:
+// the next one is planned when the previous one finishes.
+type Schedule struct {
+	Interval     time.Duration // time between cycle starts
+	BusyInterval time.Duration // used during busy hours; 0 means Interval
+	BusyStart    int           // first busy hour, UTC
+	BusyEnd      int           // first quiet hour after the busy window, UTC; equal to BusyStart disables it
+	MaxInterval  time.Duration // upper bound when the rate-limit budget stretches the interval
+	Jitter       float64       // fraction of the interval randomly added or removed
+}
+
+// busy reports whether t falls within the busy window, which may wrap
+// past midnight.
+func (s Schedule) busy(t time.Time) bool {
+	if s.BusyStart == s.BusyEnd || s.BusyInterval <= 0 {
+		return false
+	}
+	h := t.UTC().Hour()
+	if s.BusyStart < s.BusyEnd {
+		return h >= s.BusyStart && h < s.BusyEnd
+	}
+	return h >= s.BusyStart || h < s.BusyEnd
+}
+
+// scheduler plans the delay before each ingestion cycle.
+type scheduler struct {
+	schedule Schedule
+	jitter   func() float64 // uniform in [0, 1)
+
+	// cost estimates the credits one cycle consumes, learnt from how much
+	// RateLimit.Remaining drops between cycles.
+	cost          float64
+	lastRemaining int
+}
+
+func newScheduler(s Schedule) *scheduler {
+	return &scheduler{
+		schedule:      s,
+		jitter:        rand.Float64,
+		cost:          1,
+		lastRemaining: -1,
+	}
+}
+
+// interval returns the time to leave between the start of the cycle that
+// began at start and the start of the next one.
+func (s *scheduler) interval(start, now time.Time, rl RateLimit, haveRL bool) time.Duration {
+	interval := s.schedule.Interval
+	if s.schedule.busy(start) {
+		interval = s.schedule.BusyInterval
+	}
+
+	if haveRL && rl.Remaining >= 0 {
+		if s.lastRemaining >= 0 && rl.Remaining < s.lastRemaining {
+			// exponential moving average smooths over calls of varying cost
+			s.cost = 0.7*s.cost + 0.3*float64(s.lastRemaining-rl.Remaining)
+		}
+		s.lastRemaining = rl.Remaining
+
+		// spread the remaining credits evenly until they are replenished
+		if !rl.ResetAt.IsZero() && rl.ResetAt.After(now) {
+			untilReset := rl.ResetAt.Sub(now)
+			cycles := float64(rl.Remaining) / s.cost
+			if cycles < 1 {
+				interval = max(interval, untilReset)
+			} else {
+				interval = max(interval, time.Duration(float64(untilReset)/cycles))
+			}
+		}
+	}
+
+	if j := s.schedule.Jitter; j > 0 {
+		interval += time.Duration((s.jitter()*2 - 1) * j * float64(interval))
+	}
+
+	// clamped after the jitter, which could otherwise push past the bound
+	if s.schedule.MaxInterval > 0 {
+		interval = min(interval, s.schedule.MaxInterval)
+	}
+
+	// a provider's explicit back-off wins over everything else
+	if haveRL && rl.RetryAfter > 0 {
+		interval = max(interval, now.Sub(start)+rl.RetryAfter)
+	}
+
+	return interval
+}
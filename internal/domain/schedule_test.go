@@ -0,0 +1,87 @@
+package domain_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/northeastloon/flight_tracker/internal/domain"
+)
+
+func TestSchedulerInterval(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	now := start.Add(5 * time.Second)
+	base := domain.Schedule{Interval: 30 * time.Second}
+	// budget leaves remaining credits until ten minutes from now
+	budget := func(remaining int) *domain.RateLimit {
+		return &domain.RateLimit{Remaining: remaining, ResetAt: now.Add(10 * time.Minute)}
+	}
+
+	tests := []struct {
+		name     string
+		schedule func(s *domain.Schedule)
+		jitter   float64 // the jitter source; 0.5 adds nothing
+		start    time.Time
+		rl       *domain.RateLimit
+		want     time.Duration
+	}{
+		{"interval", nil, 0.5, start, nil, 30 * time.Second},
+		{"busy hours", func(s *domain.Schedule) {
+			s.BusyInterval, s.BusyStart, s.BusyEnd = 10*time.Second, 6, 22
+		}, 0.5, start, nil, 10 * time.Second},
+		{"quiet hours", func(s *domain.Schedule) {
+			s.BusyInterval, s.BusyStart, s.BusyEnd = 10*time.Second, 6, 22
+		}, 0.5, start.Add(11 * time.Hour), nil, 30 * time.Second},
+		{"jitter added", func(s *domain.Schedule) { s.Jitter = 0.2 }, 1, start, nil, 36 * time.Second},
+		{"jitter removed", func(s *domain.Schedule) { s.Jitter = 0.2 }, 0, start, nil, 24 * time.Second},
+		{"jitter clamped", func(s *domain.Schedule) {
+			s.Jitter, s.MaxInterval = 0.2, 30*time.Second
+		}, 1, start, nil, 30 * time.Second},
+		{"credits spread", nil, 0.5, start, budget(10), time.Minute},
+		{"credits spread clamped", func(s *domain.Schedule) { s.MaxInterval = 45 * time.Second }, 0.5, start, budget(10), 45 * time.Second},
+		{"credits run out", nil, 0.5, start, budget(0), 10 * time.Minute},
+		{"credits unknown", nil, 0.5, start, &domain.RateLimit{Remaining: -1}, 30 * time.Second},
+		{"retry after wins", func(s *domain.Schedule) { s.MaxInterval = 45 * time.Second }, 0.5, start,
+			&domain.RateLimit{Remaining: -1, RetryAfter: 2 * time.Minute}, 2*time.Minute + 5*time.Second},
+	}
+
+	for _, tt := range tests {
+		s := base
+		if tt.schedule != nil {
+			tt.schedule(&s)
+		}
+		var rl domain.RateLimit
+		if tt.rl != nil {
+			rl = *tt.rl
+		}
+		got := domain.NewScheduler(s, tt.jitter).Interval(tt.start, now, rl, tt.rl != nil)
+		if got != tt.want {
+			t.Errorf("%s: interval = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestScheduleBusyWindowWrapsPastMidnight(t *testing.T) {
+	s := domain.Schedule{Interval: time.Minute, BusyInterval: 10 * time.Second, BusyStart: 22, BusyEnd: 6}
+	day := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	for hour, want := range map[int]bool{21: false, 22: true, 23: true, 0: true, 5: true, 6: false, 12: false} {
+		if got := s.Busy(day.Add(time.Duration(hour) * time.Hour)); got != want {
+			t.Errorf("busy at %02d:00 = %v, want %v", hour, got, want)
+		}
+	}
+
+	// hours are UTC whatever the location of the time
+	dublinSummer := time.FixedZone("IST", 3600)
+	if !s.Busy(time.Date(2026, 7, 1, 23, 30, 0, 0, dublinSummer)) {
+		t.Error("22:30 UTC given in another zone is not busy")
+	}
+
+	for _, disabled := range []domain.Schedule{
+		{Interval: time.Minute, BusyInterval: 10 * time.Second, BusyStart: 6, BusyEnd: 6},
+		{Interval: time.Minute, BusyStart: 22, BusyEnd: 6},
+	} {
+		if disabled.Busy(day) {
+			t.Errorf("schedule %+v is busy at midnight", disabled)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package domain
+
+import "time"
+
+// Dimensions traffic statistics can be grouped by.
+const (
+	GroupByNone          = "none"
+	GroupByOriginCountry = "origin_country"
+	GroupByCategory      = "category"
+	GroupByCell          = "cell" // 1° latitude/longitude grid cell
+)
+
+type StatsFilter struct {
+	From    time.Time
+	To      time.Time
+	Bucket  time.Duration // 0 aggregates the whole range into one bucket
+	GroupBy string
+	// Airborne restricts counts to aircraft in the air (true) or on the
+	// ground (false). Nil counts both.
+	Airborne *bool
+	Limit    int // 0 means no limit
+}
+
+type StatsRow struct {
+	BucketStart  *time.Time // nil when the filter has no bucket
+	Key          string
+	Aircraft     int64 // distinct icao24
+	Observations int64 // state vectors received
+}
+
+// CoverageFilter selects per-feed coverage statistics.
+type CoverageFilter struct {
+	From   time.Time
+	To     time.Time
+	Bucket time.Duration // 0 aggregates the whole range into one bucket
+	Source *string       // nil reports every feed
+}
+
+// CoverageRow reports how much of the traffic one feed saw in one bucket.
+type CoverageRow struct {
+	BucketStart   *time.Time // nil when the filter has no bucket
+	Source        string
+	Aircraft      int64 // distinct icao24 the feed saw
+	TotalAircraft int64 // distinct icao24 any feed saw
+	Observations  int64 // stored states the feed saw
+	Selected      int64 // stored states fusion took from the feed
+	Exclusive     int64 // stored states no other feed saw
+}
@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"sync"
+	"time"
+)
+
+// IngestStatus reports how the ingestion loop has been doing.
+type IngestStatus struct {
+	LastAttempt     time.Time
+	LastSuccess     time.Time
+	LastError       string
+	LastErrorAt     time.Time
+	FetchDuration   time.Duration // provider latency of the last fetch
+	StoreDuration   time.Duration
+	RowsLastIngest  int
+	ConsecutiveFail int
+	Leader          bool // holds the ingest lease, or runs without one
+
+	Interval           time.Duration // planned time between the last and next cycle start
+	NextRun            time.Time
+	RateLimitRemaining *int // provider credits left, when reported
+
+	QueueDepth     int // fetched snapshots waiting for a store worker
+	QueueCapacity  int
+	SpillSnapshots int // snapshots buffered on disk awaiting replay
+	SpillBytes     int64
+}
+
+// StorageStatus reports what the store currently holds.
+type StorageStatus struct {
+	Rows            int64 // estimated
+	Aircraft        int64 // distinct icao24, estimated
+	NewestContact   *time.Time
+	MigrationsReady bool
+}
+
+// ingestTracker records IngestStatus for concurrent readers.
+type ingestTracker struct {
+	mu     sync.RWMutex
+	status IngestStatus
+}
+
+func (t *ingestTracker) get() IngestStatus {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.status
+}
+
+func (t *ingestTracker) update(fn func(s *IngestStatus)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fn(&t.status)
+}
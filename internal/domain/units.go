@@ -0,0 +1,14 @@
+package domain
+
+// Conversions between the SI units telemetry is kept in and the aviation
+// units some feeds report and some clients ask for.
+const (
+	FeetToMetres       = 0.3048
+	MetresPerNM        = 1852.0
+	KnotsToMps         = MetresPerNM / 3600
+	FeetPerMinuteToMps = FeetToMetres / 60
+
+	MetresToFeet       = 1 / FeetToMetres
+	MpsToKnots         = 1 / KnotsToMps
+	MpsToFeetPerMinute = MetresToFeet * 60
+)
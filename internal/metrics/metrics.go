@@ -0,0 +1,219 @@
+// Package metrics defines the Prometheus metrics exported on /metrics.
+package metrics
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "flight_tracker"
+
+// Ingest row stages.
+const (
+	RowsParsed       = "parsed"
+	RowsRejected     = "rejected"
+	RowsInserted     = "inserted"
+	RowsDeduplicated = "deduplicated"
+)
+
+var (
+	FetchDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "provider",
+		Name:      "fetch_duration_seconds",
+		Help:      "Time taken to fetch and decode a provider snapshot.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 40, 60},
+	}, []string{"provider"})
+
+	FetchErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "provider",
+		Name:      "fetch_errors_total",
+		Help:      "Provider fetch failures by error type.",
+	}, []string{"provider", "type"})
+
+	IngestRows = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "ingest",
+		Name:      "rows_total",
+		Help:      "State vectors handled by ingestion, by stage.",
+	}, []string{"stage"})
+
+	IngestLastRows = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "ingest",
+		Name:      "last_rows",
+		Help:      "State vectors handled by the most recent ingest, by stage.",
+	}, []string{"stage"})
+
+	IngestInterval = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "ingest",
+		Name:      "interval_seconds",
+		Help:      "Planned time between ingestion cycle starts, after rate-limit pacing and jitter.",
+	})
+
+	RateLimitRemaining = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "provider",
+		Name:      "rate_limit_remaining",
+		Help:      "Request credits the provider reports as remaining.",
+	}, []string{"provider"})
+
+	IngestQueueDepth = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "ingest",
+		Name:      "queue_depth",
+		Help:      "Fetched snapshots waiting for a store worker.",
+	})
+
+	IngestSpillSnapshots = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "ingest",
+		Name:      "spill_snapshots",
+		Help:      "Snapshots buffered on disk awaiting replay.",
+	})
+
+	IngestSpillBytes = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "ingest",
+		Name:      "spill_bytes",
+		Help:      "Size of the snapshots buffered on disk awaiting replay.",
+	})
+
+	IngestSpillEvicted = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "ingest",
+		Name:      "spill_evicted_snapshots_total",
+		Help:      "Spilled snapshots discarded unreplayed to keep the spill file under its size cap.",
+	})
+
+	IngestDroppedSnapshots = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "ingest",
+		Name:      "dropped_snapshots_total",
+		Help:      "Snapshots discarded because they could be neither stored nor spilled, by reason.",
+	}, []string{"reason"})
+
+	UnallocatedAddresses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "ingest",
+		Name:      "unallocated_addresses_total",
+		Help:      "State vectors whose icao24 address lies outside every ICAO-allocated block, by source.",
+	}, []string{"source"})
+
+	FusedStates = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "fusion",
+		Name:      "states_total",
+		Help:      "Aircraft states reported by each feed, by whether fusion selected or superseded them.",
+	}, []string{"source", "outcome"})
+
+	StoreDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "store",
+		Name:      "telemetry_duration_seconds",
+		Help:      "Duration of the StoreTelemetry transaction.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 40},
+	}, []string{"result"})
+
+	LiveAircraft = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "live_aircraft",
+		Help:      "Distinct aircraft in the most recently stored snapshot.",
+	})
+
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+)
+
+// newestContact holds the unix time of the newest stored last_contact.
+var newestContact atomic.Int64
+
+var _ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Name:      "data_age_seconds",
+	Help:      "Seconds since the newest stored last_contact.",
+}, func() float64 {
+	newest := newestContact.Load()
+	if newest == 0 {
+		return 0
+	}
+	return time.Since(time.Unix(newest, 0)).Seconds()
+})
+
+// SetNewestContact records the newest last_contact that was stored.
+func SetNewestContact(t time.Time) {
+	for {
+		current := newestContact.Load()
+		if t.Unix() <= current || newestContact.CompareAndSwap(current, t.Unix()) {
+			return
+		}
+	}
+}
+
+// ObserveRows adds n rows to stage for both the running total and the
+// last-ingest gauge.
+func ObserveRows(stage string, n int) {
+	IngestRows.WithLabelValues(stage).Add(float64(n))
+	IngestLastRows.WithLabelValues(stage).Set(float64(n))
+}
+
+// RegisterPool exports connection pool statistics for pool on reg and
+// returns a function that stops exporting them. A registry exports one pool:
+// while another is registered, the call does nothing and returns a no-op.
+func RegisterPool(reg prometheus.Registerer, pool *pgxpool.Pool) (unregister func(), err error) {
+	c := &poolCollector{pool: pool}
+	if err := reg.Register(c); err != nil {
+		if errors.As(err, new(prometheus.AlreadyRegisteredError)) {
+			return func() {}, nil
+		}
+		return nil, err
+	}
+	return func() { reg.Unregister(c) }, nil
+}
+
+var (
+	poolAcquiredConns = poolDesc("acquired_conns", "Connections currently in use.")
+	poolIdleConns     = poolDesc("idle_conns", "Idle connections.")
+	poolTotalConns    = poolDesc("total_conns", "Total connections in the pool.")
+	poolMaxConns      = poolDesc("max_conns", "Maximum pool size.")
+	poolAcquireCount  = poolDesc("acquire_total", "Successful connection acquisitions.")
+	poolAcquireWait   = poolDesc("acquire_wait_seconds_total", "Time spent waiting to acquire a connection.")
+	poolEmptyAcquire  = poolDesc("empty_acquire_total", "Acquisitions that had to wait for a connection.")
+	poolCanceled      = poolDesc("canceled_acquire_total", "Acquisitions canceled by their context.")
+)
+
+func poolDesc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "pgxpool", name), help, nil, nil)
+}
+
+type poolCollector struct {
+	pool *pgxpool.Pool
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.pool.Stat()
+	ch <- prometheus.MustNewConstMetric(poolAcquiredConns, prometheus.GaugeValue, float64(s.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(poolIdleConns, prometheus.GaugeValue, float64(s.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(poolTotalConns, prometheus.GaugeValue, float64(s.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(poolMaxConns, prometheus.GaugeValue, float64(s.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(poolAcquireCount, prometheus.CounterValue, float64(s.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolAcquireWait, prometheus.CounterValue, s.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(poolEmptyAcquire, prometheus.CounterValue, float64(s.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolCanceled, prometheus.CounterValue, float64(s.CanceledAcquireCount()))
+}
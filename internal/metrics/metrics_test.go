@@ -0,0 +1,43 @@
+package metrics_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/northeastloon/flight_tracker/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestRegisterPoolTwice(t *testing.T) {
+	// pools connect lazily, so no database is needed
+	newPool := func() *pgxpool.Pool {
+		pool, err := pgxpool.New(context.Background(), "host=localhost dbname=none")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(pool.Close)
+		return pool
+	}
+	reg := prometheus.NewRegistry()
+
+	unregister, err := metrics.RegisterPool(reg, newPool())
+	if err != nil {
+		t.Fatalf("first RegisterPool: %v", err)
+	}
+	if _, err := metrics.RegisterPool(reg, newPool()); err != nil {
+		t.Fatalf("second RegisterPool: %v", err)
+	}
+
+	unregister()
+	if _, err := metrics.RegisterPool(reg, newPool()); err != nil {
+		t.Fatalf("RegisterPool after unregistering: %v", err)
+	}
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(families) == 0 {
+		t.Error("no pool metrics gathered")
+	}
+}
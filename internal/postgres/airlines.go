@@ -0,0 +1,56 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/northeastloon/flight_tracker/internal/domain"
+)
+
+// ImportAirlines replaces the airline table with the given airlines.
+func (d *Database) ImportAirlines(ctx context.Context, airlines []domain.Airline) error {
+	tx, err := d.Client.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM airlines`); err != nil {
+		return fmt.Errorf("failed to clear airlines: %w", err)
+	}
+
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"airlines"},
+		[]string{"icao", "iata", "name", "country", "telephony"},
+		pgx.CopyFromSlice(len(airlines), func(i int) ([]any, error) {
+			a := airlines[i]
+			return []any{a.ICAO, a.IATA, a.Name, a.Country, a.Telephony}, nil
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to copy airlines: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// nullableAirline scans the columns of a LEFT JOIN on airlines.
+type nullableAirline struct {
+	icao, iata, name, country, telephony *string
+}
+
+func (n nullableAirline) airline() *domain.Airline {
+	if n.icao == nil || n.name == nil {
+		return nil
+	}
+	return &domain.Airline{
+		ICAO:      *n.icao,
+		IATA:      n.iata,
+		Name:      *n.name,
+		Country:   n.country,
+		Telephony: n.telephony,
+	}
+}
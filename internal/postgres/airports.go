@@ -0,0 +1,199 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/northeastloon/flight_tracker/internal/domain"
+)
+
+const airportColumns = `
+	ident, type, name, icao_code, iata_code, latitude, longitude, elevation,
+	iso_country, iso_region, municipality, scheduled_service
+`
+
+// ImportAirports replaces the airports and runways with those given, then
+// infers the departure and arrival airports of every stored flight again.
+// It returns how many flights were updated.
+func (d *Database) ImportAirports(ctx context.Context, airports []domain.Airport) (int64, error) {
+	tx, err := d.Client.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM airports`); err != nil {
+		return 0, fmt.Errorf("failed to clear airports: %w", err)
+	}
+
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"airports"},
+		[]string{
+			"ident", "type", "name", "icao_code", "iata_code", "latitude", "longitude", "elevation",
+			"iso_country", "iso_region", "municipality", "scheduled_service",
+		},
+		pgx.CopyFromSlice(len(airports), func(i int) ([]any, error) {
+			a := airports[i]
+			return []any{
+				a.Ident, a.Type, a.Name, a.ICAOCode, a.IATACode, a.Latitude, a.Longitude, a.Elevation,
+				a.Country, a.Region, a.Municipality, a.ScheduledService,
+			}, nil
+		}),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to copy airports: %w", err)
+	}
+
+	var runways [][]any
+	for _, a := range airports {
+		for i, r := range a.Runways {
+			runways = append(runways, []any{
+				a.Ident, i, r.Length, r.Width, r.Surface, r.Lighted, r.Closed,
+				r.LowEnd.Ident, r.LowEnd.Latitude, r.LowEnd.Longitude, r.LowEnd.Elevation, r.LowEnd.Heading,
+				r.HighEnd.Ident, r.HighEnd.Latitude, r.HighEnd.Longitude, r.HighEnd.Elevation, r.HighEnd.Heading,
+			})
+		}
+	}
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"runways"},
+		[]string{
+			"airport_ident", "seq", "length", "width", "surface", "lighted", "closed",
+			"le_ident", "le_latitude", "le_longitude", "le_elevation", "le_heading",
+			"he_ident", "he_latitude", "he_longitude", "he_elevation", "he_heading",
+		},
+		pgx.CopyFromRows(runways),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to copy runways: %w", err)
+	}
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE flights SET
+			departure_airport = infer_airport(departure_latitude, departure_longitude, departure_altitude, false, $1, $2, $3),
+			arrival_airport = CASE WHEN status <> $4
+				THEN infer_airport(end_latitude, end_longitude, end_altitude, landing IS NOT NULL, $1, $2, $3)
+			END
+	`, domain.ApproachRadius, domain.ApproachHeight, domain.InferredAirportTypes, domain.FlightActive)
+	if err != nil {
+		return 0, fmt.Errorf("failed to infer flight airports: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// GetAirport returns the airport with the given ident, ICAO or IATA code,
+// with its runways, or nil if there is none. Idents take precedence over
+// codes of other airports.
+func (d *Database) GetAirport(ctx context.Context, code string) (*domain.Airport, error) {
+	airports, err := queryAirports(ctx, d.Client, `
+		SELECT `+airportColumns+`, NULL::double precision
+		FROM airports
+		WHERE ident = $1 OR icao_code = $1 OR iata_code = $1
+		ORDER BY ident = $1 DESC, icao_code = $1 DESC NULLS LAST, ident
+		LIMIT 1
+	`, strings.ToUpper(code))
+	if err != nil {
+		return nil, err
+	}
+	if len(airports) == 0 {
+		return nil, nil
+	}
+	a := &airports[0]
+
+	rows, err := d.Client.Query(ctx, `
+		SELECT length, width, surface, lighted, closed,
+			le_ident, le_latitude, le_longitude, le_elevation, le_heading,
+			he_ident, he_latitude, he_longitude, he_elevation, he_heading
+		FROM runways
+		WHERE airport_ident = $1
+		ORDER BY seq
+	`, a.Ident)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query runways: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var r domain.Runway
+		if err := rows.Scan(
+			&r.Length, &r.Width, &r.Surface, &r.Lighted, &r.Closed,
+			&r.LowEnd.Ident, &r.LowEnd.Latitude, &r.LowEnd.Longitude, &r.LowEnd.Elevation, &r.LowEnd.Heading,
+			&r.HighEnd.Ident, &r.HighEnd.Latitude, &r.HighEnd.Longitude, &r.HighEnd.Elevation, &r.HighEnd.Heading,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan runway row: %w", err)
+		}
+		a.Runways = append(a.Runways, r)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating runway rows: %w", err)
+	}
+
+	return a, nil
+}
+
+// NearestAirports returns the airports closest to a point, nearest first,
+// with their distance from it.
+func (d *Database) NearestAirports(ctx context.Context, filter *domain.NearestAirportFilter) ([]domain.Airport, error) {
+	if filter == nil {
+		return nil, errors.New("nearest airport search needs a position")
+	}
+
+	types := filter.Types
+	if types == nil {
+		for _, t := range domain.AirportTypes {
+			if t != domain.AirportClosed {
+				types = append(types, t)
+			}
+		}
+	}
+
+	params := []any{filter.Longitude, filter.Latitude, types}
+	query := `
+		SELECT ` + airportColumns + `, ST_Distance(position, p.point)
+		FROM airports, (SELECT ST_SetSRID(ST_MakePoint($1, $2), 4326)::geography AS point) p
+		WHERE type = ANY($3)
+	`
+	if filter.Radius > 0 {
+		params = append(params, filter.Radius*1000)
+		query += fmt.Sprintf(" AND ST_DWithin(position, p.point, $%d)", len(params))
+	}
+	query += " ORDER BY position <-> p.point"
+	if filter.Limit > 0 {
+		params = append(params, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(params))
+	}
+
+	return queryAirports(ctx, d.Client, query, params...)
+}
+
+// queryAirports runs a query selecting airportColumns and a distance.
+func queryAirports(ctx context.Context, q querier, query string, params ...any) ([]domain.Airport, error) {
+	rows, err := q.Query(ctx, query, params...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query airports: %w", err)
+	}
+	defer rows.Close()
+
+	var airports []domain.Airport
+	for rows.Next() {
+		var a domain.Airport
+		if err := rows.Scan(
+			&a.Ident, &a.Type, &a.Name, &a.ICAOCode, &a.IATACode, &a.Latitude, &a.Longitude, &a.Elevation,
+			&a.Country, &a.Region, &a.Municipality, &a.ScheduledService, &a.Distance,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan airport row: %w", err)
+		}
+		airports = append(airports, a)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating airport rows: %w", err)
+	}
+
+	return airports, nil
+}
@@ -0,0 +1,393 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/northeastloon/flight_tracker/internal/domain"
+)
+
+// flightsLockClass namespaces the advisory locks that serialize flight
+// segmentation per aircraft across concurrent store transactions.
+const flightsLockClass int32 = 0x666c6967 // "flig"
+
+const flightColumns = `
+	id, icao24, callsign, callsigns, origin_country, category, status,
+	first_seen, last_seen, takeoff, landing,
+	start_latitude, start_longitude, start_altitude,
+	end_latitude, end_longitude, end_altitude,
+	departure_latitude, departure_longitude, departure_altitude,
+	max_altitude, max_velocity, distance, observations,
+	departure_airport, arrival_airport
+`
+
+// flightSelect selects flightColumns and the airline of each flight's
+// callsign, as queryFlights scans them.
+const flightSelect = `
+	SELECT ` + flightColumns + `, al.icao, al.iata, al.name, al.country, al.telephony
+	FROM flights
+	LEFT JOIN airlines al ON al.icao = airline_designator(flights.callsign)
+`
+
+// buildFlights advances the flights of the aircraft in a snapshot and, for
+// live ingest, closes flights whose aircraft have been unseen for longer than
+// the timeout. Like the rollups it runs inside the StoreTelemetry
+// transaction, since telemetry of landed aircraft is pruned as it lands.
+// Departure airports are inferred from a flight's departure fix, once per
+// fix, and arrival airports from its last fix once it has closed.
+//
+// Each aircraft is locked for the rest of the transaction, so concurrent
+// stores wait for each other only over the aircraft they share. States older than an
+// aircraft's last segmented state are ignored, so telemetry imported out of
+// order does not rewrite existing flights: importing days that precede the
+// live data of an aircraft stores its telemetry but adds no flights.
+//
+// Lost flights are closed against the wall clock, held back to the newest
+// state stored so replaying spilled or recorded snapshots does not close the
+// flights they are about to extend. Imports close nothing; flights an import
+// leaves open are closed by the next live store.
+func (d *Database) buildFlights(ctx context.Context, tx pgx.Tx, data []domain.Telemetry, live bool) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	states := make(map[string][]domain.Telemetry)
+	icao24s := make([]string, 0, len(data))
+	var newest time.Time
+	for _, t := range data {
+		if _, ok := states[t.ICAO24]; !ok {
+			icao24s = append(icao24s, t.ICAO24)
+		}
+		states[t.ICAO24] = append(states[t.ICAO24], t)
+		if t.LastContact.After(newest) {
+			newest = t.LastContact
+		}
+	}
+
+	// locks are taken in key order so that concurrent stores cannot deadlock
+	_, err := tx.Exec(ctx, `
+		SELECT pg_advisory_xact_lock($1, key)
+		FROM (SELECT DISTINCT hashtext(icao24) AS key FROM unnest($2::text[]) AS icao24 ORDER BY key) keys
+	`, flightsLockClass, icao24s)
+	if err != nil {
+		return fmt.Errorf("failed to lock flights: %w", err)
+	}
+
+	tracks, err := loadFlightTracks(ctx, tx, states)
+	if err != nil {
+		return err
+	}
+
+	for icao24, s := range states {
+		// Advance updates the open flight in place, so keep it as stored
+		var stored *domain.Flight
+		if open := tracks[icao24].Flight; open != nil {
+			f := *open
+			stored = &f
+		}
+
+		track, flights := d.flights.Advance(tracks[icao24], s)
+		for _, f := range flights {
+			if err := upsertFlight(ctx, tx, f, departureMoved(stored, f)); err != nil {
+				return err
+			}
+		}
+
+		var flightID *string
+		if track.Flight != nil {
+			flightID = &track.Flight.ID
+		}
+		_, err := tx.Exec(ctx, `
+			INSERT INTO flight_tracks (icao24, last_seen, on_ground, flight_id)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (icao24) DO UPDATE SET
+				last_seen = EXCLUDED.last_seen,
+				on_ground = EXCLUDED.on_ground,
+				flight_id = EXCLUDED.flight_id
+		`, icao24, track.LastSeen, track.OnGround, flightID)
+		if err != nil {
+			return fmt.Errorf("failed to update flight track of %s: %w", icao24, err)
+		}
+	}
+
+	if !live {
+		return nil
+	}
+
+	// flights another store is extending are skipped rather than waited
+	// for; they are not lost, and the next store catches any that are
+	now := time.Now()
+	if newest.Before(now) {
+		now = newest
+	}
+	_, err = tx.Exec(ctx, `
+		WITH lost AS (
+			UPDATE flights SET
+				status = $2,
+				arrival_airport = infer_airport(end_latitude, end_longitude, end_altitude, false, $4, $5, $6)
+			WHERE id IN (
+				SELECT id FROM flights
+				WHERE status = $3 AND last_seen < $1
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING id
+		)
+		UPDATE flight_tracks SET flight_id = NULL
+		WHERE flight_id IN (SELECT id FROM lost)
+	`,
+		now.Add(-d.flights.TimeoutOrDefault()), domain.FlightLost, domain.FlightActive,
+		domain.ApproachRadius, domain.ApproachHeight, domain.InferredAirportTypes,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to close lost flights: %w", err)
+	}
+
+	return nil
+}
+
+// loadFlightTracks returns the segmentation state of the given aircraft,
+// with their open flights.
+func loadFlightTracks(ctx context.Context, tx pgx.Tx, states map[string][]domain.Telemetry) (map[string]domain.FlightTrack, error) {
+	icao24s := make([]string, 0, len(states))
+	for icao24 := range states {
+		icao24s = append(icao24s, icao24)
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT icao24, last_seen, on_ground, flight_id
+		FROM flight_tracks
+		WHERE icao24 = ANY($1)
+	`, icao24s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query flight tracks: %w", err)
+	}
+	defer rows.Close()
+
+	tracks := make(map[string]domain.FlightTrack, len(icao24s))
+	var open []string
+	for rows.Next() {
+		var t domain.FlightTrack
+		var flightID *string
+		if err := rows.Scan(&t.ICAO24, &t.LastSeen, &t.OnGround, &flightID); err != nil {
+			return nil, fmt.Errorf("failed to scan flight track row: %w", err)
+		}
+		tracks[t.ICAO24] = t
+		if flightID != nil {
+			open = append(open, *flightID)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating flight track rows: %w", err)
+	}
+	if len(open) == 0 {
+		return tracks, nil
+	}
+
+	flights, err := queryFlights(ctx, tx, flightSelect+` WHERE id = ANY($1)`, open)
+	if err != nil {
+		return nil, err
+	}
+	for i := range flights {
+		t := tracks[flights[i].ICAO24]
+		t.Flight = &flights[i]
+		tracks[flights[i].ICAO24] = t
+	}
+
+	return tracks, nil
+}
+
+// upsertFlight stores f. The departure airport is inferred when
+// inferDeparture is set, and kept otherwise; the arrival airport is inferred
+// when the flight closes.
+func upsertFlight(ctx context.Context, tx pgx.Tx, f domain.Flight, inferDeparture bool) error {
+	callsigns := f.Callsigns
+	if callsigns == nil {
+		callsigns = []string{}
+	}
+
+	_, err := tx.Exec(ctx, `
+		INSERT INTO flights (`+flightColumns+`)
+		VALUES (
+			$1, $2, $3, $4, $5, $6, $7,
+			$8, $9, $10, $11,
+			$12, $13, $14,
+			$15, $16, $17,
+			$18, $19, $20,
+			$21, $22, $23, $24,
+			CASE WHEN $26 THEN infer_airport($18, $19, $20, false, $27, $28, $29) ELSE $25 END,
+			CASE WHEN $7 <> $30 THEN infer_airport($15, $16, $17, $11::timestamp IS NOT NULL, $27, $28, $29) END
+		)
+		ON CONFLICT (id) DO UPDATE SET
+			callsign = EXCLUDED.callsign,
+			callsigns = EXCLUDED.callsigns,
+			origin_country = EXCLUDED.origin_country,
+			category = EXCLUDED.category,
+			status = EXCLUDED.status,
+			last_seen = EXCLUDED.last_seen,
+			takeoff = EXCLUDED.takeoff,
+			landing = EXCLUDED.landing,
+			start_latitude = EXCLUDED.start_latitude,
+			start_longitude = EXCLUDED.start_longitude,
+			start_altitude = EXCLUDED.start_altitude,
+			end_latitude = EXCLUDED.end_latitude,
+			end_longitude = EXCLUDED.end_longitude,
+			end_altitude = EXCLUDED.end_altitude,
+			departure_latitude = EXCLUDED.departure_latitude,
+			departure_longitude = EXCLUDED.departure_longitude,
+			departure_altitude = EXCLUDED.departure_altitude,
+			max_altitude = EXCLUDED.max_altitude,
+			max_velocity = EXCLUDED.max_velocity,
+			distance = EXCLUDED.distance,
+			observations = EXCLUDED.observations,
+			departure_airport = EXCLUDED.departure_airport,
+			arrival_airport = EXCLUDED.arrival_airport
+	`,
+		f.ID, f.ICAO24, f.Callsign, callsigns, f.OriginCountry, f.Category, f.Status,
+		f.FirstSeen, f.LastSeen, f.Takeoff, f.Landing,
+		f.StartLatitude, f.StartLongitude, f.StartAltitude,
+		f.EndLatitude, f.EndLongitude, f.EndAltitude,
+		f.DepartureLatitude, f.DepartureLongitude, f.DepartureAltitude,
+		f.MaxAltitude, f.MaxVelocity, f.Distance, f.Observations,
+		f.DepartureAirport, inferDeparture,
+		domain.ApproachRadius, domain.ApproachHeight, domain.InferredAirportTypes,
+		domain.FlightActive,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store flight %s: %w", f.ID, err)
+	}
+	return nil
+}
+
+// departureMoved reports whether f has a departure fix other than that of
+// stored, the open flight as loaded, so its departure airport is inferred
+// once per fix rather than on every store.
+func departureMoved(stored *domain.Flight, f domain.Flight) bool {
+	if f.DepartureLatitude == nil {
+		return false
+	}
+	if stored == nil || stored.ID != f.ID {
+		return true
+	}
+	return !equalFloat(stored.DepartureLatitude, f.DepartureLatitude) ||
+		!equalFloat(stored.DepartureLongitude, f.DepartureLongitude) ||
+		!equalFloat(stored.DepartureAltitude, f.DepartureAltitude)
+}
+
+func equalFloat(a, b *float64) bool {
+	return a == b || (a != nil && b != nil && *a == *b)
+}
+
+// querier is implemented by *pgxpool.Pool and pgx.Tx.
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+func queryFlights(ctx context.Context, q querier, query string, params ...any) ([]domain.Flight, error) {
+	rows, err := q.Query(ctx, query, params...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query flights: %w", err)
+	}
+	defer rows.Close()
+
+	var flights []domain.Flight
+	for rows.Next() {
+		var f domain.Flight
+		var airline nullableAirline
+		if err := rows.Scan(
+			&f.ID, &f.ICAO24, &f.Callsign, &f.Callsigns, &f.OriginCountry, &f.Category, &f.Status,
+			&f.FirstSeen, &f.LastSeen, &f.Takeoff, &f.Landing,
+			&f.StartLatitude, &f.StartLongitude, &f.StartAltitude,
+			&f.EndLatitude, &f.EndLongitude, &f.EndAltitude,
+			&f.DepartureLatitude, &f.DepartureLongitude, &f.DepartureAltitude,
+			&f.MaxAltitude, &f.MaxVelocity, &f.Distance, &f.Observations,
+			&f.DepartureAirport, &f.ArrivalAirport,
+			&airline.icao, &airline.iata, &airline.name, &airline.country, &airline.telephony,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan flight row: %w", err)
+		}
+		f.Airline = airline.airline()
+		flights = append(flights, f)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating flight rows: %w", err)
+	}
+
+	return flights, nil
+}
+
+func buildFlightsQuery(filter *domain.FlightFilter) (string, []any) {
+	var query strings.Builder
+	var params []any
+
+	query.WriteString(flightSelect + ` WHERE 1 = 1`)
+
+	if filter != nil {
+		if filter.ICAO24 != nil {
+			params = append(params, *filter.ICAO24)
+			query.WriteString(fmt.Sprintf(" AND icao24 = $%d", len(params)))
+		}
+		if filter.Callsign != nil {
+			params = append(params, *filter.Callsign)
+			query.WriteString(fmt.Sprintf(" AND $%d = ANY(callsigns)", len(params)))
+		}
+		if filter.OriginCountry != nil {
+			params = append(params, *filter.OriginCountry)
+			query.WriteString(fmt.Sprintf(" AND origin_country = $%d", len(params)))
+		}
+		if filter.Category != nil {
+			params = append(params, *filter.Category)
+			query.WriteString(fmt.Sprintf(" AND category = $%d", len(params)))
+		}
+		if filter.Status != nil {
+			params = append(params, *filter.Status)
+			query.WriteString(fmt.Sprintf(" AND status = $%d", len(params)))
+		}
+		if filter.Departure != nil {
+			params = append(params, *filter.Departure)
+			query.WriteString(fmt.Sprintf(" AND departure_airport = $%d", len(params)))
+		}
+		if filter.Arrival != nil {
+			params = append(params, *filter.Arrival)
+			query.WriteString(fmt.Sprintf(" AND arrival_airport = $%d", len(params)))
+		}
+		if filter.TypeCode != nil {
+			params = append(params, *filter.TypeCode)
+			query.WriteString(fmt.Sprintf(" AND icao24 IN (SELECT icao24 FROM aircraft WHERE typecode = $%d)", len(params)))
+		}
+		if filter.Operator != nil {
+			params = append(params, *filter.Operator)
+			query.WriteString(fmt.Sprintf(" AND icao24 IN (SELECT icao24 FROM aircraft WHERE operator_icao = $%d)", len(params)))
+		}
+		if filter.Airline != nil {
+			params = append(params, *filter.Airline)
+			query.WriteString(fmt.Sprintf(" AND airline_designator(flights.callsign) = $%d", len(params)))
+		}
+		if filter.From != nil {
+			params = append(params, *filter.From)
+			query.WriteString(fmt.Sprintf(" AND last_seen >= $%d", len(params)))
+		}
+		if filter.To != nil {
+			params = append(params, *filter.To)
+			query.WriteString(fmt.Sprintf(" AND first_seen <= $%d", len(params)))
+		}
+	}
+
+	query.WriteString(" ORDER BY first_seen DESC, id")
+
+	if filter != nil && filter.Limit > 0 {
+		params = append(params, filter.Limit)
+		query.WriteString(fmt.Sprintf(" LIMIT $%d", len(params)))
+	}
+
+	return query.String(), params
+}
+
+// GetFlights returns the flights matching filter, most recent first.
+func (d *Database) GetFlights(ctx context.Context, filter *domain.FlightFilter) ([]domain.Flight, error) {
+	query, params := buildFlightsQuery(filter)
+	return queryFlights(ctx, d.Client, query, params...)
+}
@@ -0,0 +1,135 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/northeastloon/flight_tracker/internal/domain"
+	"github.com/northeastloon/flight_tracker/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var importColumns = []string{
+	"source", "icao24", "callsign", "origin_country", "time_position",
+	"last_contact", "longitude", "latitude", "baro_altitude", "on_ground",
+	"velocity", "true_track", "vertical_rate", "sensors", "geo_altitude",
+	"squawk", "spi", "position_source", "category", "extras",
+	"sources",
+}
+
+// ImportTelemetry bulk-loads historical telemetry and returns how many rows
+// were new. Rows are copied into a temporary table and moved into telemetry
+// in one statement that skips those already stored, so an import can be
+// repeated or resumed safely. The hourly rollups and flights are updated as
+// StoreTelemetry does, except that no flights are closed as lost: see
+// buildFlights for how imports and live data interact.
+//
+// Imported rows are marked as such and kept: the cleanup trigger, which is
+// bypassed for the transaction, prunes only live rows, so history older than
+// the retention window survives later live inserts.
+func (d *Database) ImportTelemetry(ctx context.Context, data []domain.Telemetry) (inserted int, err error) {
+	ctx, span := tracing.Tracer("postgres").Start(ctx, "postgres.import_telemetry",
+		trace.WithAttributes(attribute.Int("telemetry.rows", len(data))))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.SetAttributes(attribute.Int("telemetry.inserted", inserted))
+		span.End()
+	}()
+
+	tx, err := d.Client.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SET LOCAL flight_tracker.bulk_import = 'on'`); err != nil {
+		return 0, fmt.Errorf("failed to enable bulk import: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `CREATE TEMP TABLE telemetry_import (LIKE telemetry) ON COMMIT DROP`); err != nil {
+		return 0, fmt.Errorf("failed to create import table: %w", err)
+	}
+
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"telemetry_import"}, importColumns,
+		pgx.CopyFromSlice(len(data), func(i int) ([]any, error) {
+			t := data[i]
+			if t.Source == "" {
+				return nil, fmt.Errorf("telemetry for %s has no source", t.ICAO24)
+			}
+			return []any{
+				t.Source, t.ICAO24, t.Callsign, t.OriginCountry, t.TimePosition,
+				t.LastContact, t.Longitude, t.Latitude, t.BaroAltitude, t.OnGround,
+				t.Velocity, t.TrueTrack, t.VerticalRate, t.Sensors, t.GeoAltitude,
+				t.Squawk, t.SPI, t.PositionSource, t.Category, t.Extras,
+				sourcesOf(t),
+			}, nil
+		}),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to copy telemetry: %w", err)
+	}
+
+	rows, err := tx.Query(ctx, `
+		INSERT INTO telemetry (
+			source, icao24, callsign, origin_country, time_position,
+			last_contact, longitude, latitude, baro_altitude, on_ground,
+			velocity, true_track, vertical_rate, sensors, geo_altitude,
+			squawk, spi, position_source, category, extras,
+			sources, imported
+		)
+		SELECT
+			source, icao24, callsign, origin_country, time_position,
+			last_contact, longitude, latitude, baro_altitude, on_ground,
+			velocity, true_track, vertical_rate, sensors, geo_altitude,
+			squawk, spi, position_source, category, extras,
+			sources, true
+		FROM telemetry_import
+		ON CONFLICT (source, icao24, last_contact) DO NOTHING
+		RETURNING source, sources, icao24, callsign, origin_country, last_contact,
+			latitude, longitude, baro_altitude, geo_altitude, on_ground,
+			velocity, category
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert imported telemetry: %w", err)
+	}
+
+	// the rollups and flights only need the columns returned above
+	var added []domain.Telemetry
+	for rows.Next() {
+		var t domain.Telemetry
+		if err := rows.Scan(
+			&t.Source, &t.Sources, &t.ICAO24, &t.Callsign, &t.OriginCountry, &t.LastContact,
+			&t.Latitude, &t.Longitude, &t.BaroAltitude, &t.GeoAltitude, &t.OnGround,
+			&t.Velocity, &t.Category,
+		); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan imported row: %w", err)
+		}
+		added = append(added, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to insert imported telemetry: %w", err)
+	}
+
+	if err := rollupTraffic(ctx, tx, added); err != nil {
+		return 0, err
+	}
+	if err := rollupCoverage(ctx, tx, added); err != nil {
+		return 0, err
+	}
+	if err := d.buildFlights(ctx, tx, added, false); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return len(added), nil
+}
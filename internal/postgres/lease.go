@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/northeastloon/flight_tracker/internal/domain"
+)
+
+// IngestLeaseName is the ingest_lease row contended for by ingestion replicas.
+const IngestLeaseName = "ingest"
+
+// Lease is a named row in ingest_lease. At most one holder owns it until it
+// expires; the owner extends it by calling TryAcquire again.
+type Lease struct {
+	db     *Database
+	name   string
+	holder string
+	ttl    time.Duration
+}
+
+var _ domain.LeaderLease = (*Lease)(nil)
+
+// NewLease returns the lease name as seen by holder. Each acquisition or
+// renewal keeps the lease for ttl.
+func (d *Database) NewLease(name, holder string, ttl time.Duration) *Lease {
+	return &Lease{db: d, name: name, holder: holder, ttl: ttl}
+}
+
+// TryAcquire takes the lease if it is free or expired, or renews it if this
+// holder already owns it. It reports whether the holder owns the lease.
+// Expiry is judged by the database clock so replicas need not agree on time.
+func (l *Lease) TryAcquire(ctx context.Context) (bool, error) {
+	var holder string
+	err := l.db.Client.QueryRow(ctx, `
+		INSERT INTO ingest_lease (name, holder, acquired_at, renewed_at, expires_at)
+		VALUES ($1, $2, now(), now(), now() + $3::float8 * interval '1 second')
+		ON CONFLICT (name) DO UPDATE SET
+			holder = EXCLUDED.holder,
+			acquired_at = CASE
+				WHEN ingest_lease.holder = EXCLUDED.holder THEN ingest_lease.acquired_at
+				ELSE EXCLUDED.acquired_at
+			END,
+			renewed_at = EXCLUDED.renewed_at,
+			expires_at = EXCLUDED.expires_at
+		WHERE ingest_lease.holder = EXCLUDED.holder OR ingest_lease.expires_at < now()
+		RETURNING holder
+	`, l.name, l.holder, l.ttl.Seconds()).Scan(&holder)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lease %s: %w", l.name, err)
+	}
+
+	return holder == l.holder, nil
+}
+
+// Release gives the lease up early so another replica can take over without
+// waiting for it to expire. It does nothing if the holder does not own it.
+func (l *Lease) Release(ctx context.Context) error {
+	_, err := l.db.Client.Exec(ctx, `
+		DELETE FROM ingest_lease WHERE name = $1 AND holder = $2
+	`, l.name, l.holder)
+	if err != nil {
+		return fmt.Errorf("failed to release lease %s: %w", l.name, err)
+	}
+	return nil
+}
+
+// GetLeaseStatus returns the current state of the ingest lease, or nil when
+// no replica has taken it yet.
+func (d *Database) GetLeaseStatus(ctx context.Context) (*domain.LeaseStatus, error) {
+	var st domain.LeaseStatus
+	err := d.Client.QueryRow(ctx, `
+		SELECT name, holder, acquired_at, renewed_at, expires_at, expires_at > now()
+		FROM ingest_lease
+		WHERE name = $1
+	`, IngestLeaseName).Scan(&st.Name, &st.Holder, &st.AcquiredAt, &st.RenewedAt, &st.ExpiresAt, &st.Valid)
+	if errors.Is(err, pgx.ErrNoRows) || isUndefinedTable(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query lease status: %w", err)
+	}
+
+	return &st, nil
+}
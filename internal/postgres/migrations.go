@@ -0,0 +1,1237 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// migration is one step of the schema history. Versions start at 1 and are
+// applied in order; each runs in its own transaction.
+type migration struct {
+	version int
+	name    string
+	up      string
+	down    string
+
+	// backfill, when set, rewrites telemetry after up has committed, one
+	// transaction per backfillWindow of last_contact, given as $1 to $2, so
+	// a large table is not rewritten in one transaction. The migration is
+	// recorded once the backfill completes; until then up is run again by
+	// the next migrate up, so it must be idempotent.
+	backfill string
+	// finish, when set, runs once the backfill completes, in the transaction
+	// that records the migration, e.g. to add a constraint the backfill made
+	// hold.
+	finish string
+}
+
+// backfillWindow is the span of telemetry each backfill transaction covers.
+const backfillWindow = time.Hour
+
+// MigrationState reports whether a migration has been applied.
+type MigrationState struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
+
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "initial schema",
+		up: `
+	CREATE EXTENSION IF NOT EXISTS postgis;
+
+	CREATE TABLE IF NOT EXISTS opensky (
+    	icao24 TEXT NOT NULL,
+    	callsign TEXT,
+    	origin_country TEXT,
+    	time_position TIMESTAMP,
+    	last_contact TIMESTAMP NOT NULL,
+    	longitude DOUBLE PRECISION,
+    	latitude DOUBLE PRECISION,
+    	baro_altitude DOUBLE PRECISION,
+    	on_ground BOOLEAN,
+    	velocity DOUBLE PRECISION,
+    	true_track DOUBLE PRECISION,
+    	vertical_rate DOUBLE PRECISION,
+    	sensors INTEGER[],
+    	geo_altitude DOUBLE PRECISION,
+    	squawk TEXT,
+    	spi BOOLEAN,
+    	position_source INTEGER,
+    	category INTEGER
+	);
+
+	CREATE TABLE IF NOT EXISTS opensky_category (
+    	id INTEGER PRIMARY KEY,
+    	category TEXT
+	);
+
+	INSERT INTO opensky_category (id, category) VALUES
+    	(0, 'No information'),
+    	(1, 'No ADS-B Emitter Category Information'),
+    	(2, 'Light (< 15500 lbs)'),
+    	(3, 'Small (15500 to 75000 lbs)'),
+   		 (4, 'Large (75000 to 300000 lbs)'),
+    	(5, 'High Vortex Large'),
+    	(6, 'Heavy (> 300000 lbs)'),
+    	(7, 'High Performance'),
+    	(8, 'Rotorcraft'),
+    	(9, 'Glider / sailplane'),
+    	(10, 'Lighter-than-air'),
+    	(11, 'Parachutist / Skydiver'),
+    	(12, 'Ultralight / hang-glider / paraglider'),
+    	(13, 'Reserved'),
+    	(14, 'Unmanned Aerial Vehicle'),
+    	(15, 'Space / Trans-atmospheric vehicle'),
+    	(16, 'Surface Vehicle – Emergency Vehicle'),
+    	(17, 'Surface Vehicle – Service Vehicle'),
+    	(18, 'Point Obstacle'),
+    	(19, 'Cluster Obstacle'),
+    	(20, 'Line Obstacle')
+	ON CONFLICT (id) DO NOTHING;  
+
+	DROP VIEW IF EXISTS aircraft_state;
+	CREATE VIEW aircraft_state AS
+		SELECT 
+   		 	icao24, 
+   			callsign, 
+   			origin_country, 
+   			time_position, 
+   			last_contact,
+   			longitude, 
+   			latitude, 
+   			ST_SetSRID(ST_MakePoint(longitude, latitude), 4326)::geography AS position,  
+    		baro_altitude, 
+			on_ground, 
+			velocity,
+			true_track, 
+			vertical_rate, 
+			sensors, 
+			geo_altitude, 
+			squawk,
+			spi, 
+			position_source,
+			category
+		FROM opensky
+		ORDER BY icao24, time_position DESC;
+
+	-- Optional indices
+	CREATE INDEX IF NOT EXISTS idx_opensky_icao_last_contact ON opensky (icao24, last_contact DESC);
+	CREATE INDEX IF NOT EXISTS idx_opensky_lat_lon ON opensky (latitude, longitude);
+	CREATE INDEX IF NOT EXISTS idx_opensky_callsign ON opensky (callsign);
+
+	-- First create the trigger function
+	CREATE OR REPLACE FUNCTION cleanup_old_observations()
+	RETURNS TRIGGER AS $$
+	BEGIN
+		-- Remove aircraft that have landed (on_ground changed from false to true)
+		DELETE FROM opensky 
+		WHERE icao24 = NEW.icao24 
+		AND on_ground = false 
+		AND NEW.on_ground = true;
+
+		-- Remove observations older than 24 hours
+		DELETE FROM opensky 
+		WHERE last_contact < NOW() - INTERVAL '24 hours';
+
+		RETURN NEW;
+	END;
+	$$ LANGUAGE plpgsql;
+
+	-- Then create the trigger
+	DROP TRIGGER IF EXISTS trigger_cleanup_observations ON opensky;
+	CREATE TRIGGER trigger_cleanup_observations
+		BEFORE INSERT ON opensky
+		FOR EACH ROW
+		EXECUTE FUNCTION cleanup_old_observations();
+	`,
+		down: `
+	DROP TRIGGER IF EXISTS trigger_cleanup_observations ON opensky;
+	DROP FUNCTION IF EXISTS cleanup_old_observations();
+	DROP VIEW IF EXISTS aircraft_state;
+	DROP TABLE IF EXISTS opensky_category;
+	DROP TABLE IF EXISTS opensky;
+	`,
+	},
+	{
+		version: 2,
+		name:    "position source labels",
+		up: `
+	CREATE TABLE IF NOT EXISTS opensky_position_source (
+    	id INTEGER PRIMARY KEY,
+    	position_source TEXT
+	);
+
+	INSERT INTO opensky_position_source (id, position_source) VALUES
+    	(0, 'ADS-B'),
+    	(1, 'ASTERIX'),
+    	(2, 'MLAT'),
+    	(3, 'FLARM')
+	ON CONFLICT (id) DO NOTHING;
+
+	DROP VIEW IF EXISTS aircraft_state;
+	CREATE VIEW aircraft_state AS
+		SELECT 
+   		 	o.icao24, 
+   			o.callsign, 
+   			o.origin_country, 
+   			o.time_position, 
+   			o.last_contact,
+   			o.longitude, 
+   			o.latitude, 
+   			ST_SetSRID(ST_MakePoint(o.longitude, o.latitude), 4326)::geography AS position,  
+    		o.baro_altitude, 
+			o.on_ground, 
+			o.velocity,
+			o.true_track, 
+			o.vertical_rate, 
+			o.sensors, 
+			o.geo_altitude, 
+			o.squawk,
+			o.spi, 
+			o.position_source,
+			ps.position_source AS position_source_label,
+			o.category,
+			c.category AS category_label
+		FROM opensky o
+		LEFT JOIN opensky_category c ON c.id = o.category
+		LEFT JOIN opensky_position_source ps ON ps.id = o.position_source
+		ORDER BY o.icao24, o.time_position DESC;
+	`,
+		down: `
+	DROP VIEW IF EXISTS aircraft_state;
+	CREATE VIEW aircraft_state AS
+		SELECT 
+   		 	icao24, 
+   			callsign, 
+   			origin_country, 
+   			time_position, 
+   			last_contact,
+   			longitude, 
+   			latitude, 
+   			ST_SetSRID(ST_MakePoint(longitude, latitude), 4326)::geography AS position,  
+    		baro_altitude, 
+			on_ground, 
+			velocity,
+			true_track, 
+			vertical_rate, 
+			sensors, 
+			geo_altitude, 
+			squawk,
+			spi, 
+			position_source,
+			category
+		FROM opensky
+		ORDER BY icao24, time_position DESC;
+
+	DROP TABLE IF EXISTS opensky_position_source;
+	`,
+	},
+	{
+		version: 3,
+		name:    "hourly traffic rollup",
+		up: `
+	-- Hourly traffic rollup, maintained at ingest time by StoreTelemetry.
+	-- One row per aircraft per 1° grid cell per hour, so distinct-aircraft
+	-- counts can be computed for any coarser bucket without scanning opensky.
+	CREATE TABLE IF NOT EXISTS traffic_hourly (
+		bucket TIMESTAMP NOT NULL,
+		icao24 TEXT NOT NULL,
+		cell_lat SMALLINT NOT NULL,
+		cell_lon SMALLINT NOT NULL,
+		airborne BOOLEAN NOT NULL,
+		origin_country TEXT,
+		category INTEGER,
+		observations INTEGER NOT NULL,
+		PRIMARY KEY (bucket, icao24, cell_lat, cell_lon, airborne)
+	);
+	`,
+		down: `
+	DROP TABLE IF EXISTS traffic_hourly;
+	`,
+	},
+	{
+		version: 4,
+		name:    "ingest lease",
+		up: `
+	-- Leader lease for ingestion. The replica named in holder may ingest
+	-- until expires_at; any replica may take the lease once it has expired.
+	CREATE TABLE IF NOT EXISTS ingest_lease (
+		name TEXT PRIMARY KEY,
+		holder TEXT NOT NULL,
+		acquired_at TIMESTAMPTZ NOT NULL,
+		renewed_at TIMESTAMPTZ NOT NULL,
+		expires_at TIMESTAMPTZ NOT NULL
+	);
+	`,
+		down: `
+	DROP TABLE IF EXISTS ingest_lease;
+	`,
+	},
+	{
+		version: 5,
+		name:    "source-aware telemetry",
+		up: `
+	-- Normalized state vectors from every provider. extras holds
+	-- provider-specific fields with no column of their own.
+	CREATE TABLE IF NOT EXISTS telemetry (
+		source TEXT NOT NULL,
+		icao24 TEXT NOT NULL,
+		callsign TEXT,
+		origin_country TEXT,
+		time_position TIMESTAMP,
+		last_contact TIMESTAMP NOT NULL,
+		longitude DOUBLE PRECISION,
+		latitude DOUBLE PRECISION,
+		baro_altitude DOUBLE PRECISION,
+		on_ground BOOLEAN,
+		velocity DOUBLE PRECISION,
+		true_track DOUBLE PRECISION,
+		vertical_rate DOUBLE PRECISION,
+		sensors INTEGER[],
+		geo_altitude DOUBLE PRECISION,
+		squawk TEXT,
+		spi BOOLEAN,
+		position_source INTEGER,
+		category INTEGER,
+		extras JSONB,
+		PRIMARY KEY (source, icao24, last_contact)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_telemetry_icao_last_contact ON telemetry (icao24, last_contact DESC);
+	CREATE INDEX IF NOT EXISTS idx_telemetry_last_contact ON telemetry (last_contact);
+	CREATE INDEX IF NOT EXISTS idx_telemetry_lat_lon ON telemetry (latitude, longitude);
+	CREATE INDEX IF NOT EXISTS idx_telemetry_callsign ON telemetry (callsign);
+
+	INSERT INTO telemetry (
+		source, icao24, callsign, origin_country, time_position, last_contact,
+		longitude, latitude, baro_altitude, on_ground, velocity, true_track,
+		vertical_rate, sensors, geo_altitude, squawk, spi, position_source, category
+	)
+	SELECT
+		'opensky', icao24, callsign, origin_country, time_position, last_contact,
+		longitude, latitude, baro_altitude, on_ground, velocity, true_track,
+		vertical_rate, sensors, geo_altitude, squawk, spi, position_source, category
+	FROM opensky
+	ON CONFLICT DO NOTHING;
+
+	DROP VIEW IF EXISTS aircraft_state;
+	CREATE VIEW aircraft_state AS
+		SELECT
+			t.source,
+			t.icao24,
+			t.callsign,
+			t.origin_country,
+			t.time_position,
+			t.last_contact,
+			t.longitude,
+			t.latitude,
+			ST_SetSRID(ST_MakePoint(t.longitude, t.latitude), 4326)::geography AS position,
+			t.baro_altitude,
+			t.on_ground,
+			t.velocity,
+			t.true_track,
+			t.vertical_rate,
+			t.sensors,
+			t.geo_altitude,
+			t.squawk,
+			t.spi,
+			t.position_source,
+			ps.position_source AS position_source_label,
+			t.category,
+			c.category AS category_label,
+			t.extras
+		FROM telemetry t
+		LEFT JOIN opensky_category c ON c.id = t.category
+		LEFT JOIN opensky_position_source ps ON ps.id = t.position_source;
+
+	CREATE OR REPLACE FUNCTION cleanup_old_observations()
+	RETURNS TRIGGER AS $$
+	BEGIN
+		-- Remove aircraft that have landed (on_ground changed from false to true)
+		DELETE FROM telemetry
+		WHERE icao24 = NEW.icao24
+		AND on_ground = false
+		AND NEW.on_ground = true;
+
+		-- Remove observations older than 24 hours
+		DELETE FROM telemetry
+		WHERE last_contact < NOW() - INTERVAL '24 hours';
+
+		RETURN NEW;
+	END;
+	$$ LANGUAGE plpgsql;
+
+	DROP TRIGGER IF EXISTS trigger_cleanup_observations ON opensky;
+	CREATE TRIGGER trigger_cleanup_observations
+		BEFORE INSERT ON telemetry
+		FOR EACH ROW
+		EXECUTE FUNCTION cleanup_old_observations();
+
+	DROP TABLE IF EXISTS opensky;
+	`,
+		down: `
+	CREATE TABLE IF NOT EXISTS opensky (
+		icao24 TEXT NOT NULL,
+		callsign TEXT,
+		origin_country TEXT,
+		time_position TIMESTAMP,
+		last_contact TIMESTAMP NOT NULL,
+		longitude DOUBLE PRECISION,
+		latitude DOUBLE PRECISION,
+		baro_altitude DOUBLE PRECISION,
+		on_ground BOOLEAN,
+		velocity DOUBLE PRECISION,
+		true_track DOUBLE PRECISION,
+		vertical_rate DOUBLE PRECISION,
+		sensors INTEGER[],
+		geo_altitude DOUBLE PRECISION,
+		squawk TEXT,
+		spi BOOLEAN,
+		position_source INTEGER,
+		category INTEGER
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_opensky_icao_last_contact ON opensky (icao24, last_contact DESC);
+	CREATE INDEX IF NOT EXISTS idx_opensky_lat_lon ON opensky (latitude, longitude);
+	CREATE INDEX IF NOT EXISTS idx_opensky_callsign ON opensky (callsign);
+
+	INSERT INTO opensky (
+		icao24, callsign, origin_country, time_position, last_contact,
+		longitude, latitude, baro_altitude, on_ground, velocity, true_track,
+		vertical_rate, sensors, geo_altitude, squawk, spi, position_source, category
+	)
+	SELECT
+		icao24, callsign, origin_country, time_position, last_contact,
+		longitude, latitude, baro_altitude, on_ground, velocity, true_track,
+		vertical_rate, sensors, geo_altitude, squawk, spi, position_source, category
+	FROM telemetry
+	WHERE source = 'opensky';
+
+	DROP VIEW IF EXISTS aircraft_state;
+	CREATE VIEW aircraft_state AS
+		SELECT
+			o.icao24,
+			o.callsign,
+			o.origin_country,
+			o.time_position,
+			o.last_contact,
+			o.longitude,
+			o.latitude,
+			ST_SetSRID(ST_MakePoint(o.longitude, o.latitude), 4326)::geography AS position,
+			o.baro_altitude,
+			o.on_ground,
+			o.velocity,
+			o.true_track,
+			o.vertical_rate,
+			o.sensors,
+			o.geo_altitude,
+			o.squawk,
+			o.spi,
+			o.position_source,
+			ps.position_source AS position_source_label,
+			o.category,
+			c.category AS category_label
+		FROM opensky o
+		LEFT JOIN opensky_category c ON c.id = o.category
+		LEFT JOIN opensky_position_source ps ON ps.id = o.position_source
+		ORDER BY o.icao24, o.time_position DESC;
+
+	CREATE OR REPLACE FUNCTION cleanup_old_observations()
+	RETURNS TRIGGER AS $$
+	BEGIN
+		DELETE FROM opensky
+		WHERE icao24 = NEW.icao24
+		AND on_ground = false
+		AND NEW.on_ground = true;
+
+		DELETE FROM opensky
+		WHERE last_contact < NOW() - INTERVAL '24 hours';
+
+		RETURN NEW;
+	END;
+	$$ LANGUAGE plpgsql;
+
+	DROP TRIGGER IF EXISTS trigger_cleanup_observations ON telemetry;
+	CREATE TRIGGER trigger_cleanup_observations
+		BEFORE INSERT ON opensky
+		FOR EACH ROW
+		EXECUTE FUNCTION cleanup_old_observations();
+
+	DROP TABLE IF EXISTS telemetry;
+	`,
+	},
+	{
+		version: 6,
+		name:    "fused sources and coverage rollup",
+		up: `
+	-- Feeds that saw each aircraft in the fused snapshot a row was chosen
+	-- from. Rows stored before fusion were seen by their own source only;
+	-- the backfill fills them in before the column is made NOT NULL.
+	ALTER TABLE telemetry ADD COLUMN IF NOT EXISTS sources TEXT[];
+
+	-- Hourly per-feed coverage, maintained at ingest time by StoreTelemetry.
+	-- One row per feed per aircraft per hour: observations counts the stored
+	-- states the feed saw, selected those fusion took from it and exclusive
+	-- those no other feed saw.
+	CREATE TABLE IF NOT EXISTS coverage_hourly (
+		bucket TIMESTAMP NOT NULL,
+		source TEXT NOT NULL,
+		icao24 TEXT NOT NULL,
+		observations INTEGER NOT NULL,
+		selected INTEGER NOT NULL,
+		exclusive INTEGER NOT NULL,
+		PRIMARY KEY (bucket, source, icao24)
+	);
+
+	INSERT INTO coverage_hourly (bucket, source, icao24, observations, selected, exclusive)
+	SELECT date_trunc('hour', last_contact), source, icao24, COUNT(*), COUNT(*), COUNT(*)
+	FROM telemetry
+	GROUP BY 1, 2, 3
+	ON CONFLICT DO NOTHING;
+
+	DROP VIEW IF EXISTS aircraft_state;
+	CREATE VIEW aircraft_state AS
+		SELECT
+			t.source,
+			t.icao24,
+			t.callsign,
+			t.origin_country,
+			t.time_position,
+			t.last_contact,
+			t.longitude,
+			t.latitude,
+			ST_SetSRID(ST_MakePoint(t.longitude, t.latitude), 4326)::geography AS position,
+			t.baro_altitude,
+			t.on_ground,
+			t.velocity,
+			t.true_track,
+			t.vertical_rate,
+			t.sensors,
+			t.geo_altitude,
+			t.squawk,
+			t.spi,
+			t.position_source,
+			ps.position_source AS position_source_label,
+			t.category,
+			c.category AS category_label,
+			t.extras,
+			t.sources
+		FROM telemetry t
+		LEFT JOIN opensky_category c ON c.id = t.category
+		LEFT JOIN opensky_position_source ps ON ps.id = t.position_source;
+	`,
+		down: `
+	DROP VIEW IF EXISTS aircraft_state;
+	CREATE VIEW aircraft_state AS
+		SELECT
+			t.source,
+			t.icao24,
+			t.callsign,
+			t.origin_country,
+			t.time_position,
+			t.last_contact,
+			t.longitude,
+			t.latitude,
+			ST_SetSRID(ST_MakePoint(t.longitude, t.latitude), 4326)::geography AS position,
+			t.baro_altitude,
+			t.on_ground,
+			t.velocity,
+			t.true_track,
+			t.vertical_rate,
+			t.sensors,
+			t.geo_altitude,
+			t.squawk,
+			t.spi,
+			t.position_source,
+			ps.position_source AS position_source_label,
+			t.category,
+			c.category AS category_label,
+			t.extras
+		FROM telemetry t
+		LEFT JOIN opensky_category c ON c.id = t.category
+		LEFT JOIN opensky_position_source ps ON ps.id = t.position_source;
+
+	DROP TABLE IF EXISTS coverage_hourly;
+	ALTER TABLE telemetry DROP COLUMN IF EXISTS sources;
+	`,
+		backfill: `
+	UPDATE telemetry SET sources = ARRAY[source]
+	WHERE last_contact >= $1 AND last_contact < $2 AND sources IS NULL;
+	`,
+		finish: `
+	-- rows an older ingest stored while the backfill ran
+	UPDATE telemetry SET sources = ARRAY[source] WHERE sources IS NULL;
+	ALTER TABLE telemetry ALTER COLUMN sources SET NOT NULL;
+	`,
+	},
+	{
+		version: 7,
+		name:    "bulk import bypasses cleanup",
+		up: `
+	CREATE OR REPLACE FUNCTION cleanup_old_observations()
+	RETURNS TRIGGER AS $$
+	BEGIN
+		-- Historical imports set this for their transaction: their rows are
+		-- older than the retention window and too many to clean up one by one.
+		IF current_setting('flight_tracker.bulk_import', true) = 'on' THEN
+			RETURN NEW;
+		END IF;
+
+		-- Remove aircraft that have landed (on_ground changed from false to true)
+		DELETE FROM telemetry
+		WHERE icao24 = NEW.icao24
+		AND on_ground = false
+		AND NEW.on_ground = true;
+
+		-- Remove observations older than 24 hours
+		DELETE FROM telemetry
+		WHERE last_contact < NOW() - INTERVAL '24 hours';
+
+		RETURN NEW;
+	END;
+	$$ LANGUAGE plpgsql;
+	`,
+		down: `
+	CREATE OR REPLACE FUNCTION cleanup_old_observations()
+	RETURNS TRIGGER AS $$
+	BEGIN
+		-- Remove aircraft that have landed (on_ground changed from false to true)
+		DELETE FROM telemetry
+		WHERE icao24 = NEW.icao24
+		AND on_ground = false
+		AND NEW.on_ground = true;
+
+		-- Remove observations older than 24 hours
+		DELETE FROM telemetry
+		WHERE last_contact < NOW() - INTERVAL '24 hours';
+
+		RETURN NEW;
+	END;
+	$$ LANGUAGE plpgsql;
+	`,
+	},
+	{
+		version: 8,
+		name:    "flights",
+		up: `
+	-- Flights segmented from telemetry at ingest time by StoreTelemetry.
+	-- Telemetry is pruned once an aircraft lands, so flights cannot be
+	-- derived from it afterwards.
+	CREATE TABLE IF NOT EXISTS flights (
+		id TEXT PRIMARY KEY,
+		icao24 TEXT NOT NULL,
+		callsign TEXT,
+		callsigns TEXT[] NOT NULL DEFAULT '{}',
+		origin_country TEXT NOT NULL,
+		category INTEGER NOT NULL,
+		status TEXT NOT NULL,
+		first_seen TIMESTAMP NOT NULL,
+		last_seen TIMESTAMP NOT NULL,
+		takeoff TIMESTAMP,
+		landing TIMESTAMP,
+		start_latitude DOUBLE PRECISION,
+		start_longitude DOUBLE PRECISION,
+		end_latitude DOUBLE PRECISION,
+		end_longitude DOUBLE PRECISION,
+		max_altitude DOUBLE PRECISION,
+		max_velocity DOUBLE PRECISION,
+		distance DOUBLE PRECISION NOT NULL,
+		observations INTEGER NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS flights_icao24_idx ON flights (icao24, first_seen);
+	CREATE INDEX IF NOT EXISTS flights_first_seen_idx ON flights (first_seen);
+	CREATE INDEX IF NOT EXISTS flights_active_idx ON flights (last_seen) WHERE status = 'active';
+
+	-- Where segmentation stopped for each aircraft: when it was last seen,
+	-- whether it was on the ground and its open flight, if any.
+	CREATE TABLE IF NOT EXISTS flight_tracks (
+		icao24 TEXT PRIMARY KEY,
+		last_seen TIMESTAMP NOT NULL,
+		on_ground BOOLEAN NOT NULL,
+		flight_id TEXT REFERENCES flights (id) ON DELETE SET NULL
+	);
+	`,
+		down: `
+	DROP TABLE IF EXISTS flight_tracks;
+	DROP TABLE IF EXISTS flights;
+	`,
+	},
+	{
+		version: 9,
+		name:    "airports",
+		up: `
+	-- Airports and runways from the OurAirports database, replaced as a whole
+	-- by ImportAirports. Elevations and runway dimensions are in metres.
+	CREATE TABLE IF NOT EXISTS airports (
+		ident TEXT PRIMARY KEY,
+		type TEXT NOT NULL,
+		name TEXT NOT NULL,
+		icao_code TEXT,
+		iata_code TEXT,
+		latitude DOUBLE PRECISION NOT NULL,
+		longitude DOUBLE PRECISION NOT NULL,
+		position GEOGRAPHY(Point, 4326)
+			GENERATED ALWAYS AS (ST_SetSRID(ST_MakePoint(longitude, latitude), 4326)::geography) STORED,
+		elevation DOUBLE PRECISION,
+		iso_country TEXT NOT NULL,
+		iso_region TEXT NOT NULL,
+		municipality TEXT,
+		scheduled_service BOOLEAN NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS airports_position_idx ON airports USING GIST (position);
+	CREATE INDEX IF NOT EXISTS airports_icao_code_idx ON airports (icao_code);
+	CREATE INDEX IF NOT EXISTS airports_iata_code_idx ON airports (iata_code);
+
+	CREATE TABLE IF NOT EXISTS runways (
+		airport_ident TEXT NOT NULL REFERENCES airports (ident) ON DELETE CASCADE,
+		seq INTEGER NOT NULL,
+		length DOUBLE PRECISION,
+		width DOUBLE PRECISION,
+		surface TEXT,
+		lighted BOOLEAN NOT NULL,
+		closed BOOLEAN NOT NULL,
+		le_ident TEXT,
+		le_latitude DOUBLE PRECISION,
+		le_longitude DOUBLE PRECISION,
+		le_elevation DOUBLE PRECISION,
+		le_heading DOUBLE PRECISION,
+		he_ident TEXT,
+		he_latitude DOUBLE PRECISION,
+		he_longitude DOUBLE PRECISION,
+		he_elevation DOUBLE PRECISION,
+		he_heading DOUBLE PRECISION,
+		PRIMARY KEY (airport_ident, seq)
+	);
+
+	ALTER TABLE flights ADD COLUMN IF NOT EXISTS start_altitude DOUBLE PRECISION;
+	ALTER TABLE flights ADD COLUMN IF NOT EXISTS end_altitude DOUBLE PRECISION;
+	ALTER TABLE flights ADD COLUMN IF NOT EXISTS departure_airport TEXT;
+	ALTER TABLE flights ADD COLUMN IF NOT EXISTS arrival_airport TEXT;
+	CREATE INDEX IF NOT EXISTS flights_departure_idx ON flights (departure_airport, first_seen);
+	CREATE INDEX IF NOT EXISTS flights_arrival_idx ON flights (arrival_airport, first_seen);
+
+	-- The nearest airport of one of types within radius metres of a fix that
+	-- was on the ground or at most height metres above the airport.
+	CREATE OR REPLACE FUNCTION infer_airport(
+		lat DOUBLE PRECISION, lon DOUBLE PRECISION, altitude DOUBLE PRECISION,
+		on_ground BOOLEAN, radius DOUBLE PRECISION, height DOUBLE PRECISION,
+		types TEXT[]
+	)
+	RETURNS TEXT AS $$
+		SELECT a.ident
+		FROM airports a
+		WHERE lat IS NOT NULL AND lon IS NOT NULL
+		AND (on_ground OR altitude - COALESCE(a.elevation, 0) <= height)
+		AND a.type = ANY(types)
+		AND ST_DWithin(a.position, ST_SetSRID(ST_MakePoint(lon, lat), 4326)::geography, radius)
+		ORDER BY a.position <-> ST_SetSRID(ST_MakePoint(lon, lat), 4326)::geography
+		LIMIT 1
+	$$ LANGUAGE sql STABLE;
+	`,
+		down: `
+	DROP FUNCTION IF EXISTS infer_airport;
+	DROP INDEX IF EXISTS flights_departure_idx;
+	DROP INDEX IF EXISTS flights_arrival_idx;
+	ALTER TABLE flights DROP COLUMN IF EXISTS start_altitude;
+	ALTER TABLE flights DROP COLUMN IF EXISTS end_altitude;
+	ALTER TABLE flights DROP COLUMN IF EXISTS departure_airport;
+	ALTER TABLE flights DROP COLUMN IF EXISTS arrival_airport;
+	DROP TABLE IF EXISTS runways;
+	DROP TABLE IF EXISTS airports;
+	`,
+	},
+	{
+		version: 10,
+		name:    "aircraft registry",
+		up: `
+	-- Aircraft registry from the OpenSky aircraft database, kept in sync by
+	-- ReloadAircraft.
+	CREATE TABLE IF NOT EXISTS aircraft (
+		icao24 TEXT PRIMARY KEY,
+		registration TEXT,
+		manufacturer TEXT,
+		model TEXT,
+		typecode TEXT,
+		operator TEXT,
+		operator_icao TEXT,
+		owner TEXT,
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+	CREATE INDEX IF NOT EXISTS aircraft_typecode_idx ON aircraft (typecode);
+	CREATE INDEX IF NOT EXISTS aircraft_operator_icao_idx ON aircraft (operator_icao);
+
+	DROP VIEW IF EXISTS aircraft_state;
+	CREATE VIEW aircraft_state AS
+		SELECT
+			t.source,
+			t.icao24,
+			t.callsign,
+			t.origin_country,
+			t.time_position,
+			t.last_contact,
+			t.longitude,
+			t.latitude,
+			ST_SetSRID(ST_MakePoint(t.longitude, t.latitude), 4326)::geography AS position,
+			t.baro_altitude,
+			t.on_ground,
+			t.velocity,
+			t.true_track,
+			t.vertical_rate,
+			t.sensors,
+			t.geo_altitude,
+			t.squawk,
+			t.spi,
+			t.position_source,
+			ps.position_source AS position_source_label,
+			t.category,
+			c.category AS category_label,
+			t.extras,
+			t.sources,
+			a.registration,
+			a.manufacturer,
+			a.model,
+			a.typecode,
+			a.operator,
+			a.operator_icao,
+			a.owner
+		FROM telemetry t
+		LEFT JOIN opensky_category c ON c.id = t.category
+		LEFT JOIN opensky_position_source ps ON ps.id = t.position_source
+		LEFT JOIN aircraft a ON a.icao24 = t.icao24;
+	`,
+		down: `
+	DROP VIEW IF EXISTS aircraft_state;
+	CREATE VIEW aircraft_state AS
+		SELECT
+			t.source,
+			t.icao24,
+			t.callsign,
+			t.origin_country,
+			t.time_position,
+			t.last_contact,
+			t.longitude,
+			t.latitude,
+			ST_SetSRID(ST_MakePoint(t.longitude, t.latitude), 4326)::geography AS position,
+			t.baro_altitude,
+			t.on_ground,
+			t.velocity,
+			t.true_track,
+			t.vertical_rate,
+			t.sensors,
+			t.geo_altitude,
+			t.squawk,
+			t.spi,
+			t.position_source,
+			ps.position_source AS position_source_label,
+			t.category,
+			c.category AS category_label,
+			t.extras,
+			t.sources
+		FROM telemetry t
+		LEFT JOIN opensky_category c ON c.id = t.category
+		LEFT JOIN opensky_position_source ps ON ps.id = t.position_source;
+
+	DROP TABLE IF EXISTS aircraft;
+	`,
+	},
+	{
+		version: 11,
+		name:    "airlines",
+		up: `
+	-- Airline table, keyed by ICAO designator and replaced by ImportAirlines.
+	CREATE TABLE IF NOT EXISTS airlines (
+		icao TEXT PRIMARY KEY,
+		iata TEXT,
+		name TEXT NOT NULL,
+		country TEXT,
+		telephony TEXT
+	);
+
+	-- The ICAO designator of an airline callsign such as BAW123A, or NULL for
+	-- other callsigns. Mirrors domain.SplitCallsign.
+	CREATE OR REPLACE FUNCTION airline_designator(callsign TEXT)
+	RETURNS TEXT AS $$
+		SELECT substring(callsign FROM '^([A-Z]{3})[0-9][0-9A-Z]{0,3}$')
+	$$ LANGUAGE sql IMMUTABLE;
+
+	CREATE INDEX IF NOT EXISTS idx_telemetry_airline ON telemetry (airline_designator(callsign));
+	CREATE INDEX IF NOT EXISTS flights_airline_idx ON flights (airline_designator(callsign));
+
+	-- callsigns used to be stored as sent, with their trailing padding;
+	-- telemetry is normalized by the backfill
+	UPDATE flights SET callsign = NULLIF(upper(btrim(callsign)), '')
+	WHERE callsign IS DISTINCT FROM NULLIF(upper(btrim(callsign)), '');
+
+	DROP VIEW IF EXISTS aircraft_state;
+	CREATE VIEW aircraft_state AS
+		SELECT
+			t.source,
+			t.icao24,
+			t.callsign,
+			t.origin_country,
+			t.time_position,
+			t.last_contact,
+			t.longitude,
+			t.latitude,
+			ST_SetSRID(ST_MakePoint(t.longitude, t.latitude), 4326)::geography AS position,
+			t.baro_altitude,
+			t.on_ground,
+			t.velocity,
+			t.true_track,
+			t.vertical_rate,
+			t.sensors,
+			t.geo_altitude,
+			t.squawk,
+			t.spi,
+			t.position_source,
+			ps.position_source AS position_source_label,
+			t.category,
+			c.category AS category_label,
+			t.extras,
+			t.sources,
+			a.registration,
+			a.manufacturer,
+			a.model,
+			a.typecode,
+			a.operator,
+			a.operator_icao,
+			a.owner,
+			al.icao AS airline_icao,
+			al.iata AS airline_iata,
+			al.name AS airline_name,
+			al.country AS airline_country,
+			al.telephony AS airline_telephony
+		FROM telemetry t
+		LEFT JOIN opensky_category c ON c.id = t.category
+		LEFT JOIN opensky_position_source ps ON ps.id = t.position_source
+		LEFT JOIN aircraft a ON a.icao24 = t.icao24
+		LEFT JOIN airlines al ON al.icao = airline_designator(t.callsign);
+	`,
+		down: `
+	DROP VIEW IF EXISTS aircraft_state;
+	CREATE VIEW aircraft_state AS
+		SELECT
+			t.source,
+			t.icao24,
+			t.callsign,
+			t.origin_country,
+			t.time_position,
+			t.last_contact,
+			t.longitude,
+			t.latitude,
+			ST_SetSRID(ST_MakePoint(t.longitude, t.latitude), 4326)::geography AS position,
+			t.baro_altitude,
+			t.on_ground,
+			t.velocity,
+			t.true_track,
+			t.vertical_rate,
+			t.sensors,
+			t.geo_altitude,
+			t.squawk,
+			t.spi,
+			t.position_source,
+			ps.position_source AS position_source_label,
+			t.category,
+			c.category AS category_label,
+			t.extras,
+			t.sources,
+			a.registration,
+			a.manufacturer,
+			a.model,
+			a.typecode,
+			a.operator,
+			a.operator_icao,
+			a.owner
+		FROM telemetry t
+		LEFT JOIN opensky_category c ON c.id = t.category
+		LEFT JOIN opensky_position_source ps ON ps.id = t.position_source
+		LEFT JOIN aircraft a ON a.icao24 = t.icao24;
+
+	DROP INDEX IF EXISTS flights_airline_idx;
+	DROP INDEX IF EXISTS idx_telemetry_airline;
+	DROP FUNCTION IF EXISTS airline_designator(TEXT);
+	DROP TABLE IF EXISTS airlines;
+	`,
+		backfill: `
+	UPDATE telemetry SET callsign = NULLIF(upper(btrim(callsign)), '')
+	WHERE last_contact >= $1 AND last_contact < $2
+		AND callsign IS DISTINCT FROM NULLIF(upper(btrim(callsign)), '');
+	`,
+	},
+	{
+		version: 12,
+		name:    "flight departure fixes",
+		up: `
+	-- The fix each departure airport is inferred from; stored flights keep
+	-- their first fix.
+	ALTER TABLE flights ADD COLUMN IF NOT EXISTS departure_latitude DOUBLE PRECISION;
+	ALTER TABLE flights ADD COLUMN IF NOT EXISTS departure_longitude DOUBLE PRECISION;
+	ALTER TABLE flights ADD COLUMN IF NOT EXISTS departure_altitude DOUBLE PRECISION;
+	UPDATE flights SET
+		departure_latitude = start_latitude,
+		departure_longitude = start_longitude,
+		departure_altitude = start_altitude;
+	`,
+		down: `
+	ALTER TABLE flights DROP COLUMN IF EXISTS departure_latitude;
+	ALTER TABLE flights DROP COLUMN IF EXISTS departure_longitude;
+	ALTER TABLE flights DROP COLUMN IF EXISTS departure_altitude;
+	`,
+	},
+	{
+		version: 13,
+		name:    "keep imported telemetry",
+		up: `
+	-- Imported history is kept: the cleanup trigger only prunes rows stored
+	-- by live ingest.
+	ALTER TABLE telemetry ADD COLUMN IF NOT EXISTS imported BOOLEAN NOT NULL DEFAULT false;
+
+	CREATE OR REPLACE FUNCTION cleanup_old_observations()
+	RETURNS TRIGGER AS $$
+	BEGIN
+		-- Historical imports set this for their transaction: their rows are
+		-- older than the retention window and too many to clean up one by one.
+		IF current_setting('flight_tracker.bulk_import', true) = 'on' THEN
+			RETURN NEW;
+		END IF;
+
+		-- Remove aircraft that have landed (on_ground changed from false to true)
+		DELETE FROM telemetry
+		WHERE icao24 = NEW.icao24
+		AND on_ground = false
+		AND NEW.on_ground = true
+		AND NOT imported;
+
+		-- Remove observations older than 24 hours
+		DELETE FROM telemetry
+		WHERE last_contact < NOW() - INTERVAL '24 hours'
+		AND NOT imported;
+
+		RETURN NEW;
+	END;
+	$$ LANGUAGE plpgsql;
+	`,
+		down: `
+	CREATE OR REPLACE FUNCTION cleanup_old_observations()
+	RETURNS TRIGGER AS $$
+	BEGIN
+		-- Historical imports set this for their transaction: their rows are
+		-- older than the retention window and too many to clean up one by one.
+		IF current_setting('flight_tracker.bulk_import', true) = 'on' THEN
+			RETURN NEW;
+		END IF;
+
+		-- Remove aircraft that have landed (on_ground changed from false to true)
+		DELETE FROM telemetry
+		WHERE icao24 = NEW.icao24
+		AND on_ground = false
+		AND NEW.on_ground = true;
+
+		-- Remove observations older than 24 hours
+		DELETE FROM telemetry
+		WHERE last_contact < NOW() - INTERVAL '24 hours';
+
+		RETURN NEW;
+	END;
+	$$ LANGUAGE plpgsql;
+
+	ALTER TABLE telemetry DROP COLUMN IF EXISTS imported;
+	`,
+	},
+	{
+		version: 14,
+		name:    "tis-b position source",
+		up: `
+	-- Receiver feeds report TIS-B, which OpenSky has no code for; it was
+	-- stored as ASTERIX, and the backfill relabels it.
+	INSERT INTO opensky_position_source (id, position_source) VALUES (4, 'TIS-B')
+	ON CONFLICT (id) DO NOTHING;
+	`,
+		down: `
+	UPDATE telemetry SET position_source = 1 WHERE position_source = 4;
+	DELETE FROM opensky_position_source WHERE id = 4;
+	`,
+		backfill: `
+	UPDATE telemetry SET position_source = 4
+	WHERE last_contact >= $1 AND last_contact < $2
+		AND position_source = 1 AND extras->>'type' LIKE 'tisb%';
+	`,
+	},
+}
+
+// latestVersion is the schema version this binary migrates to.
+var latestVersion = migrations[len(migrations)-1].version
+
+func (d *Database) ensureMigrationsTable(ctx context.Context) error {
+	_, err := d.Client.Exec(ctx, `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL DEFAULT NOW()
+	);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+	return nil
+}
+
+func (d *Database) appliedVersions(ctx context.Context) (map[int]time.Time, error) {
+	rows, err := d.Client.Query(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schema_migrations: %w", err)
+	}
+
+	applied := make(map[int]time.Time)
+	var version int
+	var appliedAt time.Time
+	_, err = pgx.ForEachRow(rows, []any{&version, &appliedAt}, func() error {
+		applied[version] = appliedAt
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan schema_migrations: %w", err)
+	}
+
+	return applied, nil
+}
+
+// MigrateUp applies every pending migration in order.
+func (d *Database) MigrateUp(ctx context.Context) error {
+	if err := d.ensureMigrationsTable(ctx); err != nil {
+		return err
+	}
+
+	applied, err := d.appliedVersions(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if _, ok := applied[m.version]; ok {
+			continue
+		}
+
+		record := func(tx pgx.Tx) error {
+			if m.finish != "" {
+				if _, err := tx.Exec(ctx, m.finish); err != nil {
+					return err
+				}
+			}
+			_, err := tx.Exec(ctx,
+				`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`,
+				m.version, m.name)
+			return err
+		}
+
+		err := pgx.BeginFunc(ctx, d.Client, func(tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, m.up); err != nil {
+				return err
+			}
+			if m.backfill != "" {
+				return nil
+			}
+			return record(tx)
+		})
+		if err == nil && m.backfill != "" {
+			if err = d.backfill(ctx, m); err == nil {
+				err = pgx.BeginFunc(ctx, d.Client, record)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("failed to apply migration %d (%s): %w", m.version, m.name, err)
+		}
+	}
+
+	return nil
+}
+
+// backfill runs m.backfill over the stored telemetry, oldest first.
+func (d *Database) backfill(ctx context.Context, m migration) error {
+	var oldest, newest *time.Time
+	err := d.Client.QueryRow(ctx, `SELECT min(last_contact), max(last_contact) FROM telemetry`).Scan(&oldest, &newest)
+	if err != nil {
+		return fmt.Errorf("failed to find telemetry to backfill: %w", err)
+	}
+	if oldest == nil {
+		return nil
+	}
+
+	slog.Info("Backfilling telemetry, which may take a while on a large table",
+		"migration", m.version, "from", *oldest, "to", *newest)
+	var rows int64
+	for from := oldest.Truncate(backfillWindow); !from.After(*newest); from = from.Add(backfillWindow) {
+		tag, err := d.Client.Exec(ctx, m.backfill, from, from.Add(backfillWindow))
+		if err != nil {
+			return fmt.Errorf("failed to backfill telemetry from %s: %w", from.Format(time.RFC3339), err)
+		}
+		rows += tag.RowsAffected()
+	}
+	slog.Info("Backfilled telemetry", "migration", m.version, "rows", rows)
+
+	return nil
+}
+
+// MigrateDown reverts the most recently applied migrations, at most steps of
+// them.
+func (d *Database) MigrateDown(ctx context.Context, steps int) error {
+	if err := d.ensureMigrationsTable(ctx); err != nil {
+		return err
+	}
+
+	applied, err := d.appliedVersions(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i := len(migrations) - 1; i >= 0 && steps > 0; i-- {
+		m := migrations[i]
+		if _, ok := applied[m.version]; !ok {
+			continue
+		}
+
+		err := pgx.BeginFunc(ctx, d.Client, func(tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, m.down); err != nil {
+				return err
+			}
+			_, err := tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1`, m.version)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to revert migration %d (%s): %w", m.version, m.name, err)
+		}
+		steps--
+	}
+
+	return nil
+}
+
+// MigrationStatus lists every known migration and when it was applied.
+func (d *Database) MigrationStatus(ctx context.Context) ([]MigrationState, error) {
+	if err := d.ensureMigrationsTable(ctx); err != nil {
+		return nil, err
+	}
+
+	applied, err := d.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]MigrationState, 0, len(migrations))
+	for _, m := range migrations {
+		state := MigrationState{Version: m.version, Name: m.name}
+		if at, ok := applied[m.version]; ok {
+			state.AppliedAt = &at
+		}
+		states = append(states, state)
+	}
+
+	return states, nil
+}
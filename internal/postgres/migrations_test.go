@@ -0,0 +1,24 @@
+package postgres
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMigrations(t *testing.T) {
+	for i, m := range migrations {
+		if m.version != i+1 {
+			t.Errorf("migration %q has version %d, want %d", m.name, m.version, i+1)
+		}
+		if m.up == "" || m.down == "" {
+			t.Errorf("migration %d (%s) cannot be applied and reverted", m.version, m.name)
+		}
+		if m.finish != "" && m.backfill == "" {
+			t.Errorf("migration %d (%s) finishes without a backfill", m.version, m.name)
+		}
+		// a backfill rewrites one window of last_contact at a time
+		if m.backfill != "" && (!strings.Contains(m.backfill, "last_contact >= $1") || !strings.Contains(m.backfill, "last_contact < $2")) {
+			t.Errorf("migration %d (%s) backfill is not limited to its window:\n%s", m.version, m.name, m.backfill)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/northeastloon/flight_tracker/internal/domain"
+)
+
+// ReloadAircraft brings the aircraft registry in line with the given
+// records: changed rows are updated, new ones inserted and those missing
+// from the records removed. Without apply the changes are only counted.
+func (d *Database) ReloadAircraft(ctx context.Context, aircraft []domain.AircraftInfo, apply bool) (domain.RegistryDiff, error) {
+	var diff domain.RegistryDiff
+
+	tx, err := d.Client.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return diff, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// one reload at a time, so the diff is against what is replaced;
+	// readers are not blocked
+	if _, err := tx.Exec(ctx, `LOCK TABLE aircraft IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+		return diff, fmt.Errorf("failed to lock aircraft: %w", err)
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT icao24, registration, manufacturer, model, typecode, operator, operator_icao, owner
+		FROM aircraft
+	`)
+	if err != nil {
+		return diff, fmt.Errorf("failed to query aircraft: %w", err)
+	}
+	stored := make(map[string]domain.AircraftInfo)
+	for rows.Next() {
+		var a domain.AircraftInfo
+		if err := rows.Scan(&a.ICAO24, &a.Registration, &a.Manufacturer, &a.Model, &a.TypeCode, &a.Operator, &a.OperatorICAO, &a.Owner); err != nil {
+			rows.Close()
+			return diff, fmt.Errorf("failed to scan aircraft row: %w", err)
+		}
+		stored[a.ICAO24] = a
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return diff, fmt.Errorf("error iterating aircraft rows: %w", err)
+	}
+
+	upsert, remove, diff := domain.DiffRegistry(stored, aircraft)
+	if !apply {
+		return diff, nil
+	}
+
+	_, err = tx.Exec(ctx, `
+		CREATE TEMP TABLE aircraft_reload (LIKE aircraft INCLUDING DEFAULTS) ON COMMIT DROP
+	`)
+	if err != nil {
+		return diff, fmt.Errorf("failed to create reload table: %w", err)
+	}
+
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"aircraft_reload"},
+		[]string{"icao24", "registration", "manufacturer", "model", "typecode", "operator", "operator_icao", "owner"},
+		pgx.CopyFromSlice(len(upsert), func(i int) ([]any, error) {
+			a := upsert[i]
+			return []any{a.ICAO24, a.Registration, a.Manufacturer, a.Model, a.TypeCode, a.Operator, a.OperatorICAO, a.Owner}, nil
+		}),
+	)
+	if err != nil {
+		return diff, fmt.Errorf("failed to copy aircraft: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO aircraft (icao24, registration, manufacturer, model, typecode, operator, operator_icao, owner)
+		SELECT icao24, registration, manufacturer, model, typecode, operator, operator_icao, owner
+		FROM aircraft_reload
+		ON CONFLICT (icao24) DO UPDATE SET
+			registration = EXCLUDED.registration,
+			manufacturer = EXCLUDED.manufacturer,
+			model = EXCLUDED.model,
+			typecode = EXCLUDED.typecode,
+			operator = EXCLUDED.operator,
+			operator_icao = EXCLUDED.operator_icao,
+			owner = EXCLUDED.owner,
+			updated_at = NOW()
+	`)
+	if err != nil {
+		return diff, fmt.Errorf("failed to upsert aircraft: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM aircraft WHERE icao24 = ANY($1)`, remove)
+	if err != nil {
+		return diff, fmt.Errorf("failed to remove aircraft: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return diff, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return diff, nil
+}
@@ -0,0 +1,301 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/northeastloon/flight_tracker/internal/domain"
+)
+
+type trafficKey struct {
+	bucket   time.Time
+	icao24   string
+	cellLat  int
+	cellLon  int
+	airborne bool
+}
+
+type trafficValue struct {
+	originCountry string
+	category      int
+	observations  int
+}
+
+// rollupTraffic adds a snapshot to the traffic_hourly rollup. It runs inside
+// the StoreTelemetry transaction so the rollup never drifts from telemetry.
+func rollupTraffic(ctx context.Context, tx pgx.Tx, data []domain.Telemetry) error {
+	rollup := make(map[trafficKey]*trafficValue)
+
+	for _, d := range data {
+		if d.Latitude == nil || d.Longitude == nil {
+			continue
+		}
+
+		key := trafficKey{
+			bucket:   d.LastContact.UTC().Truncate(time.Hour),
+			icao24:   d.ICAO24,
+			cellLat:  int(math.Floor(*d.Latitude)),
+			cellLon:  int(math.Floor(*d.Longitude)),
+			airborne: !d.OnGround,
+		}
+
+		v, ok := rollup[key]
+		if !ok {
+			v = &trafficValue{originCountry: d.OriginCountry, category: d.Category}
+			rollup[key] = v
+		}
+		v.observations++
+	}
+
+	for k, v := range rollup {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO traffic_hourly (
+				bucket, icao24, cell_lat, cell_lon, airborne,
+				origin_country, category, observations
+			)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT (bucket, icao24, cell_lat, cell_lon, airborne) DO UPDATE SET
+				origin_country = EXCLUDED.origin_country,
+				category = EXCLUDED.category,
+				observations = traffic_hourly.observations + EXCLUDED.observations
+		`,
+			k.bucket, k.icao24, k.cellLat, k.cellLon, k.airborne,
+			v.originCountry, v.category, v.observations,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update traffic rollup: %w", err)
+		}
+	}
+
+	return nil
+}
+
+type coverageKey struct {
+	bucket time.Time
+	source string
+	icao24 string
+}
+
+type coverageValue struct {
+	observations int
+	selected     int
+	exclusive    int
+}
+
+// rollupCoverage adds a snapshot to the coverage_hourly rollup, crediting
+// every feed that saw each stored state. Like rollupTraffic it runs inside
+// the StoreTelemetry transaction.
+func rollupCoverage(ctx context.Context, tx pgx.Tx, data []domain.Telemetry) error {
+	rollup := make(map[coverageKey]*coverageValue)
+
+	for _, d := range data {
+		sources := sourcesOf(d)
+		for _, source := range sources {
+			key := coverageKey{
+				bucket: d.LastContact.UTC().Truncate(time.Hour),
+				source: source,
+				icao24: d.ICAO24,
+			}
+
+			v, ok := rollup[key]
+			if !ok {
+				v = &coverageValue{}
+				rollup[key] = v
+			}
+			v.observations++
+			if source == d.Source {
+				v.selected++
+			}
+			if len(sources) == 1 {
+				v.exclusive++
+			}
+		}
+	}
+
+	for k, v := range rollup {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO coverage_hourly (
+				bucket, source, icao24, observations, selected, exclusive
+			)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (bucket, source, icao24) DO UPDATE SET
+				observations = coverage_hourly.observations + EXCLUDED.observations,
+				selected = coverage_hourly.selected + EXCLUDED.selected,
+				exclusive = coverage_hourly.exclusive + EXCLUDED.exclusive
+		`,
+			k.bucket, k.source, k.icao24, v.observations, v.selected, v.exclusive,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update coverage rollup: %w", err)
+		}
+	}
+
+	return nil
+}
+
+var statsGroupColumns = map[string]string{
+	domain.GroupByNone:          `'all'`,
+	domain.GroupByOriginCountry: `COALESCE(t.origin_country, '')`,
+	domain.GroupByCategory:      `COALESCE(c.category, t.category::text, '')`,
+	domain.GroupByCell:          `t.cell_lat || ',' || t.cell_lon`,
+}
+
+func buildStatsQuery(filter *domain.StatsFilter) (string, []any, error) {
+	key, ok := statsGroupColumns[filter.GroupBy]
+	if !ok {
+		return "", nil, fmt.Errorf("unknown group by dimension %q", filter.GroupBy)
+	}
+
+	var query strings.Builder
+	params := []any{filter.From, filter.To}
+
+	bucket := "NULL::timestamp"
+	if filter.Bucket > 0 {
+		params = append(params, filter.Bucket)
+		bucket = fmt.Sprintf("date_bin($%d, t.bucket, TIMESTAMP '2000-01-01')", len(params))
+	}
+
+	query.WriteString(fmt.Sprintf(`
+        SELECT
+            %s AS bucket_start,
+            %s AS key,
+            COUNT(DISTINCT t.icao24) AS aircraft,
+            SUM(t.observations) AS observations
+        FROM traffic_hourly t
+        LEFT JOIN opensky_category c ON c.id = t.category
+        WHERE t.bucket >= date_trunc('hour', $1::timestamp) AND t.bucket < $2
+    `, bucket, key))
+
+	if filter.Airborne != nil {
+		params = append(params, *filter.Airborne)
+		query.WriteString(fmt.Sprintf(" AND t.airborne = $%d", len(params)))
+	}
+
+	query.WriteString(" GROUP BY 1, 2")
+
+	// Limit applies per bucket, so "top N per hour" works as well as "top N".
+	if filter.Limit > 0 {
+		params = append(params, filter.Limit)
+		inner := query.String()
+		query.Reset()
+		query.WriteString(fmt.Sprintf(`
+        SELECT bucket_start, key, aircraft, observations
+        FROM (
+            SELECT s.*, ROW_NUMBER() OVER (
+                PARTITION BY bucket_start ORDER BY aircraft DESC, key
+            ) AS rank
+            FROM (%s) s
+        ) ranked
+        WHERE rank <= $%d
+    `, inner, len(params)))
+	}
+
+	query.WriteString(" ORDER BY bucket_start, aircraft DESC, key")
+
+	return query.String(), params, nil
+}
+
+func (d *Database) GetTrafficStats(ctx context.Context, filter *domain.StatsFilter) ([]domain.StatsRow, error) {
+	query, params, err := buildStatsQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := d.Client.Query(ctx, query, params...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query traffic stats: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []domain.StatsRow
+	for rows.Next() {
+		var s domain.StatsRow
+		if err := rows.Scan(&s.BucketStart, &s.Key, &s.Aircraft, &s.Observations); err != nil {
+			return nil, fmt.Errorf("failed to scan traffic stats row: %w", err)
+		}
+		stats = append(stats, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating traffic stats rows: %w", err)
+	}
+
+	return stats, nil
+}
+
+func buildCoverageQuery(filter *domain.CoverageFilter) (string, []any) {
+	params := []any{filter.From, filter.To}
+
+	bucket := "NULL::timestamp"
+	if filter.Bucket > 0 {
+		params = append(params, filter.Bucket)
+		bucket = fmt.Sprintf("date_bin($%d, bucket, TIMESTAMP '2000-01-01')", len(params))
+	}
+
+	where := ""
+	if filter.Source != nil {
+		params = append(params, *filter.Source)
+		where = fmt.Sprintf("WHERE c.source = $%d", len(params))
+	}
+
+	// totals counts every feed, so it is computed before the source filter
+	query := fmt.Sprintf(`
+        WITH c AS (
+            SELECT %s AS bucket_start, source, icao24, observations, selected, exclusive
+            FROM coverage_hourly
+            WHERE bucket >= date_trunc('hour', $1::timestamp) AND bucket < $2
+        ),
+        totals AS (
+            SELECT bucket_start, COUNT(DISTINCT icao24) AS aircraft
+            FROM c
+            GROUP BY 1
+        )
+        SELECT
+            c.bucket_start,
+            c.source,
+            COUNT(DISTINCT c.icao24) AS aircraft,
+            t.aircraft AS total_aircraft,
+            SUM(c.observations) AS observations,
+            SUM(c.selected) AS selected,
+            SUM(c.exclusive) AS exclusive
+        FROM c
+        JOIN totals t ON t.bucket_start IS NOT DISTINCT FROM c.bucket_start
+        %s
+        GROUP BY c.bucket_start, c.source, t.aircraft
+        ORDER BY c.bucket_start, aircraft DESC, c.source
+    `, bucket, where)
+
+	return query, params
+}
+
+func (d *Database) GetSourceCoverage(ctx context.Context, filter *domain.CoverageFilter) ([]domain.CoverageRow, error) {
+	query, params := buildCoverageQuery(filter)
+
+	rows, err := d.Client.Query(ctx, query, params...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query source coverage: %w", err)
+	}
+	defer rows.Close()
+
+	var coverage []domain.CoverageRow
+	for rows.Next() {
+		var c domain.CoverageRow
+		if err := rows.Scan(
+			&c.BucketStart, &c.Source, &c.Aircraft, &c.TotalAircraft,
+			&c.Observations, &c.Selected, &c.Exclusive,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan source coverage row: %w", err)
+		}
+		coverage = append(coverage, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating source coverage rows: %w", err)
+	}
+
+	return coverage, nil
+}
@@ -0,0 +1,77 @@
+package postgres
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/northeastloon/flight_tracker/internal/domain"
+)
+
+func TestBuildStatsQuery(t *testing.T) {
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+	airborne := true
+
+	tests := []struct {
+		name   string
+		filter domain.StatsFilter
+		params []any
+		want   []string // fragments the query must contain
+		not    []string // fragments it must not
+	}{
+		{
+			name:   "whole range",
+			filter: domain.StatsFilter{From: from, To: to, GroupBy: domain.GroupByNone},
+			params: []any{from, to},
+			want:   []string{"NULL::timestamp AS bucket_start", "'all' AS key", "GROUP BY 1, 2"},
+			not:    []string{"date_bin", "t.airborne", "ROW_NUMBER"},
+		},
+		{
+			name:   "buckets",
+			filter: domain.StatsFilter{From: from, To: to, Bucket: 6 * time.Hour, GroupBy: domain.GroupByOriginCountry},
+			params: []any{from, to, 6 * time.Hour},
+			want:   []string{"date_bin($3, t.bucket, TIMESTAMP '2000-01-01')", "COALESCE(t.origin_country, '') AS key"},
+		},
+		{
+			name: "every option",
+			filter: domain.StatsFilter{
+				From: from, To: to, Bucket: time.Hour, GroupBy: domain.GroupByCell,
+				Airborne: &airborne, Limit: 5,
+			},
+			params: []any{from, to, time.Hour, true, 5},
+			want: []string{
+				"date_bin($3,", "t.cell_lat || ',' || t.cell_lon AS key", "AND t.airborne = $4",
+				"PARTITION BY bucket_start", "WHERE rank <= $5",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		query, params, err := buildStatsQuery(&tt.filter)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(params, tt.params) {
+			t.Errorf("%s: params = %v, want %v", tt.name, params, tt.params)
+		}
+		for _, w := range tt.want {
+			if !strings.Contains(query, w) {
+				t.Errorf("%s: query lacks %q:\n%s", tt.name, w, query)
+			}
+		}
+		for _, n := range tt.not {
+			if strings.Contains(query, n) {
+				t.Errorf("%s: query contains %q:\n%s", tt.name, n, query)
+			}
+		}
+		if !strings.HasSuffix(query, "ORDER BY bucket_start, aircraft DESC, key") {
+			t.Errorf("%s: query is not ordered by bucket then size:\n%s", tt.name, query)
+		}
+	}
+
+	if _, _, err := buildStatsQuery(&domain.StatsFilter{GroupBy: "airline"}); err == nil {
+		t.Error("unknown group by dimension built a query")
+	}
+}
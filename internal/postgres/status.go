@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/northeastloon/flight_tracker/internal/domain"
+)
+
+func (d *Database) Ping(ctx context.Context) error {
+	return d.Client.Ping(ctx)
+}
+
+// MigrationsApplied reports whether every migration this binary knows about
+// has been applied.
+func (d *Database) MigrationsApplied(ctx context.Context) (bool, error) {
+	var version *int
+	err := d.Client.QueryRow(ctx, `SELECT max(version) FROM schema_migrations`).Scan(&version)
+	if isUndefinedTable(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	return version != nil && *version >= latestVersion, nil
+}
+
+// NewestContact returns the last contact of the newest stored state, or nil
+// when nothing is stored. It reads the end of the last_contact index, so it
+// is cheap enough for readiness probes.
+func (d *Database) NewestContact(ctx context.Context) (*time.Time, error) {
+	var newest *time.Time
+	if err := d.Client.QueryRow(ctx, `SELECT max(last_contact) FROM telemetry`).Scan(&newest); err != nil {
+		return nil, fmt.Errorf("failed to query newest contact: %w", err)
+	}
+	return newest, nil
+}
+
+// GetStorageStatus reports what the store holds. Row and aircraft counts are
+// the planner's estimates, which autovacuum keeps current, rather than scans
+// of the whole table.
+func (d *Database) GetStorageStatus(ctx context.Context) (domain.StorageStatus, error) {
+	var status domain.StorageStatus
+
+	ready, err := d.MigrationsApplied(ctx)
+	if err != nil {
+		return status, err
+	}
+	status.MigrationsReady = ready
+	if !ready {
+		return status, nil
+	}
+
+	// reltuples is -1 until the table is first analyzed; a negative
+	// n_distinct is a fraction of the rows
+	err = d.Client.QueryRow(ctx, `
+		SELECT
+			greatest(c.reltuples, 0)::bigint,
+			COALESCE((
+				SELECT CASE WHEN s.n_distinct < 0 THEN -s.n_distinct * greatest(c.reltuples, 0) ELSE s.n_distinct END
+				FROM pg_stats s
+				WHERE s.schemaname = n.nspname AND s.tablename = c.relname AND s.attname = 'icao24'
+			), 0)::bigint
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.oid = 'telemetry'::regclass
+	`).Scan(&status.Rows, &status.Aircraft)
+	if err != nil {
+		return status, fmt.Errorf("failed to query storage status: %w", err)
+	}
+
+	status.NewestContact, err = d.NewestContact(ctx)
+	if err != nil {
+		return status, err
+	}
+
+	return status, nil
+}
+
+func isUndefinedTable(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "42P01"
+}
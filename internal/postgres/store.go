@@ -0,0 +1,122 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/northeastloon/flight_tracker/internal/domain"
+	"github.com/northeastloon/flight_tracker/internal/metrics"
+	"github.com/northeastloon/flight_tracker/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var _ domain.FlightDataStore = (*Database)(nil)
+
+// sourcesOf returns the feeds that saw the aircraft, which for unfused
+// telemetry is just its source.
+func sourcesOf(t domain.Telemetry) []string {
+	if len(t.Sources) == 0 {
+		return []string{t.Source}
+	}
+	return t.Sources
+}
+
+// StoreTelemetry inserts a normalized snapshot in one transaction. States
+// already stored for the same source, icao24 and last_contact are skipped,
+// since providers repeat an aircraft's state until a newer message arrives;
+// these are the rows the deduplicated metric counts.
+func (d *Database) StoreTelemetry(ctx context.Context, data []domain.Telemetry) (err error) {
+	ctx, span := tracing.Tracer("postgres").Start(ctx, "postgres.store_telemetry",
+		trace.WithAttributes(attribute.Int("telemetry.rows", len(data))))
+	start := time.Now()
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		metrics.StoreDuration.WithLabelValues(result).Observe(time.Since(start).Seconds())
+		span.End()
+	}()
+
+	tx, err := d.Client.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	inserted := make([]domain.Telemetry, 0, len(data))
+	aircraft := make(map[string]struct{}, len(data))
+	var newest time.Time
+
+	for _, d := range data {
+		if d.Source == "" {
+			return fmt.Errorf("telemetry for %s has no source", d.ICAO24)
+		}
+
+		tag, err := tx.Exec(ctx, `
+			INSERT INTO telemetry (
+				source, icao24, callsign, origin_country, time_position,
+				last_contact, longitude, latitude, baro_altitude, on_ground,
+				velocity, true_track, vertical_rate, sensors, geo_altitude,
+				squawk, spi, position_source, category, extras,
+				sources
+			)
+			VALUES (
+				$1, $2, $3, $4, $5,
+				$6, $7, $8, $9, $10,
+				$11, $12, $13, $14, $15,
+				$16, $17, $18, $19, $20,
+				$21
+			)
+			ON CONFLICT (source, icao24, last_contact) DO NOTHING
+		`,
+			d.Source, d.ICAO24, d.Callsign, d.OriginCountry, d.TimePosition,
+			d.LastContact, d.Longitude, d.Latitude, d.BaroAltitude, d.OnGround,
+			d.Velocity, d.TrueTrack, d.VerticalRate, d.Sensors, d.GeoAltitude,
+			d.Squawk, d.SPI, d.PositionSource, d.Category, d.Extras,
+			sourcesOf(d),
+		)
+
+		if err != nil {
+			return fmt.Errorf("failed to insert %s aircraft state: %w", d.Source, err)
+		}
+
+		aircraft[d.ICAO24] = struct{}{}
+		if d.LastContact.After(newest) {
+			newest = d.LastContact
+		}
+		if tag.RowsAffected() > 0 {
+			inserted = append(inserted, d)
+		}
+	}
+
+	if err := rollupTraffic(ctx, tx, inserted); err != nil {
+		return err
+	}
+	if err := rollupCoverage(ctx, tx, inserted); err != nil {
+		return err
+	}
+	if err := d.buildFlights(ctx, tx, inserted, true); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	span.SetAttributes(attribute.Int("telemetry.inserted", len(inserted)))
+	metrics.ObserveRows(metrics.RowsInserted, len(inserted))
+	metrics.ObserveRows(metrics.RowsDeduplicated, len(data)-len(inserted))
+	metrics.LiveAircraft.Set(float64(len(aircraft)))
+	if !newest.IsZero() {
+		metrics.SetNewestContact(newest)
+	}
+
+	return nil
+}
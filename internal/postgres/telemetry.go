@@ -18,26 +18,38 @@ func buildTelemetryQuery(filter *domain.TelemetryFilter) (string, []any) {
 	if latest {
 		query.WriteString(`
             SELECT DISTINCT ON (icao24)
-                icao24, callsign, origin_country, time_position, last_contact,
+                source, icao24, callsign, origin_country, time_position, last_contact,
                 longitude, latitude, baro_altitude, on_ground, velocity,
                 true_track, vertical_rate, sensors, geo_altitude, squawk,
-                spi, position_source, category
+                spi, position_source, position_source_label,
+                category, category_label, extras, sources,
+                registration, manufacturer, model, typecode,
+                operator, operator_icao, owner,
+                airline_icao, airline_iata, airline_name, airline_country, airline_telephony
             FROM aircraft_state
             WHERE 1 = 1
         `)
 	} else {
 		query.WriteString(`
             SELECT
-                icao24, callsign, origin_country, time_position, last_contact,
+                source, icao24, callsign, origin_country, time_position, last_contact,
                 longitude, latitude, baro_altitude, on_ground, velocity,
                 true_track, vertical_rate, sensors, geo_altitude, squawk,
-                spi, position_source, category
+                spi, position_source, position_source_label,
+                category, category_label, extras, sources,
+                registration, manufacturer, model, typecode,
+                operator, operator_icao, owner,
+                airline_icao, airline_iata, airline_name, airline_country, airline_telephony
             FROM aircraft_state
             WHERE 1 = 1
         `)
 	}
 
 	if filter != nil {
+		if filter.Source != nil {
+			params = append(params, *filter.Source)
+			query.WriteString(fmt.Sprintf(" AND source = $%d", len(params)))
+		}
 		if filter.ICAO24 != nil {
 			params = append(params, *filter.ICAO24)
 			query.WriteString(fmt.Sprintf(" AND icao24 = $%d", len(params)))
@@ -54,10 +66,14 @@ func buildTelemetryQuery(filter *domain.TelemetryFilter) (string, []any) {
 			params = append(params, *filter.TimePosition)
 			query.WriteString(fmt.Sprintf(" AND time_position >= $%d", len(params)))
 		}
-		if filter.LastContact != nil {
-			params = append(params, *filter.LastContact)
+		if filter.From != nil {
+			params = append(params, *filter.From)
 			query.WriteString(fmt.Sprintf(" AND last_contact >= $%d", len(params)))
 		}
+		if filter.To != nil {
+			params = append(params, *filter.To)
+			query.WriteString(fmt.Sprintf(" AND last_contact <= $%d", len(params)))
+		}
 		if filter.Squawk != nil {
 			params = append(params, *filter.Squawk)
 			query.WriteString(fmt.Sprintf(" AND squawk = $%d", len(params)))
@@ -66,6 +82,18 @@ func buildTelemetryQuery(filter *domain.TelemetryFilter) (string, []any) {
 			params = append(params, *filter.Category)
 			query.WriteString(fmt.Sprintf(" AND category = $%d", len(params)))
 		}
+		if filter.TypeCode != nil {
+			params = append(params, *filter.TypeCode)
+			query.WriteString(fmt.Sprintf(" AND typecode = $%d", len(params)))
+		}
+		if filter.Operator != nil {
+			params = append(params, *filter.Operator)
+			query.WriteString(fmt.Sprintf(" AND operator_icao = $%d", len(params)))
+		}
+		if filter.Airline != nil {
+			params = append(params, *filter.Airline)
+			query.WriteString(fmt.Sprintf(" AND airline_designator(callsign) = $%d", len(params)))
+		}
 		if filter.Position != nil {
 			params = append(params,
 				filter.Position.Longitude,
@@ -103,15 +131,26 @@ func (d *Database) GetTelemetry(ctx context.Context, filter *domain.TelemetryFil
 	var telemetry []domain.Telemetry
 	for rows.Next() {
 		var t domain.Telemetry
+		var r domain.AircraftInfo
+		var airline nullableAirline
 		if err := rows.Scan(
-			&t.ICAO24, &t.Callsign, &t.OriginCountry, &t.TimePosition,
+			&t.Source, &t.ICAO24, &t.Callsign, &t.OriginCountry, &t.TimePosition,
 			&t.LastContact, &t.Longitude, &t.Latitude, &t.BaroAltitude,
 			&t.OnGround, &t.Velocity, &t.TrueTrack, &t.VerticalRate,
 			&t.Sensors, &t.GeoAltitude, &t.Squawk, &t.SPI,
-			&t.PositionSource, &t.Category,
+			&t.PositionSource, &t.PositionSourceLabel,
+			&t.Category, &t.CategoryLabel, &t.Extras, &t.Sources,
+			&r.Registration, &r.Manufacturer, &r.Model, &r.TypeCode,
+			&r.Operator, &r.OperatorICAO, &r.Owner,
+			&airline.icao, &airline.iata, &airline.name, &airline.country, &airline.telephony,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan telemetry row: %w", err)
 		}
+		if r.Known() {
+			r.ICAO24 = t.ICAO24
+			t.Registry = &r
+		}
+		t.Airline = airline.airline()
 		telemetry = append(telemetry, t)
 	}
 
@@ -0,0 +1,74 @@
+package provider
+
+import (
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/northeastloon/flight_tracker/internal/domain"
+)
+
+var icao24Address = regexp.MustCompile(`^[0-9a-f]{6}$`)
+
+// ReadAircraftDatabase parses the OpenSky aircraft database
+// (aircraftDatabase.csv, or the newer aircraft-database-complete exports whose
+// header names and values are quoted with single quotes), optionally
+// gzipped. Type and operator designators are upper-cased. Rows without a
+// valid icao24 or without any registry data are counted and skipped; later
+// rows replace earlier ones of the same aircraft.
+func ReadAircraftDatabase(r io.Reader) ([]domain.AircraftInfo, int, error) {
+	var out []domain.AircraftInfo
+	index := make(map[string]int)
+
+	rejected, err := readNamedCSV(r, []string{"icao24"}, func(cell func(string) string) bool {
+		value := func(names ...string) *string {
+			for _, name := range names {
+				if v := historyString(strings.TrimSpace(strings.Trim(cell(name), "'"))); v != nil {
+					return v
+				}
+			}
+			return nil
+		}
+		upper := func(v *string) *string {
+			if v != nil {
+				*v = strings.ToUpper(*v)
+			}
+			return v
+		}
+
+		icao24 := strings.ToLower(strings.Trim(cell("icao24"), "'"))
+		if !icao24Address.MatchString(icao24) {
+			return false
+		}
+		a := domain.AircraftInfo{
+			ICAO24:       icao24,
+			Registration: value("registration"),
+			Manufacturer: value("manufacturername", "manufacturericao"),
+			Model:        value("model"),
+			TypeCode:     upper(value("typecode")),
+			Operator:     value("operator"),
+			OperatorICAO: upper(value("operatoricao")),
+			Owner:        value("owner"),
+		}
+		if !a.Known() {
+			return false
+		}
+
+		if i, dup := index[a.ICAO24]; dup {
+			out[i] = a
+			return true
+		}
+		index[a.ICAO24] = len(out)
+		out = append(out, a)
+		return true
+	})
+	if err != nil {
+		return nil, rejected, err
+	}
+	if len(out) == 0 {
+		return nil, rejected, errors.New("no aircraft found")
+	}
+
+	return out, rejected, nil
+}
@@ -0,0 +1,38 @@
+package provider_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/northeastloon/flight_tracker/internal/provider"
+)
+
+const aircraftDatabaseCSV = `'icao24','timestamp','registration','manufacturerName','model','typecode','operator','operatorIcao','owner'
+'4007f6','2024-01-01','G-XLEA','Airbus','A380 841','a388','British Airways','baw','British Airways Plc'
+'ABC123','','N12345','Cessna','172S','C172','','',''
+'zzzzzz','','N1','Cessna','','','','',''
+'a00001','','','','','','','',''
+'4007f6','2024-02-01','G-XLEA','Airbus','A380 841','A388','British Airways','BAW','British Airways Plc'
+`
+
+func TestReadAircraftDatabase(t *testing.T) {
+	aircraft, rejected, err := provider.ReadAircraftDatabase(strings.NewReader(aircraftDatabaseCSV))
+	if err != nil {
+		t.Fatalf("ReadAircraftDatabase: %v", err)
+	}
+	if len(aircraft) != 2 || rejected != 2 {
+		t.Fatalf("got %d aircraft and %d rejected rows, want 2 and 2", len(aircraft), rejected)
+	}
+
+	a380 := aircraft[0]
+	if a380.ICAO24 != "4007f6" || *a380.Registration != "G-XLEA" || *a380.TypeCode != "A388" || *a380.OperatorICAO != "BAW" {
+		t.Errorf("aircraft = %+v", a380)
+	}
+	if *a380.Manufacturer != "Airbus" || *a380.Owner != "British Airways Plc" {
+		t.Errorf("manufacturer = %q, owner = %q", *a380.Manufacturer, *a380.Owner)
+	}
+
+	if c172 := aircraft[1]; c172.ICAO24 != "abc123" || c172.Operator != nil || c172.OperatorICAO != nil {
+		t.Errorf("aircraft without operator = %+v", c172)
+	}
+}
@@ -1,16 +1,61 @@
 package provider
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/northeastloon/flight_tracker/internal/capture"
+	"github.com/northeastloon/flight_tracker/internal/domain"
+	"github.com/northeastloon/flight_tracker/internal/tracing"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// StatusError is returned by Fetch when the provider answers with a non-2xx
+// status code.
+type StatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected response status: %s", e.Status)
+}
+
 type Client struct {
 	baseURL    *url.URL
 	httpClient *http.Client
 	params     url.Values
+
+	// response headers reporting the request budget; empty when the
+	// provider does not report one
+	remainingHeader  string
+	retryAfterHeader string
+
+	mu         sync.Mutex
+	rateLimit  domain.RateLimit
+	rateLimits bool // a response has reported the budget
+
+	// recorder receives every raw response; source and format label the
+	// records so they can be parsed again on replay
+	recorder Recorder
+	source   string
+	format   string
+}
+
+// Recorder stores raw provider responses. *capture.Writer implements it.
+type Recorder interface {
+	Write(rec capture.Record) error
 }
 
 type Option func(c *Client)
@@ -51,7 +96,63 @@ func WithHTTPClient(httpClient *http.Client) Option {
 	}
 }
 
-func Fetch[T any](ctx context.Context, client *Client) (T, error) {
+// WithRecorder saves every raw response body to rec.
+func WithRecorder(rec Recorder) Option {
+	return func(c *Client) {
+		c.recorder = rec
+	}
+}
+
+// withCaptureLabels names the feed and response format in recorded
+// responses. Provider clients set it themselves.
+func withCaptureLabels(source, format string) Option {
+	return func(c *Client) {
+		c.source = source
+		c.format = format
+	}
+}
+
+// WithRateLimitHeaders names the response headers carrying the remaining
+// request credits and, on a rate-limited response, the seconds to wait.
+func WithRateLimitHeaders(remaining, retryAfterSeconds string) Option {
+	return func(c *Client) {
+		c.remainingHeader = remaining
+		c.retryAfterHeader = retryAfterSeconds
+	}
+}
+
+// RateLimit returns the budget reported by the most recent response. ResetAt
+// is left for provider clients to fill in.
+func (c *Client) RateLimit() (domain.RateLimit, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rateLimit, c.rateLimits
+}
+
+func (c *Client) observeRateLimit(resp *http.Response) {
+	if c.remainingHeader == "" && c.retryAfterHeader == "" {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.rateLimits {
+		c.rateLimit.Remaining = -1
+	}
+	if v, err := strconv.Atoi(resp.Header.Get(c.remainingHeader)); err == nil {
+		c.rateLimit.Remaining = v
+		c.rateLimits = true
+	}
+
+	c.rateLimit.RetryAfter = 0
+	if v, err := strconv.ParseFloat(resp.Header.Get(c.retryAfterHeader), 64); err == nil && v > 0 {
+		c.rateLimit.RetryAfter = time.Duration(v * float64(time.Second))
+		c.rateLimits = true
+	}
+}
+
+func Fetch[T any](ctx context.Context, client *Client) (_ T, err error) {
 	var zero T
 
 	// Create a copy of the base URL
@@ -60,21 +161,58 @@ func Fetch[T any](ctx context.Context, client *Client) (T, error) {
 	// Add query parameters
 	reqURL.RawQuery = client.params.Encode()
 
+	ctx, span := tracing.Tracer("provider").Start(ctx, "provider.fetch",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.URLFull(reqURL.String())))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL.String(), nil)
 	if err != nil {
 		return zero, err
 	}
 
+	started := time.Now()
 	resp, err := client.httpClient.Do(req)
 	if err != nil {
 		return zero, err
 	}
 	defer resp.Body.Close()
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	client.observeRateLimit(resp)
+
+	var body io.Reader = resp.Body
+	if client.recorder != nil {
+		raw, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return zero, err
+		}
+		client.record(started, resp.StatusCode, raw)
+		body = bytes.NewReader(raw)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return zero, &StatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
 
 	var result T
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	if err := json.NewDecoder(body).Decode(&result); err != nil {
 		return zero, err
 	}
 
 	return result, nil
 }
+
+// record hands a raw response to the recorder. A failure to record is logged
+// and does not fail the fetch.
+func (c *Client) record(started time.Time, status int, body []byte) {
+	rec := capture.NewRecord(started, c.source, c.format, status, body)
+	if err := c.recorder.Write(rec); err != nil {
+		slog.Warn("Failed to record provider response", "source", c.source, "error", err)
+	}
+}
@@ -0,0 +1,112 @@
+package provider
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/northeastloon/flight_tracker/internal/domain"
+)
+
+var (
+	icaoAirlineCode = regexp.MustCompile(`^[A-Z]{3}$`)
+	iataAirlineCode = regexp.MustCompile(`^[A-Z0-9]{2}$`)
+)
+
+// OpenFlights airlines.dat columns.
+const (
+	airlineName = 1 + iota
+	airlineAlias
+	airlineIATA
+	airlineICAO
+	airlineCallsign
+	airlineCountry
+	airlineActive
+	airlineColumns
+)
+
+// ReadOpenFlightsAirlines parses the OpenFlights airline database
+// (airlines.dat, https://openflights.org/data), optionally gzipped. Rows
+// without a valid ICAO designator are counted and skipped. When several
+// airlines share a designator, the first active one wins.
+func ReadOpenFlightsAirlines(r io.Reader) ([]domain.Airline, int, error) {
+	br, err := maybeGunzip(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	cr := csv.NewReader(br)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+
+	var out []domain.Airline
+	var active []bool
+	index := make(map[string]int)
+	rejected := 0
+
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			rejected++
+			continue
+		}
+		if err != nil {
+			return nil, rejected, fmt.Errorf("failed to read CSV: %w", err)
+		}
+		if len(record) < airlineColumns {
+			rejected++
+			continue
+		}
+
+		cell := func(i int) *string {
+			v := strings.TrimSpace(record[i])
+			if v == `\N` || v == "-" || v == "N/A" {
+				v = ""
+			}
+			return historyString(v)
+		}
+		a := domain.Airline{
+			ICAO:      strings.ToUpper(strings.TrimSpace(record[airlineICAO])),
+			IATA:      cell(airlineIATA),
+			Country:   cell(airlineCountry),
+			Telephony: cell(airlineCallsign),
+		}
+		name := cell(airlineName)
+		if !icaoAirlineCode.MatchString(a.ICAO) || name == nil {
+			rejected++
+			continue
+		}
+		a.Name = *name
+		if a.IATA != nil {
+			*a.IATA = strings.ToUpper(*a.IATA)
+			if !iataAirlineCode.MatchString(*a.IATA) {
+				a.IATA = nil
+			}
+		}
+		if a.Telephony != nil {
+			*a.Telephony = strings.ToUpper(*a.Telephony)
+		}
+		isActive := strings.EqualFold(strings.TrimSpace(record[airlineActive]), "Y")
+
+		if i, dup := index[a.ICAO]; dup {
+			if isActive && !active[i] {
+				out[i], active[i] = a, true
+			}
+			continue
+		}
+		index[a.ICAO] = len(out)
+		out = append(out, a)
+		active = append(active, isActive)
+	}
+
+	if len(out) == 0 {
+		return nil, rejected, errors.New("no airlines found")
+	}
+	return out, rejected, nil
+}
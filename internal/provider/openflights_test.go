@@ -0,0 +1,33 @@
+package provider_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/northeastloon/flight_tracker/internal/provider"
+)
+
+const openFlightsAirlines = `-1,"Unknown",\N,"-","N/A",\N,\N,"Y"
+1355,"British Airways",\N,"BA","BAW","SPEEDBIRD","United Kingdom","Y"
+2000,"Defunct Speedbird",\N,"","BAW","","United Kingdom","N"
+4296,"Ryanair",\N,"FR","RYR","RYANAIR","Ireland","Y"
+9999,"Private",\N,"","","","","Y"
+`
+
+func TestReadOpenFlightsAirlines(t *testing.T) {
+	airlines, rejected, err := provider.ReadOpenFlightsAirlines(strings.NewReader(openFlightsAirlines))
+	if err != nil {
+		t.Fatalf("ReadOpenFlightsAirlines: %v", err)
+	}
+	if len(airlines) != 2 || rejected != 2 {
+		t.Fatalf("got %d airlines and %d rejected rows, want 2 and 2", len(airlines), rejected)
+	}
+
+	ba := airlines[0]
+	if ba.ICAO != "BAW" || ba.Name != "British Airways" || *ba.IATA != "BA" || *ba.Telephony != "SPEEDBIRD" || *ba.Country != "United Kingdom" {
+		t.Errorf("airline = %+v", ba)
+	}
+	if airlines[1].ICAO != "RYR" {
+		t.Errorf("second airline = %+v", airlines[1])
+	}
+}
@@ -3,15 +3,26 @@ package provider
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"time"
 
 	"github.com/northeastloon/flight_tracker/internal/domain"
+	"github.com/northeastloon/flight_tracker/internal/metrics"
+	"github.com/northeastloon/flight_tracker/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
-	openSkyBaseURL = "https://opensky-network.org/api/states/all"
+	openSkyBaseURL      = "https://opensky-network.org/api/states/all"
+	openSkyProviderName = "opensky"
 )
 
+// ErrNoStates is returned when an OpenSky response contains no state vectors.
+var ErrNoStates = errors.New("no states returned")
+
 type OpenSkyTelemetry struct {
 	Icao24         string
 	Callsign       *string
@@ -45,6 +56,8 @@ type OpenSkyClient struct {
 func NewOpenSkyClient(opts ...Option) *OpenSkyClient {
 	baseOpts := []Option{
 		WithBaseURL(openSkyBaseURL),
+		WithRateLimitHeaders("X-Rate-Limit-Remaining", "X-Rate-Limit-Retry-After-Seconds"),
+		withCaptureLabels(openSkyProviderName, FormatOpenSky),
 	}
 
 	opts = append(baseOpts, opts...)
@@ -54,6 +67,18 @@ func NewOpenSkyClient(opts ...Option) *OpenSkyClient {
 	}
 }
 
+var _ domain.RateLimitReporter = (*OpenSkyClient)(nil)
+
+// RateLimit reports the API credits left. OpenSky replenishes credits daily
+// at midnight UTC.
+func (c *OpenSkyClient) RateLimit() (domain.RateLimit, bool) {
+	rl, ok := c.Client.RateLimit()
+	if ok && rl.Remaining >= 0 {
+		rl.ResetAt = time.Now().UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+	}
+	return rl, ok
+}
+
 // UnmarshalJSON implements the json.Unmarshaler interface
 func (r *OpenSkyResponse) UnmarshalJSON(data []byte) error {
 
@@ -75,68 +100,148 @@ func (r *OpenSkyResponse) UnmarshalJSON(data []byte) error {
 	}
 
 	if len(states) == 0 {
-		return fmt.Errorf("no states returned")
+		return ErrNoStates
 	}
 
 	r.States = states
 	return nil
 }
 
+// ParseOpenSkyTelemetry converts the raw state arrays of a response. States
+// that are malformed or lack an icao24 address are skipped, logged and
+// counted in the rejected metric; an error is returned only if every state
+// was rejected. Rejecting states one at a time is what gives the rejected
+// count meaning: failing the whole response over one bad state would lose
+// the good ones and report a fetch error instead.
 func ParseOpenSkyTelemetry(r OpenSkyResponse) ([]OpenSkyTelemetry, error) {
-	parsed := make([]OpenSkyTelemetry, 0)
+	parsed := make([]OpenSkyTelemetry, 0, len(r.States))
+	var lastErr error
 
 	for _, rawState := range r.States {
-		// Assert that rawState is a slice
-		state, ok := rawState.([]any)
-		if !ok {
-			return nil, fmt.Errorf("invalid state format: expected []any")
+		telemetry, err := parseOpenSkyState(rawState)
+		if err != nil {
+			lastErr = err
+			continue
 		}
 
-		if len(state) < 18 {
-			return nil, fmt.Errorf("invalid state length: got %d, expected 18", len(state))
-		}
+		parsed = append(parsed, telemetry)
+	}
 
-		// Create the telemetry struct
-		telemetry := OpenSkyTelemetry{
-			Icao24:         mustString(state[0]),
-			Callsign:       optionalString(state[1]),
-			OriginCountry:  mustString(state[2]),
-			TimePosition:   optionalInt64(state[3]),
-			LastContact:    mustInt64(state[4]),
-			Longitude:      optionalFloat(state[5]),
-			Latitude:       optionalFloat(state[6]),
-			BaroAltitude:   optionalFloat(state[7]),
-			OnGround:       mustBool(state[8]),
-			Velocity:       optionalFloat(state[9]),
-			TrueTrack:      optionalFloat(state[10]),
-			VerticalRate:   optionalFloat(state[11]),
-			Sensors:        optionalIntSlice(state[12]),
-			GeoAltitude:    optionalFloat(state[13]),
-			Squawk:         optionalString(state[14]),
-			SPI:            mustBool(state[15]),
-			PositionSource: mustInt(state[16]),
-			Category:       mustInt(state[17]),
-		}
+	rejected := len(r.States) - len(parsed)
+	metrics.ObserveRows(metrics.RowsParsed, len(parsed))
+	metrics.ObserveRows(metrics.RowsRejected, rejected)
+	if rejected > 0 {
+		slog.Warn("Rejected malformed OpenSky states", "count", rejected, "error", lastErr)
+	}
 
-		parsed = append(parsed, telemetry)
+	if len(parsed) == 0 && lastErr != nil {
+		return nil, lastErr
 	}
 
 	return parsed, nil
 }
 
+func parseOpenSkyState(rawState any) (OpenSkyTelemetry, error) {
+	// Assert that rawState is a slice
+	state, ok := rawState.([]any)
+	if !ok {
+		return OpenSkyTelemetry{}, fmt.Errorf("invalid state format: expected []any")
+	}
+
+	if len(state) < 18 {
+		return OpenSkyTelemetry{}, fmt.Errorf("invalid state length: got %d, expected 18", len(state))
+	}
+
+	// Create the telemetry struct
+	telemetry := OpenSkyTelemetry{
+		Icao24:         mustString(state[0]),
+		Callsign:       optionalString(state[1]),
+		OriginCountry:  mustString(state[2]),
+		TimePosition:   optionalInt64(state[3]),
+		LastContact:    mustInt64(state[4]),
+		Longitude:      optionalFloat(state[5]),
+		Latitude:       optionalFloat(state[6]),
+		BaroAltitude:   optionalFloat(state[7]),
+		OnGround:       mustBool(state[8]),
+		Velocity:       optionalFloat(state[9]),
+		TrueTrack:      optionalFloat(state[10]),
+		VerticalRate:   optionalFloat(state[11]),
+		Sensors:        optionalIntSlice(state[12]),
+		GeoAltitude:    optionalFloat(state[13]),
+		Squawk:         optionalString(state[14]),
+		SPI:            mustBool(state[15]),
+		PositionSource: mustInt(state[16]),
+		Category:       mustInt(state[17]),
+	}
+
+	if telemetry.Icao24 == "" || telemetry.LastContact == 0 {
+		return OpenSkyTelemetry{}, fmt.Errorf("invalid state: missing icao24 or last_contact")
+	}
+
+	return telemetry, nil
+}
+
+// Normalize converts an OpenSky state vector into the provider-agnostic form.
+func (t OpenSkyTelemetry) Normalize() domain.Telemetry {
+	n := domain.Telemetry{
+		Source:         openSkyProviderName,
+		ICAO24:         t.Icao24,
+		Callsign:       t.Callsign,
+		OriginCountry:  t.OriginCountry,
+		LastContact:    time.Unix(t.LastContact, 0).UTC(),
+		Longitude:      t.Longitude,
+		Latitude:       t.Latitude,
+		BaroAltitude:   t.BaroAltitude,
+		OnGround:       t.OnGround,
+		Velocity:       t.Velocity,
+		TrueTrack:      t.TrueTrack,
+		VerticalRate:   t.VerticalRate,
+		Sensors:        t.Sensors,
+		GeoAltitude:    t.GeoAltitude,
+		Squawk:         t.Squawk,
+		SPI:            t.SPI,
+		PositionSource: t.PositionSource,
+		Category:       t.Category,
+	}
+	if t.TimePosition != nil {
+		tp := time.Unix(*t.TimePosition, 0).UTC()
+		n.TimePosition = &tp
+	}
+	return n
+}
+
 // Compile-time check that OpenSkyClient implements FlightDataProvider
-var _ domain.FlightDataProvider[[]OpenSkyTelemetry] = (*OpenSkyClient)(nil)
+var _ domain.FlightDataProvider = (*OpenSkyClient)(nil)
+
+func (c *OpenSkyClient) FetchTelemetry(ctx context.Context) ([]domain.Telemetry, error) {
+	start := time.Now()
+	defer func() {
+		metrics.FetchDuration.WithLabelValues(openSkyProviderName).Observe(time.Since(start).Seconds())
+	}()
 
-func (c *OpenSkyClient) FetchTelemetry(ctx context.Context) ([]OpenSkyTelemetry, error) {
 	response, err := Fetch[OpenSkyResponse](ctx, c.Client)
+	if rl, ok := c.RateLimit(); ok && rl.Remaining >= 0 {
+		metrics.RateLimitRemaining.WithLabelValues(openSkyProviderName).Set(float64(rl.Remaining))
+	}
 	if err != nil {
+		metrics.FetchErrors.WithLabelValues(openSkyProviderName, errorType(err)).Inc()
 		return nil, err
 	}
 
+	_, span := tracing.Tracer("provider").Start(ctx, "provider.parse_opensky",
+		trace.WithAttributes(attribute.Int("opensky.states", len(response.States))))
 	parsed, err := ParseOpenSkyTelemetry(response)
+	span.SetAttributes(attribute.Int("opensky.parsed", len(parsed)))
+	span.End()
 	if err != nil {
+		metrics.FetchErrors.WithLabelValues(openSkyProviderName, "parse").Inc()
 		return nil, err
 	}
 
-	return parsed, nil
+	telemetry := make([]domain.Telemetry, 0, len(parsed))
+	for _, t := range parsed {
+		telemetry = append(telemetry, t.Normalize())
+	}
+
+	return telemetry, nil
 }
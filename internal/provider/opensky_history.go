@@ -0,0 +1,242 @@
+package provider
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/northeastloon/flight_tracker/internal/domain"
+)
+
+// historyColumns maps the column names used by OpenSky's historical dumps,
+// and the snake_case names of the live API fields, onto one name each.
+var historyColumns = map[string]string{
+	"icao24":          "icao24",
+	"callsign":        "callsign",
+	"origin_country":  "origin_country",
+	"lastposupdate":   "time_position",
+	"time_position":   "time_position",
+	"lastcontact":     "last_contact",
+	"last_contact":    "last_contact",
+	"lon":             "longitude",
+	"longitude":       "longitude",
+	"lat":             "latitude",
+	"latitude":        "latitude",
+	"baroaltitude":    "baro_altitude",
+	"baro_altitude":   "baro_altitude",
+	"onground":        "on_ground",
+	"on_ground":       "on_ground",
+	"velocity":        "velocity",
+	"heading":         "true_track",
+	"true_track":      "true_track",
+	"vertrate":        "vertical_rate",
+	"vertical_rate":   "vertical_rate",
+	"geoaltitude":     "geo_altitude",
+	"geo_altitude":    "geo_altitude",
+	"squawk":          "squawk",
+	"spi":             "spi",
+	"position_source": "position_source",
+	"category":        "category",
+	"alert":           "alert",
+}
+
+// ReadOpenSkyHistory streams the state vectors of an OpenSky historical dump
+// to fn. r may be a states_YYYY-MM-DD-HH.csv.tar archive, or the CSV inside
+// it, each optionally gzipped. Rows that cannot be parsed are counted and
+// skipped; fn's error stops the read.
+func ReadOpenSkyHistory(r io.Reader, fn func(domain.Telemetry) error) (rejected int, err error) {
+	br, err := maybeGunzip(r)
+	if err != nil {
+		return 0, err
+	}
+
+	// a tar archive has "ustar" at offset 257
+	head, _ := br.Peek(262)
+	if len(head) < 262 || !bytes.Equal(head[257:262], []byte("ustar")) {
+		return readOpenSkyCSV(br, fn)
+	}
+
+	tr := tar.NewReader(br)
+	found := false
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return rejected, fmt.Errorf("failed to read archive: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg || !isHistoryCSV(hdr.Name) {
+			continue
+		}
+
+		found = true
+		entry, err := maybeGunzip(tr)
+		if err != nil {
+			return rejected, fmt.Errorf("failed to read %s: %w", hdr.Name, err)
+		}
+		n, err := readOpenSkyCSV(entry, fn)
+		rejected += n
+		if err != nil {
+			return rejected, fmt.Errorf("%s: %w", hdr.Name, err)
+		}
+	}
+
+	if !found {
+		return rejected, fmt.Errorf("archive contains no state vector CSV")
+	}
+	return rejected, nil
+}
+
+func isHistoryCSV(name string) bool {
+	return strings.HasSuffix(name, ".csv") || strings.HasSuffix(name, ".csv.gz")
+}
+
+// maybeGunzip decompresses r if it starts with the gzip magic number.
+func maybeGunzip(r io.Reader) (*bufio.Reader, error) {
+	br := bufio.NewReaderSize(r, 1<<16)
+	magic, _ := br.Peek(2)
+	if len(magic) < 2 || magic[0] != 0x1f || magic[1] != 0x8b {
+		return br, nil
+	}
+
+	gz, err := gzip.NewReader(br)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open gzip stream: %w", err)
+	}
+	return bufio.NewReaderSize(gz, 1<<16), nil
+}
+
+func readOpenSkyCSV(r io.Reader, fn func(domain.Telemetry) error) (rejected int, err error) {
+	cr := csv.NewReader(r)
+	cr.ReuseRecord = true
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		if field, ok := historyColumns[strings.ToLower(strings.TrimSpace(name))]; ok {
+			columns[field] = i
+		}
+	}
+	for _, required := range []string{"icao24", "last_contact"} {
+		if _, ok := columns[required]; !ok {
+			return 0, fmt.Errorf("CSV has no %s column", required)
+		}
+	}
+
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return rejected, nil
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			rejected++
+			continue
+		}
+		if err != nil {
+			return rejected, fmt.Errorf("failed to read CSV: %w", err)
+		}
+
+		t, ok := parseHistoryRow(record, columns)
+		if !ok {
+			rejected++
+			continue
+		}
+		if err := fn(t); err != nil {
+			return rejected, err
+		}
+	}
+}
+
+// parseHistoryRow converts one CSV row. Empty cells are missing values.
+func parseHistoryRow(record []string, columns map[string]int) (domain.Telemetry, bool) {
+	cell := func(field string) string {
+		i, ok := columns[field]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	icao24 := strings.ToLower(cell("icao24"))
+	lastContact := historyTime(cell("last_contact"))
+	if len(icao24) != 6 || lastContact == nil {
+		return domain.Telemetry{}, false
+	}
+
+	t := domain.Telemetry{
+		Source:         openSkyProviderName,
+		ICAO24:         icao24,
+		Callsign:       domain.NormalizeCallsign(historyString(cell("callsign"))),
+		OriginCountry:  cell("origin_country"),
+		TimePosition:   historyTime(cell("time_position")),
+		LastContact:    *lastContact,
+		Longitude:      historyFloat(cell("longitude")),
+		Latitude:       historyFloat(cell("latitude")),
+		BaroAltitude:   historyFloat(cell("baro_altitude")),
+		OnGround:       historyBool(cell("on_ground")),
+		Velocity:       historyFloat(cell("velocity")),
+		TrueTrack:      historyFloat(cell("true_track")),
+		VerticalRate:   historyFloat(cell("vertical_rate")),
+		GeoAltitude:    historyFloat(cell("geo_altitude")),
+		Squawk:         historyString(cell("squawk")),
+		SPI:            historyBool(cell("spi")),
+		PositionSource: historyInt(cell("position_source")),
+		Category:       historyInt(cell("category")),
+	}
+	if historyBool(cell("alert")) {
+		t.Extras = map[string]any{"alert": true}
+	}
+
+	return t, true
+}
+
+func historyString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func historyFloat(s string) *float64 {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil
+	}
+	return &f
+}
+
+func historyInt(s string) int {
+	i, _ := strconv.Atoi(s)
+	return i
+}
+
+// historyBool accepts the True/False spelling of the dumps as well as Go's.
+func historyBool(s string) bool {
+	b, _ := strconv.ParseBool(strings.ToLower(s))
+	return b
+}
+
+// historyTime parses fractional unix seconds.
+func historyTime(s string) *time.Time {
+	f := historyFloat(s)
+	if f == nil || *f <= 0 {
+		return nil
+	}
+	t := unixFloat(*f).Truncate(time.Microsecond)
+	return &t
+}
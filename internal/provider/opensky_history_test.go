@@ -0,0 +1,125 @@
+package provider_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"testing"
+	"time"
+
+	"github.com/northeastloon/flight_tracker/internal/domain"
+	"github.com/northeastloon/flight_tracker/internal/provider"
+)
+
+// openSkyHistoryCSV uses the column names of the historical dumps, in their
+// order; the third row has no last contact and the fourth a bad address.
+const openSkyHistoryCSV = `time,icao24,lat,lon,velocity,heading,vertrate,callsign,onground,alert,spi,squawk,baroaltitude,geoaltitude,lastposupdate,lastcontact
+1711965600,4ca7b4,53.4213,-6.2701,231.5,271.3,-5.2,RYR12A  ,False,True,False,1000,10972.8,11201.4,1711965599.5,1711965599.9
+1711965600,3c6444,50.0333,8.5706,0.0,,,,True,False,False,,,,,1711965598
+1711965600,4ca7b5,53.0,-6.0,,,,,False,False,False,,,,,
+1711965600,xyz,53.0,-6.0,,,,,False,False,False,,,,,1711965598
+`
+
+// openSkyHistoryRows reads the dump r and fails unless it yields the rows
+// of openSkyHistoryCSV.
+func openSkyHistoryRows(t *testing.T, r *bytes.Reader) []domain.Telemetry {
+	t.Helper()
+	var rows []domain.Telemetry
+	rejected, err := provider.ReadOpenSkyHistory(r, func(tel domain.Telemetry) error {
+		rows = append(rows, tel)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ReadOpenSkyHistory: %v", err)
+	}
+	if len(rows) != 2 || rejected != 2 {
+		t.Fatalf("got %d rows and %d rejected, want 2 and 2", len(rows), rejected)
+	}
+	return rows
+}
+
+func gzipped(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// historyArchive packs files into a tar archive, as OpenSky publishes them.
+func historyArchive(t *testing.T, files map[string][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, name := range []string{"LICENSE.txt", "states_2024-04-01-10.csv.gz"} {
+		data, ok := files[name]
+		if !ok {
+			continue
+		}
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(data)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadOpenSkyHistoryFormats(t *testing.T) {
+	csv := []byte(openSkyHistoryCSV)
+	archive := historyArchive(t, map[string][]byte{
+		"LICENSE.txt":                 []byte("not a CSV\n"),
+		"states_2024-04-01-10.csv.gz": gzipped(t, csv),
+	})
+
+	tests := map[string][]byte{
+		"csv":             csv,
+		"gzipped csv":     gzipped(t, csv),
+		"archive":         archive,
+		"gzipped archive": gzipped(t, archive),
+	}
+	for name, data := range tests {
+		rows := openSkyHistoryRows(t, bytes.NewReader(data))
+		if rows[0].ICAO24 != "4ca7b4" || rows[1].ICAO24 != "3c6444" {
+			t.Errorf("%s: read %s and %s", name, rows[0].ICAO24, rows[1].ICAO24)
+		}
+	}
+
+	empty := historyArchive(t, map[string][]byte{"LICENSE.txt": []byte("not a CSV\n")})
+	_, err := provider.ReadOpenSkyHistory(bytes.NewReader(empty), func(domain.Telemetry) error { return nil })
+	if err == nil {
+		t.Error("archive without a CSV was read without error")
+	}
+}
+
+func TestReadOpenSkyHistoryColumns(t *testing.T) {
+	rows := openSkyHistoryRows(t, bytes.NewReader([]byte(openSkyHistoryCSV)))
+
+	ryr := rows[0]
+	if ryr.Source != "opensky" || *ryr.Callsign != "RYR12A" || ryr.OnGround || !ryr.LastContact.Equal(time.Unix(1711965599, 900_000_000)) {
+		t.Errorf("row = %+v", ryr)
+	}
+	if *ryr.Latitude != 53.4213 || *ryr.Longitude != -6.2701 || *ryr.BaroAltitude != 10972.8 || *ryr.GeoAltitude != 11201.4 {
+		t.Errorf("position = %v, %v at %v (geo %v)", *ryr.Latitude, *ryr.Longitude, *ryr.BaroAltitude, *ryr.GeoAltitude)
+	}
+	if *ryr.Velocity != 231.5 || *ryr.TrueTrack != 271.3 || *ryr.VerticalRate != -5.2 || *ryr.Squawk != "1000" {
+		t.Errorf("velocity = %v, track = %v, vertical rate = %v, squawk = %v", *ryr.Velocity, *ryr.TrueTrack, *ryr.VerticalRate, *ryr.Squawk)
+	}
+	if ryr.TimePosition == nil || !ryr.TimePosition.Equal(time.Unix(1711965599, 500_000_000)) || ryr.Extras["alert"] != true {
+		t.Errorf("time position = %v, extras = %v", ryr.TimePosition, ryr.Extras)
+	}
+
+	ground := rows[1]
+	if !ground.OnGround || ground.Callsign != nil || ground.TrueTrack != nil || ground.TimePosition != nil || ground.Extras != nil {
+		t.Errorf("row with missing values = %+v", ground)
+	}
+}
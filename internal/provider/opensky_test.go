@@ -0,0 +1,310 @@
+package provider_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/northeastloon/flight_tracker/internal/domain"
+	"github.com/northeastloon/flight_tracker/internal/provider"
+	"github.com/northeastloon/flight_tracker/internal/provider/openskytest"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+var snapshotTime = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func testTelemetry() []domain.Telemetry {
+	return []domain.Telemetry{
+		{
+			ICAO24:        "4ca7b4",
+			Callsign:      ptr("RYR1234 "),
+			OriginCountry: "Ireland",
+			TimePosition:  ptr(snapshotTime.Add(-2 * time.Second)),
+			LastContact:   snapshotTime.Add(-time.Second),
+			Longitude:     ptr(-6.27),
+			Latitude:      ptr(53.42),
+			BaroAltitude:  ptr(1200.5),
+			Velocity:      ptr(110.2),
+			TrueTrack:     ptr(280.0),
+			VerticalRate:  ptr(6.5),
+			GeoAltitude:   ptr(1250.0),
+			Squawk:        ptr("4621"),
+			Category:      4,
+		},
+		{
+			ICAO24:        "a0b1c2",
+			OriginCountry: "United States",
+			LastContact:   snapshotTime,
+			OnGround:      true,
+			Longitude:     ptr(-73.78),
+			Latitude:      ptr(40.64),
+			Sensors:       ptr([]int{1, 2}),
+		},
+		{
+			// no position yet
+			ICAO24:        "3c6444",
+			OriginCountry: "Germany",
+			LastContact:   snapshotTime,
+		},
+	}
+}
+
+func newServer(opts ...openskytest.Option) *openskytest.Server {
+	opts = append([]openskytest.Option{
+		openskytest.WithSnapshots(openskytest.NewSnapshot(snapshotTime, testTelemetry()...)),
+		openskytest.WithRateLimit(400, 30*time.Second),
+	}, opts...)
+	return openskytest.NewServer(opts...)
+}
+
+func newClient(srv *openskytest.Server, opts ...provider.Option) *provider.OpenSkyClient {
+	return provider.NewOpenSkyClient(append([]provider.Option{provider.WithBaseURL(srv.StatesURL())}, opts...)...)
+}
+
+func TestOpenSkyClientFetchTelemetry(t *testing.T) {
+	srv := newServer()
+	defer srv.Close()
+
+	got, err := newClient(srv).FetchTelemetry(context.Background())
+	if err != nil {
+		t.Fatalf("FetchTelemetry: %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("got %d states, want 3", len(got))
+	}
+
+	first := got[0]
+	if first.Source != "opensky" || first.ICAO24 != "4ca7b4" || *first.Callsign != "RYR1234 " {
+		t.Errorf("identity = %q %q %q", first.Source, first.ICAO24, *first.Callsign)
+	}
+	if !first.LastContact.Equal(snapshotTime.Add(-time.Second)) || !first.TimePosition.Equal(snapshotTime.Add(-2*time.Second)) {
+		t.Errorf("times = %v, %v", first.LastContact, *first.TimePosition)
+	}
+	if *first.Latitude != 53.42 || *first.BaroAltitude != 1200.5 || *first.Squawk != "4621" || first.Category != 4 {
+		t.Errorf("state = %+v", first)
+	}
+	if s := got[1].Sensors; !got[1].OnGround || s == nil || len(*s) != 2 {
+		t.Errorf("second state = %+v", got[1])
+	}
+	if got[2].Latitude != nil || got[2].TimePosition != nil {
+		t.Errorf("third state has a position: %+v", got[2])
+	}
+}
+
+func TestOpenSkyClientQueryParameters(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  [][2]string
+		want    []string
+		credits int
+	}{
+		{
+			name:    "small bounding box",
+			params:  boundingBox(50, -10, 55, -5),
+			want:    []string{"4ca7b4"},
+			credits: 1,
+		},
+		{
+			name:    "large bounding box",
+			params:  boundingBox(20, -80, 60, 0),
+			want:    []string{"4ca7b4", "a0b1c2"},
+			credits: 4,
+		},
+		{
+			name:    "icao24",
+			params:  [][2]string{{"icao24", "a0b1c2"}, {"icao24", "3C6444"}},
+			want:    []string{"a0b1c2", "3c6444"},
+			credits: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newServer()
+			defer srv.Close()
+
+			var opts []provider.Option
+			for _, p := range tt.params {
+				opts = append(opts, provider.WithQueryParam(p[0], p[1]))
+			}
+			client := newClient(srv, opts...)
+
+			got, err := client.FetchTelemetry(context.Background())
+			if err != nil {
+				t.Fatalf("FetchTelemetry: %v", err)
+			}
+			var icao24s []string
+			for _, g := range got {
+				icao24s = append(icao24s, g.ICAO24)
+			}
+			if !slices.Equal(icao24s, tt.want) {
+				t.Errorf("got %v, want %v", icao24s, tt.want)
+			}
+
+			rl, ok := client.RateLimit()
+			if !ok || 400-rl.Remaining != tt.credits {
+				t.Errorf("charged %d credits, want %d", 400-rl.Remaining, tt.credits)
+			}
+			if rl.ResetAt.IsZero() {
+				t.Error("reset time not set")
+			}
+		})
+	}
+}
+
+func boundingBox(lamin, lomin, lamax, lomax float64) [][2]string {
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	return [][2]string{{"lamin", f(lamin)}, {"lomin", f(lomin)}, {"lamax", f(lamax)}, {"lomax", f(lomax)}}
+}
+
+func TestOpenSkyClientFaults(t *testing.T) {
+	tests := []struct {
+		name    string
+		fault   openskytest.Fault
+		timeout time.Duration
+		check   func(t *testing.T, err error, rl domain.RateLimit)
+	}{
+		{
+			name:  "rate limited",
+			fault: openskytest.FaultTooManyRequests,
+			check: func(t *testing.T, err error, rl domain.RateLimit) {
+				var se *provider.StatusError
+				if !errors.As(err, &se) || se.StatusCode != 429 {
+					t.Errorf("err = %v, want a 429 StatusError", err)
+				}
+				if rl.RetryAfter != 30*time.Second {
+					t.Errorf("RetryAfter = %v, want 30s", rl.RetryAfter)
+				}
+			},
+		},
+		{
+			name:  "unavailable",
+			fault: openskytest.FaultUnavailable,
+			check: func(t *testing.T, err error, rl domain.RateLimit) {
+				var se *provider.StatusError
+				if !errors.As(err, &se) || se.StatusCode != 503 {
+					t.Errorf("err = %v, want a 503 StatusError", err)
+				}
+			},
+		},
+		{
+			name:  "truncated",
+			fault: openskytest.FaultTruncated,
+			check: func(t *testing.T, err error, rl domain.RateLimit) {
+				if err == nil {
+					t.Error("truncated body parsed without error")
+				}
+			},
+		},
+		{
+			name:  "empty",
+			fault: openskytest.FaultEmpty,
+			check: func(t *testing.T, err error, rl domain.RateLimit) {
+				if !errors.Is(err, provider.ErrNoStates) {
+					t.Errorf("err = %v, want ErrNoStates", err)
+				}
+			},
+		},
+		{
+			name:    "slow body",
+			fault:   openskytest.FaultSlowBody(time.Second),
+			timeout: 100 * time.Millisecond,
+			check: func(t *testing.T, err error, rl domain.RateLimit) {
+				if !errors.Is(err, context.DeadlineExceeded) {
+					t.Errorf("err = %v, want a deadline error", err)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newServer()
+			defer srv.Close()
+			srv.Inject(tt.fault)
+			client := newClient(srv)
+
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+			_, err := client.FetchTelemetry(ctx)
+			rl, _ := client.RateLimit()
+			tt.check(t, err, rl)
+
+			// the fault only affects one request
+			if _, err := client.FetchTelemetry(context.Background()); err != nil {
+				t.Errorf("fetch after the fault: %v", err)
+			}
+		})
+	}
+}
+
+func TestOpenSkyClientCreditsRunOut(t *testing.T) {
+	srv := newServer(openskytest.WithRateLimit(5, 2*time.Second))
+	defer srv.Close()
+	client := newClient(srv)
+
+	if _, err := client.FetchTelemetry(context.Background()); err != nil {
+		t.Fatalf("first fetch: %v", err)
+	}
+	if rl, _ := client.RateLimit(); rl.Remaining != 1 {
+		t.Fatalf("Remaining = %d, want 1", rl.Remaining)
+	}
+
+	_, err := client.FetchTelemetry(context.Background())
+	var se *provider.StatusError
+	if !errors.As(err, &se) || se.StatusCode != 429 {
+		t.Fatalf("err = %v, want a 429 StatusError", err)
+	}
+	if rl, _ := client.RateLimit(); rl.RetryAfter != 2*time.Second || rl.Remaining != 1 {
+		t.Errorf("rate limit = %+v", rl)
+	}
+}
+
+func TestParseOpenSkyTelemetry(t *testing.T) {
+	valid := openskytest.State(testTelemetry()[0])
+	response := provider.OpenSkyResponse{
+		Time: snapshotTime.Unix(),
+		States: []any{
+			toJSON(t, valid),
+			"not a state",
+			toJSON(t, valid[:10]),
+			toJSON(t, append([]any{""}, valid[1:]...)),
+		},
+	}
+
+	got, err := provider.ParseOpenSkyTelemetry(response)
+	if err != nil {
+		t.Fatalf("ParseOpenSkyTelemetry: %v", err)
+	}
+	if len(got) != 1 || got[0].Icao24 != "4ca7b4" || *got[0].Velocity != 110.2 {
+		t.Errorf("got %+v, want only the valid state", got)
+	}
+
+	if _, err := provider.ParseOpenSkyTelemetry(provider.OpenSkyResponse{States: response.States[1:]}); err == nil {
+		t.Error("no error when every state is malformed")
+	}
+}
+
+// toJSON round-trips v through JSON, giving the types a decoded response
+// holds.
+func toJSON(t *testing.T, v []any) any {
+	t.Helper()
+	raw, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded any
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
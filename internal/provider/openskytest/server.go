@@ -0,0 +1,438 @@
+// Package openskytest provides a fake of the OpenSky states API for tests
+// and local development. It serves recorded or synthetic snapshots, filters
+// them like the real endpoint and can misbehave on demand: rate limiting,
+// server errors, slow or truncated bodies and empty snapshots.
+package openskytest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/northeastloon/flight_tracker/internal/capture"
+	"github.com/northeastloon/flight_tracker/internal/domain"
+	"github.com/northeastloon/flight_tracker/internal/provider"
+)
+
+// StatesPath is the path the fake serves, as on opensky-network.org.
+const StatesPath = "/api/states/all"
+
+// Rate-limit headers sent by OpenSky.
+const (
+	RemainingHeader  = "X-Rate-Limit-Remaining"
+	RetryAfterHeader = "X-Rate-Limit-Retry-After-Seconds"
+)
+
+// Snapshot is a states response. States holds the 18-element state arrays.
+type Snapshot struct {
+	Time   int64   `json:"time"`
+	States [][]any `json:"states"`
+}
+
+// NewSnapshot builds a snapshot taken at the given time.
+func NewSnapshot(at time.Time, telemetry ...domain.Telemetry) Snapshot {
+	s := Snapshot{Time: at.Unix()}
+	for _, t := range telemetry {
+		s.States = append(s.States, State(t))
+	}
+	return s
+}
+
+// State encodes t as an OpenSky state array.
+func State(t domain.Telemetry) []any {
+	var timePosition, sensors any
+	if t.TimePosition != nil {
+		timePosition = t.TimePosition.Unix()
+	}
+	if t.Sensors != nil {
+		sensors = *t.Sensors
+	}
+	return []any{
+		t.ICAO24, t.Callsign, t.OriginCountry, timePosition, t.LastContact.Unix(),
+		t.Longitude, t.Latitude, t.BaroAltitude, t.OnGround, t.Velocity,
+		t.TrueTrack, t.VerticalRate, sensors, t.GeoAltitude, t.Squawk,
+		t.SPI, t.PositionSource, t.Category,
+	}
+}
+
+// LoadCapture reads the successful OpenSky responses of a capture file or
+// directory written with capture.Writer.
+func LoadCapture(paths ...string) ([]Snapshot, error) {
+	r, err := capture.Open(paths...)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var snapshots []Snapshot
+	for {
+		rec, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if rec.Format != provider.FormatOpenSky || rec.Status < 200 || rec.Status > 299 {
+			continue
+		}
+
+		var s Snapshot
+		if err := json.Unmarshal(rec.Bytes(), &s); err != nil {
+			return nil, fmt.Errorf("record at %s: %w", rec.Time.Format(time.RFC3339), err)
+		}
+		snapshots = append(snapshots, s)
+	}
+
+	if len(snapshots) == 0 {
+		return nil, fmt.Errorf("capture holds no OpenSky responses")
+	}
+	return snapshots, nil
+}
+
+type faultKind int
+
+const (
+	faultTooManyRequests faultKind = iota + 1
+	faultUnavailable
+	faultSlowBody
+	faultTruncated
+	faultEmpty
+)
+
+// Fault makes one response misbehave. Faults are queued with Server.Inject;
+// the zero Fault leaves its response alone.
+type Fault struct {
+	kind  faultKind
+	delay time.Duration
+}
+
+var (
+	// FaultTooManyRequests answers 429 with a Retry-After header, as when
+	// the credits have run out.
+	FaultTooManyRequests = Fault{kind: faultTooManyRequests}
+	// FaultUnavailable answers 503.
+	FaultUnavailable = Fault{kind: faultUnavailable}
+	// FaultTruncated cuts the JSON body off halfway.
+	FaultTruncated = Fault{kind: faultTruncated}
+	// FaultEmpty answers with null states, as OpenSky does when no aircraft
+	// match.
+	FaultEmpty = Fault{kind: faultEmpty}
+)
+
+// FaultSlowBody sends half of the body, then waits d before sending the rest.
+func FaultSlowBody(d time.Duration) Fault {
+	return Fault{kind: faultSlowBody, delay: d}
+}
+
+// Request is a request the fake has received.
+type Request struct {
+	Time   time.Time
+	Query  url.Values
+	Status int
+}
+
+// Server is a fake of the OpenSky states endpoint on an httptest server.
+// Point a client at it with provider.WithBaseURL(s.StatesURL()).
+type Server struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	snapshots  []Snapshot
+	next       int
+	provider   domain.FlightDataProvider
+	credits    int // negative when rate limiting is off
+	retryAfter time.Duration
+	faults     []Fault
+	requests   []Request
+}
+
+type Option func(s *Server)
+
+// WithSnapshots serves the snapshots in turn, repeating the last one.
+func WithSnapshots(snapshots ...Snapshot) Option {
+	return func(s *Server) {
+		s.snapshots = snapshots
+	}
+}
+
+// WithProvider serves a fresh snapshot from p on every request, for example
+// from a provider.SimulatorProvider.
+func WithProvider(p domain.FlightDataProvider) Option {
+	return func(s *Server) {
+		s.provider = p
+	}
+}
+
+// WithRateLimit charges requests credits as OpenSky does, reporting the rest
+// in X-Rate-Limit-Remaining, and answers 429 asking clients to retry after
+// retryAfter once they run out.
+func WithRateLimit(credits int, retryAfter time.Duration) Option {
+	return func(s *Server) {
+		s.credits = credits
+		s.retryAfter = retryAfter
+	}
+}
+
+// NewServer starts a fake serving an empty snapshot unless options say
+// otherwise. Close it when done.
+func NewServer(opts ...Option) *Server {
+	s := NewUnstartedServer(opts...)
+	s.Start()
+	return s
+}
+
+// NewUnstartedServer returns a fake that is not yet listening, so its
+// listener can be replaced before calling Start.
+func NewUnstartedServer(opts ...Option) *Server {
+	s := &Server{credits: -1, retryAfter: time.Minute}
+	for _, o := range opts {
+		o(s)
+	}
+	s.Server = httptest.NewUnstartedServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// StatesURL returns the URL of the states endpoint.
+func (s *Server) StatesURL() string {
+	return s.URL + StatesPath
+}
+
+// Inject queues faults for the next requests, one per request.
+func (s *Server) Inject(faults ...Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, faults...)
+}
+
+// SetCredits replaces the remaining credits.
+func (s *Server) SetCredits(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.credits = n
+}
+
+// Requests returns the requests received so far.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != StatesPath {
+		http.NotFound(w, r)
+		return
+	}
+
+	query := r.URL.Query()
+	f, err := parseFilter(query)
+	if err != nil {
+		s.log(query, http.StatusBadRequest)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	var fault Fault
+	if len(s.faults) > 0 {
+		fault = s.faults[0]
+		s.faults = s.faults[1:]
+	}
+	limited := fault.kind == faultTooManyRequests
+	if s.credits >= 0 && !limited {
+		if cost := f.cost(); s.credits < cost {
+			limited = true
+		} else {
+			s.credits -= cost
+			w.Header().Set(RemainingHeader, strconv.Itoa(s.credits))
+		}
+	}
+	s.mu.Unlock()
+
+	switch {
+	case limited:
+		s.log(query, http.StatusTooManyRequests)
+		w.Header().Set(RetryAfterHeader, strconv.FormatFloat(s.retryAfter.Seconds(), 'f', -1, 64))
+		http.Error(w, "Too many requests", http.StatusTooManyRequests)
+		return
+	case fault.kind == faultUnavailable:
+		s.log(query, http.StatusServiceUnavailable)
+		http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	snapshot, err := s.snapshot(r)
+	if err != nil {
+		s.log(query, http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if fault.kind == faultEmpty {
+		snapshot.States = nil
+	}
+	body, err := json.Marshal(f.apply(snapshot))
+	if err != nil {
+		s.log(query, http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.log(query, http.StatusOK)
+	w.Header().Set("Content-Type", "application/json")
+	switch fault.kind {
+	case faultTruncated:
+		w.Write(body[:len(body)/2])
+	case faultSlowBody:
+		w.Write(body[:len(body)/2])
+		if fl, ok := w.(http.Flusher); ok {
+			fl.Flush()
+		}
+		select {
+		case <-time.After(fault.delay):
+			w.Write(body[len(body)/2:])
+		case <-r.Context().Done():
+		}
+	default:
+		w.Write(body)
+	}
+}
+
+func (s *Server) log(query url.Values, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, Request{Time: time.Now(), Query: query, Status: status})
+}
+
+// snapshot returns the next snapshot to serve.
+func (s *Server) snapshot(r *http.Request) (Snapshot, error) {
+	if s.provider != nil {
+		data, err := s.provider.FetchTelemetry(r.Context())
+		if err != nil {
+			return Snapshot{}, err
+		}
+		return NewSnapshot(time.Now(), data...), nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.snapshots) == 0 {
+		return Snapshot{Time: time.Now().Unix()}, nil
+	}
+	snapshot := s.snapshots[s.next]
+	if s.next < len(s.snapshots)-1 {
+		s.next++
+	}
+	return snapshot, nil
+}
+
+// filter holds the query parameters the states endpoint supports.
+type filter struct {
+	box    *[4]float64 // lamin, lomin, lamax, lomax
+	icao24 map[string]bool
+}
+
+func parseFilter(q url.Values) (filter, error) {
+	var f filter
+
+	names := []string{"lamin", "lomin", "lamax", "lomax"}
+	var box [4]float64
+	given := 0
+	for i, name := range names {
+		raw := q.Get(name)
+		if raw == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return f, fmt.Errorf("invalid %s: %q", name, raw)
+		}
+		box[i] = v
+		given++
+	}
+	switch given {
+	case 0:
+	case 4:
+		f.box = &box
+	default:
+		return f, fmt.Errorf("a bounding box needs lamin, lomin, lamax and lomax")
+	}
+
+	for _, v := range q["icao24"] {
+		if f.icao24 == nil {
+			f.icao24 = make(map[string]bool)
+		}
+		f.icao24[strings.ToLower(v)] = true
+	}
+
+	return f, nil
+}
+
+// cost is the credits OpenSky charges for a request, by the area of its
+// bounding box in square degrees.
+func (f filter) cost() int {
+	if f.box == nil {
+		return 4
+	}
+	area := math.Abs((f.box[2] - f.box[0]) * (f.box[3] - f.box[1]))
+	switch {
+	case area <= 25:
+		return 1
+	case area <= 100:
+		return 2
+	case area <= 400:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// apply keeps the states inside the bounding box and of the requested
+// aircraft. States without a position are outside any box.
+func (f filter) apply(s Snapshot) Snapshot {
+	if f.box == nil && f.icao24 == nil {
+		return s
+	}
+
+	out := Snapshot{Time: s.Time}
+	for _, state := range s.States {
+		if f.icao24 != nil {
+			icao24, _ := state[0].(string)
+			if !f.icao24[strings.ToLower(icao24)] {
+				continue
+			}
+		}
+		if f.box != nil {
+			lon, okLon := number(state[5])
+			lat, okLat := number(state[6])
+			if !okLon || !okLat || lat < f.box[0] || lon < f.box[1] || lat > f.box[2] || lon > f.box[3] {
+				continue
+			}
+		}
+		out.States = append(out.States, state)
+	}
+	return out
+}
+
+// number reads a coordinate from a state built by State or decoded from
+// JSON.
+func number(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case *float64:
+		if n != nil {
+			return *n, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,175 @@
+package provider
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/northeastloon/flight_tracker/internal/domain"
+)
+
+var icaoAirportCode = regexp.MustCompile(`^[A-Z]{4}$`)
+
+// ReadOurAirports parses the airports.csv of the OurAirports database and,
+// when runways is not nil, attaches the runways of runways.csv to their
+// airports. Either file may be gzipped. Columns are found by name, so the
+// order of the columns does not matter. Rows that cannot be parsed are
+// counted and skipped.
+func ReadOurAirports(airports, runways io.Reader) ([]domain.Airport, int, error) {
+	var out []domain.Airport
+	index := make(map[string]int)
+
+	rejected, err := readNamedCSV(airports, []string{"ident", "type", "latitude_deg", "longitude_deg"}, func(cell func(string) string) bool {
+		a, ok := parseOurAirport(cell)
+		if !ok {
+			return false
+		}
+		if i, dup := index[a.Ident]; dup {
+			out[i] = a
+			return true
+		}
+		index[a.Ident] = len(out)
+		out = append(out, a)
+		return true
+	})
+	if err != nil {
+		return nil, rejected, fmt.Errorf("airports: %w", err)
+	}
+	if len(out) == 0 {
+		return nil, rejected, errors.New("airports: no airports found")
+	}
+
+	if runways == nil {
+		return out, rejected, nil
+	}
+	n, err := readNamedCSV(runways, []string{"airport_ident"}, func(cell func(string) string) bool {
+		i, ok := index[strings.ToUpper(cell("airport_ident"))]
+		if !ok {
+			return false
+		}
+		out[i].Runways = append(out[i].Runways, parseOurRunway(cell))
+		return true
+	})
+	rejected += n
+	if err != nil {
+		return nil, rejected, fmt.Errorf("runways: %w", err)
+	}
+
+	return out, rejected, nil
+}
+
+func parseOurAirport(cell func(string) string) (domain.Airport, bool) {
+	lat, lon := historyFloat(cell("latitude_deg")), historyFloat(cell("longitude_deg"))
+	a := domain.Airport{
+		Ident:            strings.ToUpper(cell("ident")),
+		Type:             cell("type"),
+		Name:             cell("name"),
+		IATACode:         historyString(strings.ToUpper(cell("iata_code"))),
+		Elevation:        scaled(historyFloat(cell("elevation_ft")), domain.FeetToMetres),
+		Country:          cell("iso_country"),
+		Region:           cell("iso_region"),
+		Municipality:     historyString(cell("municipality")),
+		ScheduledService: cell("scheduled_service") == "yes",
+	}
+	if a.Ident == "" || lat == nil || lon == nil || *lat < -90 || *lat > 90 || *lon < -180 || *lon > 180 {
+		return domain.Airport{}, false
+	}
+	if !slices.Contains(domain.AirportTypes, a.Type) {
+		return domain.Airport{}, false
+	}
+	a.Latitude, a.Longitude = *lat, *lon
+
+	// older exports have no icao_code column, but use the ICAO code as the
+	// ident where there is one
+	icao := strings.ToUpper(cell("icao_code"))
+	if icao == "" && icaoAirportCode.MatchString(a.Ident) {
+		icao = a.Ident
+	}
+	a.ICAOCode = historyString(icao)
+
+	return a, true
+}
+
+func parseOurRunway(cell func(string) string) domain.Runway {
+	end := func(prefix string) domain.RunwayEnd {
+		return domain.RunwayEnd{
+			Ident:     historyString(cell(prefix + "ident")),
+			Latitude:  historyFloat(cell(prefix + "latitude_deg")),
+			Longitude: historyFloat(cell(prefix + "longitude_deg")),
+			Elevation: scaled(historyFloat(cell(prefix+"elevation_ft")), domain.FeetToMetres),
+			Heading:   historyFloat(cell(prefix + "heading_degT")),
+		}
+	}
+	return domain.Runway{
+		Length:  scaled(historyFloat(cell("length_ft")), domain.FeetToMetres),
+		Width:   scaled(historyFloat(cell("width_ft")), domain.FeetToMetres),
+		Surface: historyString(cell("surface")),
+		Lighted: historyBool(cell("lighted")),
+		Closed:  historyBool(cell("closed")),
+		LowEnd:  end("le_"),
+		HighEnd: end("he_"),
+	}
+}
+
+// readNamedCSV calls fn for every row of a CSV with a header, passing a
+// function that returns a row's trimmed cell by column name. Column names
+// match regardless of case and of quotes around them. Rows fn rejects and
+// rows that are not valid CSV are counted.
+func readNamedCSV(r io.Reader, required []string, fn func(cell func(string) string) bool) (rejected int, err error) {
+	br, err := maybeGunzip(r)
+	if err != nil {
+		return 0, err
+	}
+	cr := csv.NewReader(br)
+	cr.ReuseRecord = true
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[columnName(name)] = i
+	}
+	for _, name := range required {
+		if _, ok := columns[columnName(name)]; !ok {
+			return 0, fmt.Errorf("CSV has no %s column", name)
+		}
+	}
+
+	var record []string
+	cell := func(name string) string {
+		i, ok := columns[columnName(name)]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	for {
+		record, err = cr.Read()
+		if errors.Is(err, io.EOF) {
+			return rejected, nil
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			rejected++
+			continue
+		}
+		if err != nil {
+			return rejected, fmt.Errorf("failed to read CSV: %w", err)
+		}
+		if !fn(cell) {
+			rejected++
+		}
+	}
+}
+
+func columnName(name string) string {
+	return strings.ToLower(strings.Trim(strings.TrimSpace(name), `'"`))
+}
@@ -0,0 +1,49 @@
+package provider_test
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/northeastloon/flight_tracker/internal/provider"
+)
+
+const ourAirportsCSV = `"id","ident","type","name","latitude_deg","longitude_deg","elevation_ft","continent","iso_country","iso_region","municipality","scheduled_service","gps_code","iata_code","local_code"
+2434,"EGLL","large_airport","London Heathrow Airport",51.4706,-0.461941,83,"EU","GB","GB-ENG","London","yes","EGLL","LHR",
+4,"00AK","small_airport","Lowell Field",59.947733,-151.692524,450,"NA","US","US-AK","Anchor Point","no","00AK",,"00AK"
+5,"XXXX","spaceport","Not an airport",10,10,,"EU","GB","GB-ENG",,"no",,,
+6,"BAD1","small_airport","No position",,,,"EU","GB","GB-ENG",,"no",,,
+`
+
+const ourRunwaysCSV = `"id","airport_ref","airport_ident","length_ft","width_ft","surface","lighted","closed","le_ident","le_latitude_deg","le_longitude_deg","le_elevation_ft","le_heading_degT","le_displaced_threshold_ft","he_ident","he_latitude_deg","he_longitude_deg","he_elevation_ft","he_heading_degT","he_displaced_threshold_ft"
+1,2434,"EGLL",12799,164,"ASP",1,0,"09L",51.4775,-0.484885,79,89.6,1013,"27R",51.4777,-0.433425,78,269.6,
+2,9999,"ZZZZ",1000,50,"GRS",0,0,"18",,,,,,"36",,,,,
+`
+
+func TestReadOurAirports(t *testing.T) {
+	airports, rejected, err := provider.ReadOurAirports(strings.NewReader(ourAirportsCSV), strings.NewReader(ourRunwaysCSV))
+	if err != nil {
+		t.Fatalf("ReadOurAirports: %v", err)
+	}
+	if len(airports) != 2 || rejected != 3 {
+		t.Fatalf("got %d airports and %d rejected rows, want 2 and 3", len(airports), rejected)
+	}
+
+	lhr := airports[0]
+	if lhr.Ident != "EGLL" || *lhr.ICAOCode != "EGLL" || *lhr.IATACode != "LHR" || !lhr.ScheduledService {
+		t.Errorf("airport = %+v", lhr)
+	}
+	if math.Abs(*lhr.Elevation-25.3) > 0.1 {
+		t.Errorf("elevation = %v m, want about 25.3", *lhr.Elevation)
+	}
+	if len(lhr.Runways) != 1 || *lhr.Runways[0].LowEnd.Ident != "09L" || !lhr.Runways[0].Lighted {
+		t.Fatalf("runways = %+v", lhr.Runways)
+	}
+	if math.Abs(*lhr.Runways[0].Length-3901) > 1 {
+		t.Errorf("runway length = %v m, want about 3901", *lhr.Runways[0].Length)
+	}
+
+	if small := airports[1]; small.ICAOCode != nil || small.IATACode != nil || small.Runways != nil {
+		t.Errorf("airport without codes = %+v", small)
+	}
+}
@@ -0,0 +1,221 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/northeastloon/flight_tracker/internal/domain"
+	"github.com/northeastloon/flight_tracker/internal/metrics"
+)
+
+// readsbMaxSeen drops aircraft a receiver has not heard from for this long;
+// readsb keeps them in aircraft.json for a while after they fade.
+const readsbMaxSeen = 60 * time.Second
+
+// ReadsbResponse is the aircraft.json document served by readsb, tar1090 and
+// dump1090-fa.
+type ReadsbResponse struct {
+	Now      float64          `json:"now"` // unix seconds
+	Aircraft []ReadsbAircraft `json:"aircraft"`
+}
+
+// ReadsbAircraft is one entry of aircraft.json. Altitudes are in feet, speeds
+// in knots and rates in feet per minute.
+type ReadsbAircraft struct {
+	Hex      string   `json:"hex"` // prefixed with ~ for non-ICAO addresses
+	Type     string   `json:"type"`
+	Flight   *string  `json:"flight"`
+	AltBaro  any      `json:"alt_baro"` // feet, or "ground"
+	AltGeom  *float64 `json:"alt_geom"`
+	GS       *float64 `json:"gs"`
+	Track    *float64 `json:"track"`
+	BaroRate *float64 `json:"baro_rate"`
+	GeomRate *float64 `json:"geom_rate"`
+	Squawk   *string  `json:"squawk"`
+	Category *string  `json:"category"`
+	Lat      *float64 `json:"lat"`
+	Lon      *float64 `json:"lon"`
+	SeenPos  *float64 `json:"seen_pos"` // seconds since the last position
+	Seen     float64  `json:"seen"`     // seconds since the last message
+	SPI      *int     `json:"spi"`
+	RSSI     *float64 `json:"rssi"`
+	Messages *int     `json:"messages"`
+}
+
+// ReadsbClient polls the aircraft.json endpoint of a local receiver.
+type ReadsbClient struct {
+	*Client
+	name string
+}
+
+// NewReadsbClient returns a client for the receiver whose aircraft.json is
+// at url. name becomes the Source of its telemetry, so receivers can be told
+// apart.
+func NewReadsbClient(name, url string, opts ...Option) *ReadsbClient {
+	opts = append([]Option{WithBaseURL(url), withCaptureLabels(name, FormatReadsb)}, opts...)
+	return &ReadsbClient{
+		Client: NewClient(opts...),
+		name:   name,
+	}
+}
+
+var _ domain.FlightDataProvider = (*ReadsbClient)(nil)
+
+func (c *ReadsbClient) FetchTelemetry(ctx context.Context) ([]domain.Telemetry, error) {
+	start := time.Now()
+	defer func() {
+		metrics.FetchDuration.WithLabelValues(c.name).Observe(time.Since(start).Seconds())
+	}()
+
+	response, err := Fetch[ReadsbResponse](ctx, c.Client)
+	if err != nil {
+		metrics.FetchErrors.WithLabelValues(c.name, errorType(err)).Inc()
+		return nil, err
+	}
+
+	telemetry := ParseReadsbTelemetry(c.name, response)
+	if len(telemetry) == 0 && len(response.Aircraft) > 0 {
+		metrics.FetchErrors.WithLabelValues(c.name, "parse").Inc()
+		return nil, fmt.Errorf("none of %d aircraft could be parsed", len(response.Aircraft))
+	}
+
+	return telemetry, nil
+}
+
+// ParseReadsbTelemetry normalizes the aircraft of a response. Non-ICAO
+// addresses and aircraft not heard from for a minute are skipped and counted
+// in the rejected metric.
+func ParseReadsbTelemetry(source string, r ReadsbResponse) []domain.Telemetry {
+	now := time.Now().UTC()
+	if r.Now > 0 {
+		now = unixFloat(r.Now)
+	}
+	telemetry := make([]domain.Telemetry, 0, len(r.Aircraft))
+
+	for _, a := range r.Aircraft {
+		if len(a.Hex) != 6 || a.Seen > readsbMaxSeen.Seconds() {
+			continue
+		}
+		telemetry = append(telemetry, a.Normalize(source, now))
+	}
+
+	metrics.ObserveRows(metrics.RowsParsed, len(telemetry))
+	metrics.ObserveRows(metrics.RowsRejected, len(r.Aircraft)-len(telemetry))
+
+	return telemetry
+}
+
+// Normalize converts a readsb aircraft into the provider-agnostic form in SI
+// units. now is the response time the seen offsets are relative to.
+func (a ReadsbAircraft) Normalize(source string, now time.Time) domain.Telemetry {
+	t := domain.Telemetry{
+		Source:         source,
+		ICAO24:         strings.ToLower(a.Hex),
+		Callsign:       domain.NormalizeCallsign(a.Flight),
+		LastContact:    now.Add(-seconds(a.Seen)).Truncate(time.Second),
+		Longitude:      a.Lon,
+		Latitude:       a.Lat,
+		GeoAltitude:    scaled(a.AltGeom, domain.FeetToMetres),
+		Velocity:       scaled(a.GS, domain.KnotsToMps),
+		TrueTrack:      a.Track,
+		Squawk:         a.Squawk,
+		SPI:            a.SPI != nil && *a.SPI != 0,
+		PositionSource: readsbPositionSource(a.Type),
+		Category:       readsbCategory(a.Category),
+	}
+
+	switch alt := a.AltBaro.(type) {
+	case string:
+		t.OnGround = alt == "ground"
+	case float64:
+		t.BaroAltitude = scaled(&alt, domain.FeetToMetres)
+	}
+
+	if a.BaroRate != nil {
+		t.VerticalRate = scaled(a.BaroRate, domain.FeetPerMinuteToMps)
+	} else {
+		t.VerticalRate = scaled(a.GeomRate, domain.FeetPerMinuteToMps)
+	}
+
+	if a.SeenPos != nil && a.Lat != nil && a.Lon != nil {
+		tp := now.Add(-seconds(*a.SeenPos)).Truncate(time.Second)
+		t.TimePosition = &tp
+	}
+
+	extras := map[string]any{}
+	if a.Type != "" {
+		extras["type"] = a.Type
+	}
+	if a.RSSI != nil {
+		extras["rssi"] = *a.RSSI
+	}
+	if a.Messages != nil {
+		extras["messages"] = *a.Messages
+	}
+	if len(extras) > 0 {
+		t.Extras = extras
+	}
+
+	return t
+}
+
+// readsbPositionSource maps the readsb message type onto the position_source
+// codes: 0 ADS-B, 2 MLAT and 4 TIS-B, which OpenSky itself does not report.
+func readsbPositionSource(typ string) int {
+	switch {
+	case typ == "mlat":
+		return 2
+	case strings.HasPrefix(typ, "tisb"):
+		return 4
+	default:
+		return 0
+	}
+}
+
+// readsbCategory maps an ADS-B emitter category such as "A3" onto OpenSky's
+// category codes: A1-A7 are 2-8, B1-B7 are 9-15 and C1-C5 are 16-20. Unknown
+// categories are 0 and A0, B0 and C0 are 1 (no category information).
+func readsbCategory(category *string) int {
+	if category == nil || len(*category) != 2 {
+		return 0
+	}
+	set, n := (*category)[0], int((*category)[1]-'0')
+	if n < 0 || n > 7 {
+		return 0
+	}
+	if n == 0 {
+		return 1
+	}
+
+	switch set {
+	case 'A':
+		return 1 + n
+	case 'B':
+		return 8 + n
+	case 'C':
+		if n <= 5 {
+			return 15 + n
+		}
+	}
+	return 0
+}
+
+func unixFloat(secs float64) time.Time {
+	whole, frac := math.Modf(secs)
+	return time.Unix(int64(whole), int64(frac*1e9)).UTC()
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+func scaled(v *float64, factor float64) *float64 {
+	if v == nil {
+		return nil
+	}
+	s := *v * factor
+	return &s
+}
@@ -0,0 +1,74 @@
+package provider_test
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/northeastloon/flight_tracker/internal/provider"
+)
+
+const readsbAircraftJSON = `{
+	"now": 1772366400.5,
+	"aircraft": [
+		{"hex": "4CA7B4", "type": "adsb_icao", "flight": "ryr12a  ", "alt_baro": 10000, "alt_geom": 10250,
+		 "gs": 300, "track": 271.3, "baro_rate": -1000, "geom_rate": 500, "squawk": "1000", "category": "A3",
+		 "lat": 53.42, "lon": -6.27, "seen_pos": 1.2, "seen": 0.5, "spi": 1, "rssi": -20.5, "messages": 42},
+		{"hex": "3c6444", "type": "tisb_icao", "alt_baro": "ground", "geom_rate": 64, "category": "C1", "seen": 3},
+		{"hex": "3c6445", "type": "mlat", "alt_baro": 2000, "category": "B0", "lat": 53.0, "lon": -6.0, "seen": 10},
+		{"hex": "~2a0001", "type": "tisb_other", "seen": 1},
+		{"hex": "3c6446", "type": "adsb_icao", "seen": 61}
+	]
+}`
+
+func TestParseReadsbTelemetry(t *testing.T) {
+	var response provider.ReadsbResponse
+	if err := json.Unmarshal([]byte(readsbAircraftJSON), &response); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1772366400, 500_000_000).UTC()
+
+	got := provider.ParseReadsbTelemetry("garage", response)
+	if len(got) != 3 {
+		t.Fatalf("got %d aircraft, want 3 without the non-ICAO and faded ones", len(got))
+	}
+
+	near := func(v *float64, want float64) bool { return v != nil && math.Abs(*v-want) < 1e-6 }
+
+	adsb := got[0]
+	if adsb.Source != "garage" || adsb.ICAO24 != "4ca7b4" || *adsb.Callsign != "RYR12A" || adsb.OnGround {
+		t.Errorf("identity = %+v", adsb)
+	}
+	if !near(adsb.BaroAltitude, 3048) || !near(adsb.GeoAltitude, 3124.2) {
+		t.Errorf("altitudes = %v, %v; want 3048 and 3124.2 metres", *adsb.BaroAltitude, *adsb.GeoAltitude)
+	}
+	if !near(adsb.Velocity, 154.333333) || !near(adsb.VerticalRate, -5.08) || *adsb.TrueTrack != 271.3 {
+		t.Errorf("velocity = %v, vertical rate = %v, track = %v", *adsb.Velocity, *adsb.VerticalRate, *adsb.TrueTrack)
+	}
+	if !adsb.LastContact.Equal(now.Truncate(time.Second)) || !adsb.TimePosition.Equal(now.Add(-1200*time.Millisecond).Truncate(time.Second)) {
+		t.Errorf("last contact = %v, time position = %v", adsb.LastContact, adsb.TimePosition)
+	}
+	if adsb.PositionSource != 0 || adsb.Category != 4 || !adsb.SPI || *adsb.Squawk != "1000" {
+		t.Errorf("position source %d, category %d, spi %v", adsb.PositionSource, adsb.Category, adsb.SPI)
+	}
+	if adsb.Extras["type"] != "adsb_icao" || adsb.Extras["rssi"] != -20.5 || adsb.Extras["messages"] != 42 {
+		t.Errorf("extras = %v", adsb.Extras)
+	}
+
+	tisb := got[1]
+	if !tisb.OnGround || tisb.BaroAltitude != nil || tisb.PositionSource != 4 || tisb.Category != 16 {
+		t.Errorf("TIS-B aircraft = %+v, want on the ground with position source 4 and category 16", tisb)
+	}
+	if !near(tisb.VerticalRate, 0.32512) || tisb.TimePosition != nil || tisb.Callsign != nil {
+		t.Errorf("TIS-B vertical rate = %v, time position = %v, callsign = %v", tisb.VerticalRate, tisb.TimePosition, tisb.Callsign)
+	}
+	if !tisb.LastContact.Equal(now.Add(-3 * time.Second).Truncate(time.Second)) {
+		t.Errorf("TIS-B last contact = %v", tisb.LastContact)
+	}
+
+	mlat := got[2]
+	if mlat.PositionSource != 2 || mlat.Category != 1 || !near(mlat.BaroAltitude, 609.6) {
+		t.Errorf("MLAT aircraft = %+v, want position source 2, category 1 and 609.6 metres", mlat)
+	}
+}
@@ -0,0 +1,288 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/northeastloon/flight_tracker/internal/capture"
+	"github.com/northeastloon/flight_tracker/internal/domain"
+)
+
+// Response formats named in capture records.
+const (
+	FormatOpenSky = "opensky"
+	FormatReadsb  = "readsb"
+)
+
+// replayBatchWindow groups records fetched within this long of each other
+// into one snapshot when replaying as fast as possible, so feeds fetched in
+// the same cycle are fused together as they were live.
+const replayBatchWindow = time.Second
+
+// ErrReplayFinished is returned by ReplayProvider once every record has been
+// replayed.
+var ErrReplayFinished = errors.New("replay finished")
+
+// decoders turn a recorded response body back into telemetry.
+var decoders = map[string]func(source string, body []byte) ([]domain.Telemetry, error){
+	FormatOpenSky: func(_ string, body []byte) ([]domain.Telemetry, error) {
+		var r OpenSkyResponse
+		if err := json.Unmarshal(body, &r); err != nil {
+			return nil, err
+		}
+		parsed, err := ParseOpenSkyTelemetry(r)
+		if err != nil {
+			return nil, err
+		}
+		telemetry := make([]domain.Telemetry, 0, len(parsed))
+		for _, t := range parsed {
+			telemetry = append(telemetry, t.Normalize())
+		}
+		return telemetry, nil
+	},
+	FormatReadsb: func(source string, body []byte) ([]domain.Telemetry, error) {
+		var r ReadsbResponse
+		if err := json.Unmarshal(body, &r); err != nil {
+			return nil, err
+		}
+		return ParseReadsbTelemetry(source, r), nil
+	},
+}
+
+// ReplayProvider feeds recorded responses back through the parsers they came
+// from. It is also the ingestion loop's clock during a replay: time starts at
+// the first record and runs at the replay speed.
+//
+// At a positive speed each fetch returns the newest record of every feed that
+// is due by the replay clock, waiting for the next one if none is, much as
+// polling the live feeds would. At speed 0 every record is returned in turn
+// as fast as the loop fetches, and the clock only moves when the loop waits.
+type ReplayProvider struct {
+	reader *capture.Reader
+	speed  float64
+	shift  bool
+
+	mu       sync.Mutex
+	next     *capture.Record // first record not yet returned; nil at the end
+	origin   time.Time       // time of the first record
+	started  time.Time       // when the replay started, in real time
+	elapsed  time.Duration   // replay time waited so far at speed 0
+	offset   time.Duration   // added to replayed timestamps
+	finished bool
+	done     chan struct{}
+}
+
+type ReplayOption func(p *ReplayProvider)
+
+// WithReplaySpeed sets how many times faster than real time to replay. 0
+// replays as fast as possible.
+func WithReplaySpeed(speed float64) ReplayOption {
+	return func(p *ReplayProvider) {
+		p.speed = speed
+	}
+}
+
+// WithTimeShift moves replayed timestamps so the first record appears to have
+// been fetched when the replay started. Without it, old captures fall outside
+// the store's retention window as soon as they are stored.
+func WithTimeShift() ReplayOption {
+	return func(p *ReplayProvider) {
+		p.shift = true
+	}
+}
+
+var (
+	_ domain.FlightDataProvider = (*ReplayProvider)(nil)
+	_ domain.Clock              = (*ReplayProvider)(nil)
+)
+
+// NewReplayProvider replays the records of r at real time unless another
+// speed is set. It reads the first record to fix the replay's start time.
+func NewReplayProvider(r *capture.Reader, opts ...ReplayOption) (*ReplayProvider, error) {
+	p := &ReplayProvider{
+		reader:  r,
+		speed:   1,
+		started: time.Now(),
+		done:    make(chan struct{}),
+	}
+	for _, o := range opts {
+		o(p)
+	}
+	if p.speed < 0 {
+		return nil, fmt.Errorf("replay speed must not be negative")
+	}
+
+	first, err := r.Next()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("capture is empty")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p.next = &first
+	p.origin = first.Time
+	if p.shift {
+		p.offset = p.started.Sub(p.origin)
+	}
+
+	return p, nil
+}
+
+// Done is closed once the last record has been returned.
+func (p *ReplayProvider) Done() <-chan struct{} {
+	return p.done
+}
+
+// Now returns the replay clock's time.
+func (p *ReplayProvider) Now() time.Time {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.now()
+}
+
+func (p *ReplayProvider) now() time.Time {
+	if p.speed == 0 {
+		return p.origin.Add(p.elapsed + p.offset)
+	}
+	real := time.Since(p.started)
+	return p.origin.Add(time.Duration(float64(real)*p.speed) + p.offset)
+}
+
+// After waits d of replay time: d divided by the speed in real time, or not
+// at all at speed 0.
+func (p *ReplayProvider) After(d time.Duration) <-chan time.Time {
+	if p.speed > 0 {
+		return time.After(time.Duration(float64(d) / p.speed))
+	}
+
+	p.mu.Lock()
+	p.elapsed += max(d, 0)
+	now := p.now()
+	p.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	ch <- now
+	return ch
+}
+
+func (p *ReplayProvider) FetchTelemetry(ctx context.Context) ([]domain.Telemetry, error) {
+	batch, err := p.nextBatch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots [][]domain.Telemetry
+	var errs []error
+	for _, rec := range batch {
+		data, err := decodeRecord(rec)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s at %s: %w", rec.Source, rec.Time.Format(time.RFC3339), err))
+			continue
+		}
+		for i := range data {
+			data[i].LastContact = data[i].LastContact.Add(p.offset)
+			if tp := data[i].TimePosition; tp != nil {
+				shifted := tp.Add(p.offset)
+				data[i].TimePosition = &shifted
+			}
+		}
+		snapshots = append(snapshots, data)
+	}
+
+	if len(snapshots) == 0 {
+		return nil, errors.Join(errs...)
+	}
+	if len(snapshots) == 1 {
+		return snapshots[0], nil
+	}
+	return domain.FuseTelemetry(snapshots...), nil
+}
+
+// nextBatch returns the newest due record of each feed, waiting for the next
+// record to fall due when none is. At speed 0 it returns the next record of
+// each feed fetched within replayBatchWindow of the first.
+func (p *ReplayProvider) nextBatch(ctx context.Context) ([]capture.Record, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.next == nil {
+		if !p.finished {
+			p.finished = true
+			close(p.done)
+		}
+		return nil, ErrReplayFinished
+	}
+
+	due := p.next.Time.Add(replayBatchWindow)
+	if p.speed > 0 {
+		// wait, unlocked, until the replay clock reaches the next record
+		if wait := time.Duration(float64(p.next.Time.Add(p.offset).Sub(p.now())) / p.speed); wait > 0 {
+			p.mu.Unlock()
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				p.mu.Lock()
+				return nil, ctx.Err()
+			}
+			p.mu.Lock()
+		}
+		due = p.now().Add(-p.offset)
+	}
+
+	latest := make(map[string]capture.Record)
+	var order []string
+	for p.next != nil && !p.next.Time.After(due) {
+		if _, ok := latest[p.next.Source]; !ok {
+			order = append(order, p.next.Source)
+		} else if p.speed == 0 {
+			// a feed's next response starts the next snapshot
+			break
+		}
+		latest[p.next.Source] = *p.next
+
+		rec, err := p.reader.Next()
+		if errors.Is(err, io.EOF) {
+			p.next = nil
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		p.next = &rec
+	}
+
+	if p.next == nil && !p.finished {
+		p.finished = true
+		close(p.done)
+	}
+
+	batch := make([]capture.Record, 0, len(order))
+	for _, source := range order {
+		batch = append(batch, latest[source])
+	}
+	return batch, nil
+}
+
+// decodeRecord parses a recorded response the way the live provider would
+// have, returning a StatusError for recorded non-2xx responses.
+func decodeRecord(rec capture.Record) ([]domain.Telemetry, error) {
+	if rec.Status < 200 || rec.Status > 299 {
+		return nil, &StatusError{
+			StatusCode: rec.Status,
+			Status:     fmt.Sprintf("%d %s", rec.Status, http.StatusText(rec.Status)),
+		}
+	}
+
+	decode, ok := decoders[rec.Format]
+	if !ok {
+		return nil, fmt.Errorf("unknown capture format %q", rec.Format)
+	}
+	return decode(rec.Source, rec.Bytes())
+}
@@ -0,0 +1,115 @@
+package provider_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/northeastloon/flight_tracker/internal/capture"
+	"github.com/northeastloon/flight_tracker/internal/domain"
+	"github.com/northeastloon/flight_tracker/internal/provider"
+	"github.com/northeastloon/flight_tracker/internal/provider/openskytest"
+)
+
+func openSkyRecord(t *testing.T, at time.Time, icao24 string) capture.Record {
+	t.Helper()
+	body, err := json.Marshal(openskytest.NewSnapshot(at, domain.Telemetry{
+		ICAO24:        icao24,
+		OriginCountry: "Ireland",
+		LastContact:   at,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return capture.NewRecord(at, "opensky", provider.FormatOpenSky, 200, body)
+}
+
+func readsbRecord(t *testing.T, at time.Time, hex string) capture.Record {
+	t.Helper()
+	body, err := json.Marshal(provider.ReadsbResponse{
+		Now:      float64(at.Unix()),
+		Aircraft: []provider.ReadsbAircraft{{Hex: hex, Type: "adsb_icao"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return capture.NewRecord(at, "home", provider.FormatReadsb, 200, body)
+}
+
+func TestReplayBatchesAsFastAsPossible(t *testing.T) {
+	dir := t.TempDir()
+	w, err := capture.NewWriter(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := []capture.Record{
+		// one live cycle: both feeds fetched together
+		openSkyRecord(t, snapshotTime, "4ca7b4"),
+		readsbRecord(t, snapshotTime.Add(200*time.Millisecond), "a0b1c2"),
+		// a second cycle, where a feed's next response within the batch
+		// window starts another snapshot
+		openSkyRecord(t, snapshotTime.Add(5*time.Second), "3c6444"),
+		openSkyRecord(t, snapshotTime.Add(5500*time.Millisecond), "4ca7b4"),
+		{Time: snapshotTime.Add(10 * time.Second), Source: "opensky", Format: provider.FormatOpenSky, Status: 503},
+	}
+	for _, rec := range records {
+		if err := w.Write(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := capture.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	rp, err := provider.NewReplayProvider(r, provider.WithReplaySpeed(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rp.Now().Equal(snapshotTime) {
+		t.Errorf("replay clock starts at %s, want %s", rp.Now(), snapshotTime)
+	}
+
+	ctx := context.Background()
+	want := [][]string{{"4ca7b4", "a0b1c2"}, {"3c6444"}, {"4ca7b4"}}
+	for i, icao24s := range want {
+		data, err := rp.FetchTelemetry(ctx)
+		if err != nil {
+			t.Fatalf("fetch %d: %v", i, err)
+		}
+		var got []string
+		for _, tel := range data {
+			got = append(got, tel.ICAO24)
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, icao24s) {
+			t.Errorf("fetch %d returned %v, want %v", i, got, icao24s)
+		}
+	}
+
+	var statusErr *provider.StatusError
+	if _, err := rp.FetchTelemetry(ctx); !errors.As(err, &statusErr) || statusErr.StatusCode != 503 {
+		t.Errorf("recorded 503 replayed as %v", err)
+	}
+	select {
+	case <-rp.Done():
+	default:
+		t.Error("Done is not closed after the last record")
+	}
+	if _, err := rp.FetchTelemetry(ctx); !errors.Is(err, provider.ErrReplayFinished) {
+		t.Errorf("fetch after the end = %v, want ErrReplayFinished", err)
+	}
+
+	// the clock only moves when the loop waits
+	<-rp.After(time.Minute)
+	if want := snapshotTime.Add(time.Minute); !rp.Now().Equal(want) {
+		t.Errorf("replay clock = %s, want %s", rp.Now(), want)
+	}
+}
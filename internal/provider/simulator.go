@@ -0,0 +1,623 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/northeastloon/flight_tracker/internal/domain"
+)
+
+const simulatorProviderName = "simulator"
+
+// Flight profile of simulated aircraft. Altitudes follow the distance flown
+// and left to fly, so an aircraft can be placed anywhere on its route.
+const (
+	simTick            = time.Second
+	simClimbGradient   = 1.0 / 20 // metres of climb per metre flown
+	simDescentGradient = 1.0 / 18 // about a three degree approach
+	simApproachSpeed   = 75.0     // m/s at the runway
+	simSpeedUpAltitude = 6000.0   // metres above the field where cruise speed is reached
+	simTaxiSpeed       = 8.0      // m/s
+	simTaxiOut         = 4 * time.Minute
+	simTaxiIn          = 3 * time.Minute
+	simParkedVisible   = 2 * time.Minute // transponders are switched off after this
+	simMinTurnaround   = 10 * time.Minute
+	simMaxTurnaround   = 40 * time.Minute
+	simIdentDuration   = 18 * time.Second // SPI is held this long after an ident
+	simMinRoute        = 200e3            // metres
+)
+
+// Chance per flight of a squawk change on the way, and of an emergency.
+const (
+	simSquawkChangeChance = 0.3
+	simEmergencyChance    = 0.01
+)
+
+// SimAirport is an airport the simulator flies between.
+type SimAirport struct {
+	Code      string // ICAO location indicator
+	Latitude  float64
+	Longitude float64
+	Elevation float64 // metres
+}
+
+// simAirports are flown between when no airports are configured.
+var simAirports = []SimAirport{
+	{"EGLL", 51.4706, -0.4619, 25},
+	{"LFPG", 49.0097, 2.5479, 119},
+	{"EDDF", 50.0333, 8.5706, 111},
+	{"EHAM", 52.3086, 4.7639, -3},
+	{"LEMD", 40.4719, -3.5626, 610},
+	{"LIRF", 41.8003, 12.2389, 5},
+	{"EIDW", 53.4213, -6.2701, 74},
+	{"LSZH", 47.4647, 8.5492, 432},
+	{"KJFK", 40.6398, -73.7789, 4},
+	{"KORD", 41.9786, -87.9048, 205},
+	{"KATL", 33.6367, -84.4281, 313},
+	{"KLAX", 33.9425, -118.4081, 38},
+	{"KSFO", 37.6190, -122.3749, 4},
+	{"CYYZ", 43.6772, -79.6306, 173},
+	{"OMDB", 25.2528, 55.3644, 19},
+	{"WSSS", 1.3502, 103.9944, 7},
+	{"VHHH", 22.3089, 113.9146, 9},
+	{"RJTT", 35.5523, 139.7798, 11},
+	{"YSSY", -33.9461, 151.1772, 6},
+	{"SBGR", -23.4356, -46.4731, 750},
+	{"FAOR", -26.1392, 28.2460, 1694},
+}
+
+// Simulated aircraft take their ICAO24 addresses from a block ICAO has not
+// allocated to any state, so they can never collide with real aircraft seen
+// by live feeds, and the API reports them with icao24_allocated false.
+const (
+	simAddressFirst uint32 = 0xb00000
+	simAddressLast  uint32 = 0xbfffff
+)
+
+// simAirline gives simulated aircraft their callsign prefix and the country
+// reported as their origin.
+type simAirline struct {
+	code    string // ICAO designator
+	country string // as OpenSky reports it
+}
+
+var simAirlines = []simAirline{
+	{"BAW", "United Kingdom"},
+	{"EZY", "United Kingdom"},
+	{"DLH", "Germany"},
+	{"AFR", "France"},
+	{"KLM", "Kingdom of the Netherlands"},
+	{"RYR", "Ireland"},
+	{"IBE", "Spain"},
+	{"SWR", "Switzerland"},
+	{"UAL", "United States"},
+	{"DAL", "United States"},
+	{"AAL", "United States"},
+	{"ACA", "Canada"},
+	{"UAE", "United Arab Emirates"},
+	{"SIA", "Singapore"},
+	{"CPA", "China"},
+	{"JAL", "Japan"},
+	{"QFA", "Australia"},
+	{"TAM", "Brazil"},
+	{"SAA", "South Africa"},
+}
+
+// ParseSimAirports parses a comma-separated list of airports to simulate.
+// Each entry is the ICAO code of a built-in airport, or CODE=lat:lon with an
+// optional :elevation in metres. An empty spec selects every built-in
+// airport.
+func ParseSimAirports(spec string) ([]SimAirport, error) {
+	if strings.TrimSpace(spec) == "" {
+		return append([]SimAirport(nil), simAirports...), nil
+	}
+
+	var airports []SimAirport
+	seen := make(map[string]bool)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		code, position, custom := strings.Cut(entry, "=")
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if seen[code] {
+			return nil, fmt.Errorf("airport %s is listed twice", code)
+		}
+		seen[code] = true
+
+		if !custom {
+			a, ok := builtinSimAirport(code)
+			if !ok {
+				return nil, fmt.Errorf("unknown airport %s; give its position as %s=lat:lon", code, code)
+			}
+			airports = append(airports, a)
+			continue
+		}
+
+		a, err := parseSimAirport(code, position)
+		if err != nil {
+			return nil, err
+		}
+		airports = append(airports, a)
+	}
+
+	if len(airports) < 2 {
+		return nil, fmt.Errorf("at least two airports are needed")
+	}
+	return airports, nil
+}
+
+func builtinSimAirport(code string) (SimAirport, bool) {
+	for _, a := range simAirports {
+		if a.Code == code {
+			return a, true
+		}
+	}
+	return SimAirport{}, false
+}
+
+func parseSimAirport(code, position string) (SimAirport, error) {
+	parts := strings.Split(position, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return SimAirport{}, fmt.Errorf("airport %s: position must be lat:lon or lat:lon:elevation", code)
+	}
+	values := make([]float64, 3)
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return SimAirport{}, fmt.Errorf("airport %s: invalid position %q", code, position)
+		}
+		values[i] = v
+	}
+	if values[0] < -90 || values[0] > 90 || values[1] < -180 || values[1] > 180 {
+		return SimAirport{}, fmt.Errorf("airport %s: position out of range", code)
+	}
+	return SimAirport{Code: code, Latitude: values[0], Longitude: values[1], Elevation: values[2]}, nil
+}
+
+// SimulatorProvider generates traffic with no network: aircraft fly great
+// circle routes between airports with climb, cruise and descent, land, turn
+// around and fly on. Now and then a flight is given a new squawk, and a few
+// declare an emergency and divert to the nearest airport.
+//
+// The simulation advances in one second steps from the time it was created.
+// Every aircraft draws from its own generator seeded from the seed, so a
+// given seed produces the same traffic at the same elapsed time however
+// often it is fetched.
+type SimulatorProvider struct {
+	seed     uint64
+	count    int
+	airports []SimAirport
+	clock    domain.Clock
+
+	mu       sync.Mutex
+	now      time.Time
+	aircraft []*simAircraft
+}
+
+type SimulatorOption func(p *SimulatorProvider)
+
+// WithSimulatorSeed sets the seed traffic is generated from.
+func WithSimulatorSeed(seed uint64) SimulatorOption {
+	return func(p *SimulatorProvider) {
+		p.seed = seed
+	}
+}
+
+// WithSimulatorAircraft sets how many aircraft are simulated.
+func WithSimulatorAircraft(n int) SimulatorOption {
+	return func(p *SimulatorProvider) {
+		p.count = n
+	}
+}
+
+// WithSimulatorAirports sets the airports flown between, replacing the
+// built-in set.
+func WithSimulatorAirports(airports []SimAirport) SimulatorOption {
+	return func(p *SimulatorProvider) {
+		p.airports = airports
+	}
+}
+
+// WithSimulatorClock sets the clock the simulation follows instead of the
+// system clock.
+func WithSimulatorClock(c domain.Clock) SimulatorOption {
+	return func(p *SimulatorProvider) {
+		p.clock = c
+	}
+}
+
+var _ domain.FlightDataProvider = (*SimulatorProvider)(nil)
+
+// NewSimulatorProvider simulates 100 aircraft between the built-in airports
+// unless options say otherwise. Aircraft start spread along their first
+// routes, most of them airborne.
+func NewSimulatorProvider(opts ...SimulatorOption) (*SimulatorProvider, error) {
+	p := &SimulatorProvider{
+		seed:     1,
+		count:    100,
+		airports: simAirports,
+	}
+	for _, o := range opts {
+		o(p)
+	}
+	if p.count < 1 {
+		return nil, fmt.Errorf("simulator needs at least one aircraft")
+	}
+	codes := make(map[string]bool)
+	for _, a := range p.airports {
+		codes[a.Code] = true
+	}
+	if len(codes) < 2 {
+		return nil, fmt.Errorf("simulator needs at least two distinct airports")
+	}
+
+	p.now = p.clockNow().Truncate(simTick)
+	seen := make(map[string]bool)
+	for i := range p.count {
+		a := newSimAircraft(rand.New(rand.NewPCG(p.seed, uint64(i))), p.airports, seen)
+		a.place(p.now)
+		p.aircraft = append(p.aircraft, a)
+	}
+
+	return p, nil
+}
+
+// FetchTelemetry advances the simulation to the clock's time and returns the
+// aircraft whose transponders are on, in the shape OpenSky reports them.
+func (p *SimulatorProvider) FetchTelemetry(ctx context.Context) ([]domain.Telemetry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	target := p.clockNow().Truncate(simTick)
+	for p.now.Before(target) {
+		p.now = p.now.Add(simTick)
+		for _, a := range p.aircraft {
+			a.step(p.now, p.airports)
+		}
+	}
+
+	telemetry := make([]domain.Telemetry, 0, len(p.aircraft))
+	for _, a := range p.aircraft {
+		if !a.visible(p.now) {
+			continue
+		}
+		t := a.state(p.now).Normalize()
+		t.Source = simulatorProviderName
+		telemetry = append(telemetry, t)
+	}
+	return telemetry, nil
+}
+
+func (p *SimulatorProvider) clockNow() time.Time {
+	if p.clock == nil {
+		return time.Now().UTC()
+	}
+	return p.clock.Now().UTC()
+}
+
+type simPhase int
+
+const (
+	simParked simPhase = iota
+	simTaxiingOut
+	simAirborne
+	simTaxiingIn
+)
+
+// simAircraft is one airframe flying a sequence of flights.
+type simAircraft struct {
+	rng       *rand.Rand
+	icao24    string
+	airline   simAirline
+	category  int
+	heavy     bool
+	geoOffset float64 // geometric minus barometric altitude, metres
+
+	phase    simPhase
+	phaseEnd time.Time // end of a timed ground phase
+	parkedAt time.Time
+
+	// current flight; the route starts at the origin airport, or where the
+	// aircraft was when it diverted
+	callsign    string
+	squawk      string
+	from, to    SimAirport
+	startAlt    float64
+	distance    float64
+	flown       float64
+	cruiseAlt   float64
+	cruiseSpeed float64
+	changeAt    float64 // distance at which the squawk changes; negative for never
+	emergencyAt float64 // distance at which an emergency is declared; negative for never
+	emergency   bool
+	identUntil  time.Time
+
+	latitude, longitude float64
+	altitude, speed     float64
+	verticalRate, track float64
+}
+
+func newSimAircraft(rng *rand.Rand, airports []SimAirport, seen map[string]bool) *simAircraft {
+	a := &simAircraft{
+		rng:       rng,
+		airline:   simAirlines[rng.IntN(len(simAirlines))],
+		geoOffset: 20 + rng.Float64()*150,
+	}
+
+	// mostly narrow-bodies, some heavies and a few high vortex large
+	switch r := rng.Float64(); {
+	case r < 0.7:
+		a.category = 4
+	case r < 0.75:
+		a.category = 5
+	default:
+		a.category = 6
+		a.heavy = true
+	}
+
+	for {
+		addr := simAddressFirst + uint32(rng.IntN(int(simAddressLast-simAddressFirst+1)))
+		a.icao24 = fmt.Sprintf("%06x", addr)
+		if !seen[a.icao24] {
+			seen[a.icao24] = true
+			break
+		}
+	}
+
+	a.to = airports[rng.IntN(len(airports))]
+	a.plan(airports)
+	return a
+}
+
+// place puts a newly created aircraft somewhere on its first flight.
+func (a *simAircraft) place(now time.Time) {
+	switch r := a.rng.Float64(); {
+	case r < 0.75:
+		a.phase = simAirborne
+		a.flown = a.rng.Float64() * a.distance
+		a.altitude = a.profileAltitude()
+		a.speed = a.profileSpeed()
+		a.latitude, a.longitude = domain.IntermediatePoint(
+			a.from.Latitude, a.from.Longitude, a.to.Latitude, a.to.Longitude, a.flown/a.distance)
+		a.track = domain.InitialBearing(a.latitude, a.longitude, a.to.Latitude, a.to.Longitude)
+	case r < 0.85:
+		a.phase = simTaxiingOut
+		a.phaseEnd = now.Add(time.Duration(a.rng.Int64N(int64(simTaxiOut))))
+		a.speed = simTaxiSpeed
+	default:
+		a.phase = simParked
+		a.parkedAt = now.Add(-time.Duration(a.rng.Int64N(int64(simMinTurnaround))))
+		a.phaseEnd = now.Add(time.Duration(a.rng.Int64N(int64(simMaxTurnaround))))
+	}
+}
+
+// plan prepares the next flight from the airport the aircraft is at.
+func (a *simAircraft) plan(airports []SimAirport) {
+	a.from = a.to
+	a.to = a.pickDestination(airports)
+	a.distance = domain.GreatCircleDistance(a.from.Latitude, a.from.Longitude, a.to.Latitude, a.to.Longitude)
+	a.startAlt = a.from.Elevation
+	a.flown = 0
+
+	a.latitude, a.longitude = a.from.Latitude, a.from.Longitude
+	a.altitude = a.from.Elevation
+	a.verticalRate = 0
+	a.track = domain.InitialBearing(a.from.Latitude, a.from.Longitude, a.to.Latitude, a.to.Longitude)
+
+	a.callsign = fmt.Sprintf("%s%d", a.airline.code, 1+a.rng.IntN(9999))
+	a.squawk = a.newSquawk()
+	a.emergency = false
+	a.identUntil = time.Time{}
+
+	if a.heavy {
+		a.cruiseAlt = float64(340+10*a.rng.IntN(6)) * 100 * domain.FeetToMetres
+		a.cruiseSpeed = 245 + a.rng.Float64()*15
+	} else {
+		a.cruiseAlt = float64(300+10*a.rng.IntN(8)) * 100 * domain.FeetToMetres
+		a.cruiseSpeed = 220 + a.rng.Float64()*15
+	}
+
+	a.changeAt, a.emergencyAt = -1, -1
+	if a.rng.Float64() < simSquawkChangeChance {
+		a.changeAt = a.distance * (0.2 + 0.6*a.rng.Float64())
+	}
+	if a.rng.Float64() < simEmergencyChance {
+		a.emergencyAt = a.distance * (0.1 + 0.7*a.rng.Float64())
+	}
+}
+
+// pickDestination prefers routes suited to the airframe: narrow-bodies up to
+// 5000 km, heavies from 2000 km, anything at least simMinRoute away.
+func (a *simAircraft) pickDestination(airports []SimAirport) SimAirport {
+	var suited, others []SimAirport
+	for _, b := range airports {
+		if b.Code == a.from.Code {
+			continue
+		}
+		d := domain.GreatCircleDistance(a.from.Latitude, a.from.Longitude, b.Latitude, b.Longitude)
+		switch {
+		case d < simMinRoute:
+			others = append(others, b)
+		case a.heavy && d >= 2000e3, !a.heavy && d <= 5000e3:
+			suited = append(suited, b)
+		default:
+			others = append(others, b)
+		}
+	}
+	if len(suited) > 0 {
+		return suited[a.rng.IntN(len(suited))]
+	}
+	return others[a.rng.IntN(len(others))]
+}
+
+// newSquawk draws a discrete code, avoiding the special purpose ones.
+func (a *simAircraft) newSquawk() string {
+	for {
+		code := fmt.Sprintf("%d%d%d%d", a.rng.IntN(8), a.rng.IntN(8), a.rng.IntN(8), a.rng.IntN(8))
+		switch code {
+		case "0000", "1200", "2000", "7000", "7500", "7600", "7700", a.squawk:
+			continue
+		}
+		return code
+	}
+}
+
+// profileAltitude is the altitude for the distance flown: climbing out of
+// the origin, descending into the destination and level in between.
+func (a *simAircraft) profileAltitude() float64 {
+	climb := a.startAlt + a.flown*simClimbGradient
+	descent := a.to.Elevation + (a.distance-a.flown)*simDescentGradient
+	return math.Min(a.cruiseAlt, math.Min(climb, descent))
+}
+
+// profileSpeed rises from approach speed near the ground to cruise speed.
+func (a *simAircraft) profileSpeed() float64 {
+	field := math.Min(a.from.Elevation, a.to.Elevation)
+	f := math.Max(0, math.Min(1, (a.altitude-field)/simSpeedUpAltitude))
+	return simApproachSpeed + (a.cruiseSpeed-simApproachSpeed)*f
+}
+
+// step advances the aircraft by one tick ending at now.
+func (a *simAircraft) step(now time.Time, airports []SimAirport) {
+	dt := simTick.Seconds()
+
+	switch a.phase {
+	case simParked:
+		if !now.Before(a.phaseEnd) {
+			a.plan(airports)
+			a.phase = simTaxiingOut
+			a.phaseEnd = now.Add(simTaxiOut)
+			a.speed = simTaxiSpeed
+		}
+
+	case simTaxiingOut:
+		if !now.Before(a.phaseEnd) {
+			a.phase = simAirborne
+			a.speed = simApproachSpeed
+		}
+
+	case simAirborne:
+		before := a.flown
+		a.flown += a.speed * dt
+		if a.flown >= a.distance {
+			a.land(now)
+			return
+		}
+
+		if before < a.changeAt && a.flown >= a.changeAt && !a.emergency {
+			a.squawk = a.newSquawk()
+			a.identUntil = now.Add(simIdentDuration)
+		}
+		if before < a.emergencyAt && a.flown >= a.emergencyAt {
+			a.declareEmergency(now, airports)
+		}
+
+		previous := a.altitude
+		a.latitude, a.longitude = domain.IntermediatePoint(
+			a.from.Latitude, a.from.Longitude, a.to.Latitude, a.to.Longitude, a.flown/a.distance)
+		a.track = domain.InitialBearing(a.latitude, a.longitude, a.to.Latitude, a.to.Longitude)
+		a.altitude = a.profileAltitude()
+		a.verticalRate = (a.altitude - previous) / dt
+		a.speed = a.profileSpeed()
+
+	case simTaxiingIn:
+		if !now.Before(a.phaseEnd) {
+			a.phase = simParked
+			a.parkedAt = now
+			a.phaseEnd = now.Add(simMinTurnaround +
+				time.Duration(a.rng.Int64N(int64(simMaxTurnaround-simMinTurnaround))))
+			a.speed = 0
+		}
+	}
+}
+
+func (a *simAircraft) land(now time.Time) {
+	a.phase = simTaxiingIn
+	a.phaseEnd = now.Add(simTaxiIn)
+	a.latitude, a.longitude = a.to.Latitude, a.to.Longitude
+	a.altitude = a.to.Elevation
+	a.verticalRate = 0
+	a.speed = simTaxiSpeed
+}
+
+// declareEmergency squawks 7700 and diverts to the nearest airport, or, one
+// time in ten, squawks 7600 for a radio failure and flies on.
+func (a *simAircraft) declareEmergency(now time.Time, airports []SimAirport) {
+	a.emergency = true
+	a.identUntil = now.Add(simIdentDuration)
+	if a.rng.Float64() < 0.1 {
+		a.squawk = "7600"
+		return
+	}
+	a.squawk = "7700"
+
+	nearest := airports[0]
+	best := math.Inf(1)
+	for _, b := range airports {
+		if d := domain.GreatCircleDistance(a.latitude, a.longitude, b.Latitude, b.Longitude); d < best {
+			nearest, best = b, d
+		}
+	}
+
+	// the new route starts here, level at the current altitude
+	a.from = SimAirport{Latitude: a.latitude, Longitude: a.longitude, Elevation: a.altitude}
+	a.to = nearest
+	a.startAlt = a.altitude
+	a.cruiseAlt = a.altitude
+	a.distance = math.Max(best, 1)
+	a.flown = 0
+	a.changeAt, a.emergencyAt = -1, -1
+}
+
+// visible reports whether the transponder is on: from taxi-out until shortly
+// after parking.
+func (a *simAircraft) visible(now time.Time) bool {
+	return a.phase != simParked || now.Sub(a.parkedAt) < simParkedVisible
+}
+
+// state reports the aircraft as an OpenSky state vector would.
+func (a *simAircraft) state(now time.Time) OpenSkyTelemetry {
+	ts := now.Unix()
+	callsign := fmt.Sprintf("%-8s", a.callsign)
+	squawk := a.squawk
+
+	t := OpenSkyTelemetry{
+		Icao24:        a.icao24,
+		Callsign:      &callsign,
+		OriginCountry: a.airline.country,
+		TimePosition:  &ts,
+		LastContact:   ts,
+		Longitude:     simRound(a.longitude, 4),
+		Latitude:      simRound(a.latitude, 4),
+		OnGround:      a.phase != simAirborne,
+		Velocity:      simRound(a.speed, 2),
+		TrueTrack:     simRound(a.track, 2),
+		Squawk:        &squawk,
+		SPI:           now.Before(a.identUntil),
+		Category:      a.category,
+	}
+	if !t.OnGround {
+		t.BaroAltitude = simRound(a.altitude, 2)
+		t.GeoAltitude = simRound(a.altitude+a.geoOffset, 2)
+		t.VerticalRate = simRound(a.verticalRate, 2)
+	}
+	return t
+}
+
+func simRound(v float64, places int) *float64 {
+	scale := math.Pow(10, float64(places))
+	r := math.Round(v*scale) / scale
+	return &r
+}
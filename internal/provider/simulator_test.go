@@ -0,0 +1,65 @@
+package provider_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/northeastloon/flight_tracker/internal/domain"
+	"github.com/northeastloon/flight_tracker/internal/provider"
+)
+
+// manualClock is a domain.Clock the test moves by hand.
+type manualClock struct{ now time.Time }
+
+func (c *manualClock) Now() time.Time { return c.now }
+
+func (c *manualClock) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	ch <- c.now.Add(d)
+	return ch
+}
+
+func TestSimulatorIsDeterministic(t *testing.T) {
+	run := func(seed uint64) [][]domain.Telemetry {
+		clock := &manualClock{now: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)}
+		sim, err := provider.NewSimulatorProvider(
+			provider.WithSimulatorSeed(seed),
+			provider.WithSimulatorAircraft(25),
+			provider.WithSimulatorClock(clock),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var snapshots [][]domain.Telemetry
+		for range 5 {
+			clock.now = clock.now.Add(30 * time.Second)
+			snapshot, err := sim.FetchTelemetry(context.Background())
+			if err != nil {
+				t.Fatalf("FetchTelemetry: %v", err)
+			}
+			snapshots = append(snapshots, snapshot)
+		}
+		return snapshots
+	}
+
+	first, second := run(7), run(7)
+	if !reflect.DeepEqual(first, second) {
+		t.Fatal("two simulators with the same seed diverged")
+	}
+	if reflect.DeepEqual(first, run(8)) {
+		t.Error("simulators with different seeds produced identical snapshots")
+	}
+
+	for _, snapshot := range first {
+		if len(snapshot) == 0 {
+			t.Fatal("empty snapshot")
+		}
+		for _, tel := range snapshot {
+			if _, allocated := domain.DecodeAddress(tel.ICAO24); allocated {
+				t.Errorf("simulated address %s is allocated to a state", tel.ICAO24)
+			}
+		}
+	}
+}
@@ -1,5 +1,42 @@
 package provider
 
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+)
+
+// errorType classifies a fetch error into a small, fixed set of metric labels.
+func errorType(err error) string {
+	var statusErr *StatusError
+	var netErr net.Error
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.As(err, &statusErr) && statusErr.StatusCode == 429:
+		return "rate_limited"
+	case errors.As(err, &statusErr):
+		return "http_status"
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case errors.Is(err, ErrNoStates):
+		return "empty"
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return "decode"
+	case errors.As(err, &netErr):
+		return "network"
+	default:
+		return "other"
+	}
+}
+
 func optionalString(v any) *string {
 	if s, ok := v.(string); ok {
 		return &s
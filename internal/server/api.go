@@ -3,6 +3,7 @@ package server
 import (
 	"context"
 	"net/http"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/northeastloon/flight_tracker/internal/domain"
@@ -10,6 +11,16 @@ import (
 
 type TelemetryStore interface {
 	GetTelemetry(ctx context.Context, filter *domain.TelemetryFilter) ([]domain.Telemetry, error)
+	GetTrafficStats(ctx context.Context, filter *domain.StatsFilter) ([]domain.StatsRow, error)
+	GetSourceCoverage(ctx context.Context, filter *domain.CoverageFilter) ([]domain.CoverageRow, error)
+	GetFlights(ctx context.Context, filter *domain.FlightFilter) ([]domain.Flight, error)
+	GetAirport(ctx context.Context, code string) (*domain.Airport, error)
+	NearestAirports(ctx context.Context, filter *domain.NearestAirportFilter) ([]domain.Airport, error)
+	Ping(ctx context.Context) error
+	MigrationsApplied(ctx context.Context) (bool, error)
+	NewestContact(ctx context.Context) (*time.Time, error)
+	GetStorageStatus(ctx context.Context) (domain.StorageStatus, error)
+	GetLeaseStatus(ctx context.Context) (*domain.LeaseStatus, error)
 }
 
 type APIHandler struct {
@@ -23,15 +34,145 @@ func NewAPIHandler(store TelemetryStore) *APIHandler {
 }
 
 func (h *APIHandler) GetTelemetry(c echo.Context) error {
-	filter := &domain.TelemetryFilter{}
-	if err := c.Bind(filter); err != nil {
+	q, err := ParseTelemetryQuery(c.QueryParams())
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, err)
+	}
+
+	telemetry, err := h.store.GetTelemetry(c.Request().Context(), q.Filter)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	resp, err := telemetryResponse(telemetry, q.Units)
+	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
-	telemetry, err := h.store.GetTelemetry(c.Request().Context(), filter)
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *APIHandler) GetAircraft(c echo.Context) error {
+	q, err := ParseAircraftQuery(c.Param("icao24"), c.QueryParams())
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, err)
+	}
+
+	ctx := c.Request().Context()
+	latestOnly := true
+
+	latest, err := h.store.GetTelemetry(ctx, &domain.TelemetryFilter{
+		ICAO24: &q.ICAO24,
+		Latest: &latestOnly,
+	})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if len(latest) == 0 {
+		return echo.NewHTTPError(http.StatusNotFound, "aircraft not found")
+	}
+
+	track, err := h.store.GetTelemetry(ctx, &domain.TelemetryFilter{
+		ICAO24: &q.ICAO24,
+		From:   q.From,
+		To:     q.To,
+	})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	resp, err := aircraftResponse(domain.SummarizeAircraft(latest[0], track), q.Units)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *APIHandler) GetStats(c echo.Context) error {
+	q, err := ParseStatsQuery(c.QueryParams(), time.Now())
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, err)
+	}
+
+	stats, err := h.store.GetTrafficStats(c.Request().Context(), q.Filter)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, newStatsV1(q, stats))
+}
+
+func (h *APIHandler) GetSourceCoverage(c echo.Context) error {
+	q, err := ParseCoverageQuery(c.QueryParams(), time.Now())
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, err)
+	}
+
+	coverage, err := h.store.GetSourceCoverage(c.Request().Context(), q.Filter)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
 
-	return c.JSON(http.StatusOK, telemetry)
+	return c.JSON(http.StatusOK, newCoverageV1(q, coverage))
+}
+
+func (h *APIHandler) GetFlights(c echo.Context) error {
+	q, err := ParseFlightsQuery(c.QueryParams())
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, err)
+	}
+
+	flights, err := h.store.GetFlights(c.Request().Context(), q.Filter)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	resp, err := flightsResponse(flights, q.Units)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *APIHandler) GetAirport(c echo.Context) error {
+	q, err := ParseAirportQuery(c.Param("icao"), c.QueryParams())
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, err)
+	}
+
+	airport, err := h.store.GetAirport(c.Request().Context(), q.Code)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if airport == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "airport not found")
+	}
+
+	resp, err := airportResponse(*airport, q.Units)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *APIHandler) GetNearestAirports(c echo.Context) error {
+	q, err := ParseNearestAirportsQuery(c.QueryParams())
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, err)
+	}
+
+	airports, err := h.store.NearestAirports(c.Request().Context(), q.Filter)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	resp, err := airportsResponse(airports, q.Units)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, resp)
 }
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/northeastloon/flight_tracker/internal/domain"
+)
+
+// IngestStatusSource is implemented by the ingestion service when it runs in
+// the same process as the server.
+type IngestStatusSource interface {
+	Status() domain.IngestStatus
+}
+
+type HealthHandler struct {
+	store      TelemetryStore
+	ingest     IngestStatusSource // nil when ingestion runs elsewhere
+	staleAfter time.Duration
+	replica    string
+	started    time.Time
+}
+
+func NewHealthHandler(store TelemetryStore, ingest IngestStatusSource, staleAfter time.Duration, replica string) *HealthHandler {
+	return &HealthHandler{
+		store:      store,
+		ingest:     ingest,
+		staleAfter: staleAfter,
+		replica:    replica,
+		started:    time.Now(),
+	}
+}
+
+// Healthz reports that the process is up. It never touches the database.
+func (h *HealthHandler) Healthz(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// Readyz reports whether the server can do useful work: the database answers,
+// the schema is current and ingestion is not stale.
+func (h *HealthHandler) Readyz(c echo.Context) error {
+	ctx := c.Request().Context()
+	checks := map[string]string{}
+	ready := true
+
+	fail := func(check, reason string) {
+		checks[check] = reason
+		ready = false
+	}
+
+	if err := h.store.Ping(ctx); err != nil {
+		fail("database", err.Error())
+	} else {
+		checks["database"] = "ok"
+	}
+
+	if applied, err := h.store.MigrationsApplied(ctx); err != nil {
+		fail("migrations", err.Error())
+	} else if !applied {
+		fail("migrations", "pending")
+	} else {
+		checks["migrations"] = "ok"
+	}
+
+	if age, err := h.ingestAge(ctx, time.Now()); err != nil {
+		fail("ingestion", err.Error())
+	} else if age > h.staleAfter {
+		fail("ingestion", "stale for "+age.Round(time.Second).String())
+	} else {
+		checks["ingestion"] = "ok"
+	}
+
+	resp := ReadinessV1{Status: "ready", Checks: checks}
+	if !ready {
+		resp.Status = "not ready"
+		return c.JSON(http.StatusServiceUnavailable, resp)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// ingestAge is the time since ingestion last succeeded. When ingestion runs in
+// another process, or another replica holds the ingest lease, the age of the
+// newest stored fix is used instead.
+func (h *HealthHandler) ingestAge(ctx context.Context, now time.Time) (time.Duration, error) {
+	if h.ingest != nil {
+		if st := h.ingest.Status(); st.Leader {
+			if !st.LastSuccess.IsZero() {
+				return now.Sub(st.LastSuccess), nil
+			}
+			return now.Sub(h.started), nil
+		}
+	}
+
+	newest, err := h.store.NewestContact(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if newest == nil {
+		return now.Sub(h.started), nil
+	}
+	return now.Sub(*newest), nil
+}
+
+func (h *HealthHandler) GetStatus(c echo.Context) error {
+	ctx := c.Request().Context()
+	now := time.Now()
+
+	storage, err := h.store.GetStorageStatus(ctx)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	lease, err := h.store.GetLeaseStatus(ctx)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	resp := StatusV1{
+		Time:          formatTime(now),
+		UptimeSeconds: now.Sub(h.started).Seconds(),
+		Storage: StorageStatusV1{
+			Rows:              storage.Rows,
+			Aircraft:          storage.Aircraft,
+			NewestLastContact: formatOptionalTime(storage.NewestContact),
+			MigrationsApplied: storage.MigrationsReady,
+		},
+	}
+	if storage.NewestContact != nil {
+		age := now.Sub(*storage.NewestContact).Seconds()
+		resp.Storage.DataAgeSeconds = &age
+	}
+	if h.replica != "" {
+		resp.Replica = &h.replica
+	}
+	if h.ingest != nil {
+		st := newIngestStatusV1(h.ingest.Status())
+		resp.Ingest = &st
+	}
+	if lease != nil {
+		l := newLeaseStatusV1(*lease)
+		resp.Lease = &l
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
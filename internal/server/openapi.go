@@ -0,0 +1,18 @@
+package server
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// openAPISpec describes every /api/v1 route. It is kept in step with the
+// handlers by openapi_test.go.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+func (h *APIHandler) GetOpenAPI(c echo.Context) error {
+	return c.Blob(http.StatusOK, echo.MIMEApplicationJSON, openAPISpec)
+}
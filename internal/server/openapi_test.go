@@ -0,0 +1,321 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/northeastloon/flight_tracker/internal/domain"
+)
+
+const apiPrefix = "/api/v1"
+
+type stubStore struct{}
+
+func (stubStore) GetTelemetry(ctx context.Context, filter *domain.TelemetryFilter) ([]domain.Telemetry, error) {
+	return nil, nil
+}
+
+func (stubStore) GetTrafficStats(ctx context.Context, filter *domain.StatsFilter) ([]domain.StatsRow, error) {
+	return nil, nil
+}
+
+func (stubStore) GetSourceCoverage(ctx context.Context, filter *domain.CoverageFilter) ([]domain.CoverageRow, error) {
+	return nil, nil
+}
+
+func (stubStore) GetFlights(ctx context.Context, filter *domain.FlightFilter) ([]domain.Flight, error) {
+	return nil, nil
+}
+
+func (stubStore) GetAirport(ctx context.Context, code string) (*domain.Airport, error) {
+	return nil, nil
+}
+
+func (stubStore) NearestAirports(ctx context.Context, filter *domain.NearestAirportFilter) ([]domain.Airport, error) {
+	return nil, nil
+}
+
+func (stubStore) Ping(ctx context.Context) error { return nil }
+
+func (stubStore) MigrationsApplied(ctx context.Context) (bool, error) { return true, nil }
+
+func (stubStore) NewestContact(ctx context.Context) (*time.Time, error) { return nil, nil }
+
+func (stubStore) GetStorageStatus(ctx context.Context) (domain.StorageStatus, error) {
+	return domain.StorageStatus{}, nil
+}
+
+func (stubStore) GetLeaseStatus(ctx context.Context) (*domain.LeaseStatus, error) {
+	return nil, nil
+}
+
+type openAPIDoc struct {
+	Paths map[string]map[string]struct {
+		Parameters []struct {
+			Name   string `json:"name"`
+			In     string `json:"in"`
+			Schema struct {
+				Enum    []string `json:"enum"`
+				Default any      `json:"default"`
+			} `json:"schema"`
+		} `json:"parameters"`
+	} `json:"paths"`
+	Components struct {
+		Schemas map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"schemas"`
+	} `json:"components"`
+}
+
+func loadSpec(t *testing.T) openAPIDoc {
+	t.Helper()
+	var doc openAPIDoc
+	if err := json.Unmarshal(openAPISpec, &doc); err != nil {
+		t.Fatalf("openapi.json is not valid JSON: %v", err)
+	}
+	return doc
+}
+
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	s, err := NewServer(stubStore{})
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	return s
+}
+
+var echoParam = regexp.MustCompile(`:([^/]+)`)
+
+func TestOpenAPIRoutesMatchHandlers(t *testing.T) {
+	doc := loadSpec(t)
+	s := newTestServer(t)
+
+	registered := map[string]bool{}
+	for _, r := range s.Echo.Routes() {
+		if !strings.HasPrefix(r.Path, apiPrefix+"/") {
+			continue
+		}
+		path := echoParam.ReplaceAllString(strings.TrimPrefix(r.Path, apiPrefix), "{$1}")
+		registered[strings.ToLower(r.Method)+" "+path] = true
+	}
+
+	documented := map[string]bool{}
+	for path, ops := range doc.Paths {
+		for method := range ops {
+			documented[method+" "+path] = true
+		}
+	}
+
+	for op := range registered {
+		if !documented[op] {
+			t.Errorf("route %q is served but missing from openapi.json", op)
+		}
+	}
+	for op := range documented {
+		if !registered[op] {
+			t.Errorf("route %q is documented but not served", op)
+		}
+	}
+}
+
+func TestOpenAPIQueryParametersMatchParsers(t *testing.T) {
+	doc := loadSpec(t)
+
+	parsers := map[string]func(p *queryParser) error{
+		"/telemetry": func(p *queryParser) error {
+			_, err := parseTelemetryQuery(p)
+			return err
+		},
+		"/aircraft/{icao24}": func(p *queryParser) error {
+			_, err := parseAircraftQuery("3c6444", p)
+			return err
+		},
+		"/stats": func(p *queryParser) error {
+			_, err := parseStatsQuery(p, time.Now())
+			return err
+		},
+		"/stats/sources": func(p *queryParser) error {
+			_, err := parseCoverageQuery(p, time.Now())
+			return err
+		},
+		"/flights": func(p *queryParser) error {
+			_, err := parseFlightsQuery(p)
+			return err
+		},
+		"/airports/{icao}": func(p *queryParser) error {
+			_, err := parseAirportQuery("EGLL", p)
+			return err
+		},
+		"/airports/nearest": func(p *queryParser) error {
+			p.values = url.Values{"lat": {"51.47"}, "lon": {"-0.45"}} // required
+			_, err := parseNearestAirportsQuery(p)
+			return err
+		},
+	}
+
+	for path, parse := range parsers {
+		var documented []string
+		for _, p := range doc.Paths[path]["get"].Parameters {
+			if p.In == "query" {
+				documented = append(documented, p.Name)
+			}
+		}
+
+		p := newQueryParser(nil)
+		if err := parse(p); err != nil {
+			t.Fatalf("%s: empty query should be valid: %v", path, err)
+		}
+		var parsed []string
+		for name := range p.known {
+			parsed = append(parsed, name)
+		}
+
+		sort.Strings(documented)
+		sort.Strings(parsed)
+		if !reflect.DeepEqual(documented, parsed) {
+			t.Errorf("%s query parameters differ:\n spec:   %v\n parser: %v", path, documented, parsed)
+		}
+	}
+}
+
+func TestOpenAPIBucketsMatchParser(t *testing.T) {
+	doc := loadSpec(t)
+
+	var names []string
+	for _, b := range statsBuckets {
+		names = append(names, b.name)
+	}
+
+	for path, def := range map[string]string{"/stats": "1h", "/stats/sources": "none"} {
+		found := false
+		for _, p := range doc.Paths[path]["get"].Parameters {
+			if p.Name != "bucket" {
+				continue
+			}
+			found = true
+			if !reflect.DeepEqual(p.Schema.Enum, names) {
+				t.Errorf("%s bucket enum = %v, want %v", path, p.Schema.Enum, names)
+			}
+			if p.Schema.Default != def {
+				t.Errorf("%s bucket default = %v, want %s", path, p.Schema.Default, def)
+			}
+		}
+		if !found {
+			t.Errorf("%s has no bucket parameter", path)
+		}
+	}
+}
+
+func TestOpenAPISchemasMatchResponseTypes(t *testing.T) {
+	doc := loadSpec(t)
+
+	types := map[string]reflect.Type{
+		"TelemetryV1":              reflect.TypeOf(TelemetryV1{}),
+		"TelemetryAviationV1":      reflect.TypeOf(TelemetryAviationV1{}),
+		"AircraftDetailV1":         reflect.TypeOf(AircraftDetailV1{}),
+		"AircraftDetailAviationV1": reflect.TypeOf(AircraftDetailAviationV1{}),
+		"AircraftRegistryV1":       reflect.TypeOf(AircraftRegistryV1{}),
+		"AirlineV1":                reflect.TypeOf(AirlineV1{}),
+		"StatsV1":                  reflect.TypeOf(StatsV1{}),
+		"StatsRowV1":               reflect.TypeOf(StatsRowV1{}),
+		"CoverageV1":               reflect.TypeOf(CoverageV1{}),
+		"CoverageRowV1":            reflect.TypeOf(CoverageRowV1{}),
+		"FlightV1":                 reflect.TypeOf(FlightV1{}),
+		"FlightAviationV1":         reflect.TypeOf(FlightAviationV1{}),
+		"AirportV1":                reflect.TypeOf(AirportV1{}),
+		"AirportAviationV1":        reflect.TypeOf(AirportAviationV1{}),
+		"RunwayV1":                 reflect.TypeOf(RunwayV1{}),
+		"RunwayAviationV1":         reflect.TypeOf(RunwayAviationV1{}),
+		"StatusV1":                 reflect.TypeOf(StatusV1{}),
+		"IngestStatusV1":           reflect.TypeOf(IngestStatusV1{}),
+		"StorageStatusV1":          reflect.TypeOf(StorageStatusV1{}),
+		"ValidationError":          reflect.TypeOf(ValidationError{}),
+		"FieldError":               reflect.TypeOf(FieldError{}),
+	}
+
+	for name, typ := range types {
+		schema, ok := doc.Components.Schemas[name]
+		if !ok {
+			t.Errorf("schema %s missing from openapi.json", name)
+			continue
+		}
+
+		var documented []string
+		for prop := range schema.Properties {
+			documented = append(documented, prop)
+		}
+		fields := jsonFields(typ)
+
+		sort.Strings(documented)
+		sort.Strings(fields)
+		if !reflect.DeepEqual(documented, fields) {
+			t.Errorf("schema %s differs from %s:\n spec: %v\n go:   %v", name, typ, documented, fields)
+		}
+	}
+}
+
+func TestOpenAPIServed(t *testing.T) {
+	s := newTestServer(t)
+
+	req := httptest.NewRequest(http.MethodGet, apiPrefix+"/openapi.json", nil)
+	rec := httptest.NewRecorder()
+	s.Echo.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET openapi.json: status %d", rec.Code)
+	}
+	if !json.Valid(rec.Body.Bytes()) {
+		t.Fatal("GET openapi.json: body is not valid JSON")
+	}
+}
+
+func TestDocsPinRedoc(t *testing.T) {
+	s := newTestServer(t)
+
+	req := httptest.NewRequest(http.MethodGet, apiPrefix+"/docs", nil)
+	rec := httptest.NewRecorder()
+	s.Echo.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET docs: status %d", rec.Code)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, `src="`+redocURL()+`"`) || strings.Contains(body, "latest") {
+		t.Errorf("docs page does not load the pinned Redoc bundle:\n%s", body)
+	}
+	if redocVersion == "" || (len(redocBundle) == 0 && !strings.Contains(redocURL(), "/v"+redocVersion+"/")) {
+		t.Errorf("Redoc URL %q is not pinned to a release", redocURL())
+	}
+}
+
+// jsonFields lists the JSON property names of a struct, flattening embedded
+// structs the way encoding/json does.
+func jsonFields(typ reflect.Type) []string {
+	var fields []string
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			fields = append(fields, jsonFields(f.Type)...)
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" || !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, name)
+	}
+	return fields
+}
@@ -0,0 +1,559 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/northeastloon/flight_tracker/internal/domain"
+)
+
+var (
+	icao24Pattern   = regexp.MustCompile(`^[0-9a-f]{6}$`)
+	callsignPattern = regexp.MustCompile(`^[A-Z0-9]{1,8}$`)
+	squawkPattern   = regexp.MustCompile(`^[0-7]{4}$`)
+	sourcePattern   = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+	airportPattern  = regexp.MustCompile(`^[A-Z0-9-]{2,12}$`)
+	typeCodePattern = regexp.MustCompile(`^[A-Z0-9]{2,4}$`)
+	airlinePattern  = regexp.MustCompile(`^[A-Z]{3}$`) // ICAO airline or operator designator
+)
+
+// FieldError describes a single invalid request parameter.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError is returned as the body of a 400 response when one or more
+// request parameters are invalid. The Parse*Query functions return it
+// listing every invalid field, so handlers send it as it is.
+type ValidationError struct {
+	Message string       `json:"message"`
+	Errors  []FieldError `json:"errors"`
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Errors))
+	for _, fe := range e.Errors {
+		parts = append(parts, fe.Field+": "+fe.Message)
+	}
+	return e.Message + ": " + strings.Join(parts, "; ")
+}
+
+// queryParser reads typed values out of a query string and collects every
+// problem it finds, so the client sees all invalid fields at once. Parameters
+// the caller never looked at are reported as unsupported, so a misspelt
+// filter fails rather than being silently ignored.
+type queryParser struct {
+	values url.Values
+	errors []FieldError
+	known  map[string]bool // every parameter the caller looked at
+}
+
+func newQueryParser(values url.Values) *queryParser {
+	return &queryParser{values: values, known: make(map[string]bool)}
+}
+
+func (p *queryParser) fail(field, format string, args ...any) {
+	p.errors = append(p.errors, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (p *queryParser) has(field string) bool {
+	p.known[field] = true
+	return p.values.Has(field)
+}
+
+func (p *queryParser) string(field string) *string {
+	if !p.has(field) {
+		return nil
+	}
+	v := strings.TrimSpace(p.values.Get(field))
+	if v == "" {
+		p.fail(field, "must not be empty")
+		return nil
+	}
+	return &v
+}
+
+func (p *queryParser) pattern(field string, re *regexp.Regexp, normalize func(string) string, desc string) *string {
+	v := p.string(field)
+	if v == nil {
+		return nil
+	}
+	n := normalize(*v)
+	if !re.MatchString(n) {
+		p.fail(field, "must be %s", desc)
+		return nil
+	}
+	return &n
+}
+
+func (p *queryParser) float(field string, min, max float64) *float64 {
+	v := p.string(field)
+	if v == nil {
+		return nil
+	}
+	f, err := strconv.ParseFloat(*v, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		p.fail(field, "must be a number")
+		return nil
+	}
+	if f < min || f > max {
+		p.fail(field, "must be between %g and %g", min, max)
+		return nil
+	}
+	return &f
+}
+
+func (p *queryParser) int(field string, min, max int) *int {
+	v := p.string(field)
+	if v == nil {
+		return nil
+	}
+	i, err := strconv.Atoi(*v)
+	if err != nil {
+		p.fail(field, "must be an integer")
+		return nil
+	}
+	if i < min || i > max {
+		p.fail(field, "must be between %d and %d", min, max)
+		return nil
+	}
+	return &i
+}
+
+func (p *queryParser) bool(field string) *bool {
+	v := p.string(field)
+	if v == nil {
+		return nil
+	}
+	b, err := strconv.ParseBool(*v)
+	if err != nil {
+		p.fail(field, "must be true or false")
+		return nil
+	}
+	return &b
+}
+
+func (p *queryParser) time(field string) *time.Time {
+	v := p.string(field)
+	if v == nil {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, *v)
+	if err != nil {
+		p.fail(field, "must be an RFC 3339 timestamp")
+		return nil
+	}
+	t = t.UTC()
+	return &t
+}
+
+func (p *queryParser) oneOf(field string, allowed ...string) string {
+	v := p.string(field)
+	if v == nil {
+		return ""
+	}
+	for _, a := range allowed {
+		if *v == a {
+			return a
+		}
+	}
+	p.fail(field, "must be one of %s", strings.Join(allowed, ", "))
+	return ""
+}
+
+func (p *queryParser) err() error {
+	var unknown []string
+	for field := range p.values {
+		if !p.known[field] {
+			unknown = append(unknown, field)
+		}
+	}
+	slices.Sort(unknown)
+	for _, field := range unknown {
+		p.fail(field, "is not a supported parameter")
+	}
+
+	if len(p.errors) == 0 {
+		return nil
+	}
+	return &ValidationError{
+		Message: "invalid query parameters",
+		Errors:  p.errors,
+	}
+}
+
+// TelemetryQuery is the parsed form of the /api/v1/telemetry query string.
+type TelemetryQuery struct {
+	Filter *domain.TelemetryFilter
+	Units  string
+}
+
+// ParseTelemetryQuery validates the query parameters of a telemetry request.
+//
+// Supported parameters: source, icao24, callsign, origin_country, squawk,
+// category, typecode, operator, airline, lat, lon, radius_km, from, to,
+// latest and units.
+func ParseTelemetryQuery(values url.Values) (*TelemetryQuery, error) {
+	return parseTelemetryQuery(newQueryParser(values))
+}
+
+func parseTelemetryQuery(p *queryParser) (*TelemetryQuery, error) {
+	filter := &domain.TelemetryFilter{}
+
+	filter.Source = p.pattern("source", sourcePattern, strings.ToLower, "a provider name such as opensky")
+	filter.ICAO24 = p.pattern("icao24", icao24Pattern, strings.ToLower, "6 hexadecimal digits")
+	filter.Callsign = p.pattern("callsign", callsignPattern, strings.ToUpper, "1 to 8 letters or digits")
+	filter.OriginCountry = p.string("origin_country")
+	filter.Squawk = p.pattern("squawk", squawkPattern, strings.TrimSpace, "4 octal digits")
+	filter.Category = p.int("category", 0, 20)
+	filter.TypeCode = p.pattern("typecode", typeCodePattern, strings.ToUpper, "an ICAO type designator such as A388")
+	filter.Operator = p.pattern("operator", airlinePattern, strings.ToUpper, "an ICAO operator designator such as BAW")
+	filter.Airline = p.pattern("airline", airlinePattern, strings.ToUpper, "an ICAO airline designator such as BAW")
+	filter.From = p.time("from")
+	filter.To = p.time("to")
+	filter.Latest = p.bool("latest")
+	units := p.oneOf("units", UnitsMetric, UnitsAviation)
+
+	if filter.From != nil && filter.To != nil && filter.To.Before(*filter.From) {
+		p.fail("to", "must not be before from")
+	}
+
+	hasLat, hasLon, hasRadius := p.has("lat"), p.has("lon"), p.has("radius_km")
+	if hasLat || hasLon || hasRadius {
+		lat := p.float("lat", -90, 90)
+		lon := p.float("lon", -180, 180)
+		radius := p.float("radius_km", 0, 20037.5) // half the earth's circumference
+
+		for _, field := range []string{"lat", "lon", "radius_km"} {
+			if !p.has(field) {
+				p.fail(field, "is required when searching by position")
+			}
+		}
+		if radius != nil && *radius <= 0 {
+			p.fail("radius_km", "must be greater than 0")
+			radius = nil
+		}
+
+		if lat != nil && lon != nil && radius != nil {
+			filter.Position = &domain.PositionFilter{
+				Latitude:  *lat,
+				Longitude: *lon,
+				Radius:    *radius,
+			}
+		}
+	}
+
+	if err := p.err(); err != nil {
+		return nil, err
+	}
+
+	return &TelemetryQuery{Filter: filter, Units: units}, nil
+}
+
+// AircraftQuery is the parsed form of an /api/v1/aircraft/:icao24 request.
+type AircraftQuery struct {
+	ICAO24 string
+	From   *time.Time
+	To     *time.Time
+	Units  string
+}
+
+// ParseAircraftQuery validates the path and query parameters of an aircraft
+// detail request. Supported query parameters: from, to and units.
+func ParseAircraftQuery(icao24 string, values url.Values) (*AircraftQuery, error) {
+	return parseAircraftQuery(icao24, newQueryParser(values))
+}
+
+func parseAircraftQuery(icao24 string, p *queryParser) (*AircraftQuery, error) {
+	q := &AircraftQuery{
+		ICAO24: strings.ToLower(strings.TrimSpace(icao24)),
+		From:   p.time("from"),
+		To:     p.time("to"),
+		Units:  p.oneOf("units", UnitsMetric, UnitsAviation),
+	}
+
+	if !icao24Pattern.MatchString(q.ICAO24) {
+		p.fail("icao24", "must be 6 hexadecimal digits")
+	}
+	if q.From != nil && q.To != nil && q.To.Before(*q.From) {
+		p.fail("to", "must not be before from")
+	}
+
+	if err := p.err(); err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+// statsBuckets are the bucket sizes accepted by /api/v1/stats and
+// /api/v1/stats/sources, in the order the API documents them. They are
+// multiples of the hourly rollups the statistics are served from.
+var statsBuckets = []struct {
+	name string
+	size time.Duration
+}{
+	{"none", 0},
+	{"1h", time.Hour},
+	{"3h", 3 * time.Hour},
+	{"6h", 6 * time.Hour},
+	{"12h", 12 * time.Hour},
+	{"1d", 24 * time.Hour},
+	{"7d", 7 * 24 * time.Hour},
+}
+
+// bucket parses the bucket parameter, falling back to def when it is
+// absent, and returns its name and size.
+func (p *queryParser) bucket(def string) (string, time.Duration) {
+	names := make([]string, len(statsBuckets))
+	for i, b := range statsBuckets {
+		names[i] = b.name
+	}
+	name := p.oneOf("bucket", names...)
+	if name == "" {
+		name = def
+	}
+	for _, b := range statsBuckets {
+		if b.name == name {
+			return name, b.size
+		}
+	}
+	return name, 0
+}
+
+// StatsQuery is the parsed form of the /api/v1/stats query string.
+type StatsQuery struct {
+	Filter *domain.StatsFilter
+	Bucket string
+}
+
+// ParseStatsQuery validates the query parameters of a stats request.
+//
+// Supported parameters: group_by, bucket, from, to, airborne and limit. The
+// range defaults to the 24 hours before now.
+func ParseStatsQuery(values url.Values, now time.Time) (*StatsQuery, error) {
+	return parseStatsQuery(newQueryParser(values), now)
+}
+
+func parseStatsQuery(p *queryParser, now time.Time) (*StatsQuery, error) {
+	groupBy := p.oneOf("group_by",
+		domain.GroupByNone, domain.GroupByOriginCountry, domain.GroupByCategory, domain.GroupByCell)
+	if groupBy == "" {
+		groupBy = domain.GroupByNone
+	}
+
+	bucket, size := p.bucket("1h")
+
+	to := now.UTC()
+	if t := p.time("to"); t != nil {
+		to = *t
+	}
+	from := to.Add(-24 * time.Hour)
+	if f := p.time("from"); f != nil {
+		from = *f
+	}
+	if !from.Before(to) {
+		p.fail("from", "must be before to")
+	}
+
+	filter := &domain.StatsFilter{
+		From:     from,
+		To:       to,
+		Bucket:   size,
+		GroupBy:  groupBy,
+		Airborne: p.bool("airborne"),
+	}
+	if limit := p.int("limit", 1, 1000); limit != nil {
+		filter.Limit = *limit
+	}
+
+	if err := p.err(); err != nil {
+		return nil, err
+	}
+
+	return &StatsQuery{Filter: filter, Bucket: bucket}, nil
+}
+
+// CoverageQuery is the parsed form of the /api/v1/stats/sources query string.
+type CoverageQuery struct {
+	Filter *domain.CoverageFilter
+	Bucket string
+}
+
+// ParseCoverageQuery validates the query parameters of a source coverage
+// request.
+//
+// Supported parameters: bucket, from, to and source. The range defaults to
+// the 24 hours before now and the bucket to the whole range.
+func ParseCoverageQuery(values url.Values, now time.Time) (*CoverageQuery, error) {
+	return parseCoverageQuery(newQueryParser(values), now)
+}
+
+func parseCoverageQuery(p *queryParser, now time.Time) (*CoverageQuery, error) {
+	bucket, size := p.bucket("none")
+
+	to := now.UTC()
+	if t := p.time("to"); t != nil {
+		to = *t
+	}
+	from := to.Add(-24 * time.Hour)
+	if f := p.time("from"); f != nil {
+		from = *f
+	}
+	if !from.Before(to) {
+		p.fail("from", "must be before to")
+	}
+
+	filter := &domain.CoverageFilter{
+		From:   from,
+		To:     to,
+		Bucket: size,
+		Source: p.pattern("source", sourcePattern, strings.ToLower, "a provider name such as opensky"),
+	}
+
+	if err := p.err(); err != nil {
+		return nil, err
+	}
+
+	return &CoverageQuery{Filter: filter, Bucket: bucket}, nil
+}
+
+// defaultFlightsLimit caps /api/v1/flights responses when no limit is given.
+const defaultFlightsLimit = 100
+
+// FlightsQuery is the parsed form of the /api/v1/flights query string.
+type FlightsQuery struct {
+	Filter *domain.FlightFilter
+	Units  string
+}
+
+// ParseFlightsQuery validates the query parameters of a flights request.
+//
+// Supported parameters: icao24, callsign, origin_country, category, status,
+// departure, arrival, typecode, operator, airline, from, to, limit and units.
+// from and to select flights overlapping the range; limit defaults to 100.
+func ParseFlightsQuery(values url.Values) (*FlightsQuery, error) {
+	return parseFlightsQuery(newQueryParser(values))
+}
+
+func parseFlightsQuery(p *queryParser) (*FlightsQuery, error) {
+	filter := &domain.FlightFilter{
+		ICAO24:        p.pattern("icao24", icao24Pattern, strings.ToLower, "6 hexadecimal digits"),
+		Callsign:      p.pattern("callsign", callsignPattern, strings.ToUpper, "1 to 8 letters or digits"),
+		OriginCountry: p.string("origin_country"),
+		Category:      p.int("category", 0, 20),
+		Departure:     p.pattern("departure", airportPattern, strings.ToUpper, "an airport code such as EGLL"),
+		Arrival:       p.pattern("arrival", airportPattern, strings.ToUpper, "an airport code such as EGLL"),
+		TypeCode:      p.pattern("typecode", typeCodePattern, strings.ToUpper, "an ICAO type designator such as A388"),
+		Operator:      p.pattern("operator", airlinePattern, strings.ToUpper, "an ICAO operator designator such as BAW"),
+		Airline:       p.pattern("airline", airlinePattern, strings.ToUpper, "an ICAO airline designator such as BAW"),
+		From:          p.time("from"),
+		To:            p.time("to"),
+		Limit:         defaultFlightsLimit,
+	}
+	if status := p.oneOf("status", domain.FlightActive, domain.FlightLanded, domain.FlightLost); status != "" {
+		filter.Status = &status
+	}
+	if limit := p.int("limit", 1, 1000); limit != nil {
+		filter.Limit = *limit
+	}
+	units := p.oneOf("units", UnitsMetric, UnitsAviation)
+
+	if filter.From != nil && filter.To != nil && filter.To.Before(*filter.From) {
+		p.fail("to", "must not be before from")
+	}
+
+	if err := p.err(); err != nil {
+		return nil, err
+	}
+
+	return &FlightsQuery{Filter: filter, Units: units}, nil
+}
+
+// AirportQuery is the parsed form of an /api/v1/airports/:icao request.
+type AirportQuery struct {
+	Code  string
+	Units string
+}
+
+// ParseAirportQuery validates the path and query parameters of an airport
+// lookup. code may be an OurAirports ident, ICAO or IATA code. Supported
+// query parameters: units.
+func ParseAirportQuery(code string, values url.Values) (*AirportQuery, error) {
+	return parseAirportQuery(code, newQueryParser(values))
+}
+
+func parseAirportQuery(code string, p *queryParser) (*AirportQuery, error) {
+	q := &AirportQuery{
+		Code:  strings.ToUpper(strings.TrimSpace(code)),
+		Units: p.oneOf("units", UnitsMetric, UnitsAviation),
+	}
+
+	if !airportPattern.MatchString(q.Code) {
+		p.fail("icao", "must be an airport code such as EGLL")
+	}
+
+	if err := p.err(); err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+// NearestAirportsQuery is the parsed form of the /api/v1/airports/nearest
+// query string.
+type NearestAirportsQuery struct {
+	Filter *domain.NearestAirportFilter
+	Units  string
+}
+
+// ParseNearestAirportsQuery validates the query parameters of a nearest
+// airport search.
+//
+// Supported parameters: lat and lon (required), radius_km, type, limit and
+// units. limit defaults to 1.
+func ParseNearestAirportsQuery(values url.Values) (*NearestAirportsQuery, error) {
+	return parseNearestAirportsQuery(newQueryParser(values))
+}
+
+func parseNearestAirportsQuery(p *queryParser) (*NearestAirportsQuery, error) {
+	filter := &domain.NearestAirportFilter{Limit: 1}
+
+	lat := p.float("lat", -90, 90)
+	lon := p.float("lon", -180, 180)
+	for _, field := range []string{"lat", "lon"} {
+		if !p.has(field) {
+			p.fail(field, "is required")
+		}
+	}
+	if lat != nil && lon != nil {
+		filter.Latitude, filter.Longitude = *lat, *lon
+	}
+
+	if radius := p.float("radius_km", 0, 20037.5); radius != nil {
+		if *radius <= 0 {
+			p.fail("radius_km", "must be greater than 0")
+		}
+		filter.Radius = *radius
+	}
+	if t := p.oneOf("type", domain.AirportTypes...); t != "" {
+		filter.Types = []string{t}
+	}
+	if limit := p.int("limit", 1, 100); limit != nil {
+		filter.Limit = *limit
+	}
+	units := p.oneOf("units", UnitsMetric, UnitsAviation)
+
+	if err := p.err(); err != nil {
+		return nil, err
+	}
+
+	return &NearestAirportsQuery{Filter: filter, Units: units}, nil
+}
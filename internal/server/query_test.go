@@ -0,0 +1,184 @@
+package server_test
+
+import (
+	"errors"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/northeastloon/flight_tracker/internal/server"
+)
+
+var queryNow = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// queryParsers parses a query string with each /api/v1 parser.
+var queryParsers = map[string]func(url.Values) error{
+	"telemetry": func(v url.Values) error { _, err := server.ParseTelemetryQuery(v); return err },
+	"aircraft":  func(v url.Values) error { _, err := server.ParseAircraftQuery("4ca7b4", v); return err },
+	"stats":     func(v url.Values) error { _, err := server.ParseStatsQuery(v, queryNow); return err },
+	"coverage":  func(v url.Values) error { _, err := server.ParseCoverageQuery(v, queryNow); return err },
+	"flights":   func(v url.Values) error { _, err := server.ParseFlightsQuery(v); return err },
+	"nearest":   func(v url.Values) error { _, err := server.ParseNearestAirportsQuery(v); return err },
+}
+
+func TestQueryParserErrors(t *testing.T) {
+	tests := []struct {
+		parser string
+		query  string
+		want   []string // "field: message" of every error, in order
+	}{
+		// position searches need all of lat, lon and radius_km
+		{"telemetry", "lat=53", []string{
+			"lon: is required when searching by position",
+			"radius_km: is required when searching by position",
+		}},
+		{"telemetry", "lon=-6&radius_km=10", []string{"lat: is required when searching by position"}},
+		{"telemetry", "radius_km=10", []string{
+			"lat: is required when searching by position",
+			"lon: is required when searching by position",
+		}},
+		{"telemetry", "lat=91&lon=-181&radius_km=0", []string{
+			"lat: must be between -90 and 90",
+			"lon: must be between -180 and 180",
+			"radius_km: must be greater than 0",
+		}},
+		{"telemetry", "lat=north&lon=&radius_km=20038", []string{
+			"lat: must be a number",
+			"lon: must not be empty",
+			"radius_km: must be between 0 and 20037.5",
+		}},
+		{"telemetry", "lat=NaN&lon=NaN&radius_km=NaN", []string{
+			"lat: must be a number",
+			"lon: must be a number",
+			"radius_km: must be a number",
+		}},
+		{"telemetry", "lat=Inf&lon=-Infinity&radius_km=+Inf", []string{
+			"lat: must be a number",
+			"lon: must be a number",
+			"radius_km: must be a number",
+		}},
+		{"nearest", "lat=53", []string{"lon: is required"}},
+		{"nearest", "lat=nan&lon=-6&radius_km=inf", []string{
+			"lat: must be a number",
+			"radius_km: must be a number",
+		}},
+		{"nearest", "lat=53&lon=-6&radius_km=0&limit=101", []string{
+			"radius_km: must be greater than 0",
+			"limit: must be between 1 and 100",
+		}},
+
+		// ranges must not run backwards
+		{"telemetry", "from=2026-03-01T12:00:00Z&to=2026-03-01T11:00:00Z", []string{"to: must not be before from"}},
+		{"aircraft", "from=2026-03-01T12:00:00Z&to=2026-03-01T11:00:00Z", []string{"to: must not be before from"}},
+		{"flights", "from=2026-03-01T12:00:00Z&to=2026-03-01T11:00:00Z", []string{"to: must not be before from"}},
+		{"stats", "from=2026-03-01T12:00:00Z&to=2026-03-01T12:00:00Z", []string{"from: must be before to"}},
+		{"stats", "from=2026-03-01T13:00:00Z", []string{"from: must be before to"}},
+		{"coverage", "to=2026-02-01T00:00:00Z&from=2026-03-01T00:00:00Z", []string{"from: must be before to"}},
+		{"telemetry", "from=yesterday&to=2026-03-01", []string{
+			"from: must be an RFC 3339 timestamp",
+			"to: must be an RFC 3339 timestamp",
+		}},
+
+		// every invalid field is reported at once
+		{"flights", "icao24=xyz&status=parked&limit=0&units=imperial", []string{
+			"icao24: must be 6 hexadecimal digits",
+			"status: must be one of active, landed, lost",
+			"limit: must be between 1 and 1000",
+			"units: must be one of metric, aviation",
+		}},
+
+		// parameters the endpoint does not read are reported after the rest
+		{"flights", "status=parked&icao=4ca7b4&Callsign=EIN1", []string{
+			"status: must be one of active, landed, lost",
+			"Callsign: is not a supported parameter",
+			"icao: is not a supported parameter",
+		}},
+		{"stats", "group_by=cell&bucket=1h&units=aviation", []string{"units: is not a supported parameter"}},
+	}
+
+	for _, tt := range tests {
+		values, err := url.ParseQuery(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = queryParsers[tt.parser](values)
+
+		var verr *server.ValidationError
+		if !errors.As(err, &verr) {
+			t.Errorf("%s?%s: error = %v, want a validation error", tt.parser, tt.query, err)
+			continue
+		}
+		var got []string
+		for _, fe := range verr.Errors {
+			got = append(got, fe.Field+": "+fe.Message)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s?%s:\n got  %q\n want %q", tt.parser, tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestQueryParserAcceptsPositionSearch(t *testing.T) {
+	q, err := server.ParseTelemetryQuery(url.Values{"lat": {"53.4"}, "lon": {"-6.3"}, "radius_km": {"25"}})
+	if err != nil {
+		t.Fatalf("ParseTelemetryQuery: %v", err)
+	}
+	if p := q.Filter.Position; p == nil || p.Latitude != 53.4 || p.Longitude != -6.3 || p.Radius != 25 {
+		t.Errorf("position = %+v", q.Filter.Position)
+	}
+}
+
+func TestParseStatsQuery(t *testing.T) {
+	q, err := server.ParseStatsQuery(url.Values{}, queryNow)
+	if err != nil {
+		t.Fatalf("ParseStatsQuery: %v", err)
+	}
+	f := q.Filter
+	if q.Bucket != "1h" || f.Bucket != time.Hour || f.GroupBy != "none" || f.Airborne != nil || f.Limit != 0 {
+		t.Errorf("defaults = %+v with bucket %s", f, q.Bucket)
+	}
+	if !f.From.Equal(queryNow.Add(-24*time.Hour)) || !f.To.Equal(queryNow) {
+		t.Errorf("default range = %v to %v, want the 24 hours before now", f.From, f.To)
+	}
+
+	q, err = server.ParseStatsQuery(url.Values{
+		"group_by": {"cell"},
+		"bucket":   {"7d"},
+		"from":     {"2026-01-01T00:00:00Z"},
+		"airborne": {"false"},
+		"limit":    {"10"},
+	}, queryNow)
+	if err != nil {
+		t.Fatalf("ParseStatsQuery: %v", err)
+	}
+	f = q.Filter
+	if q.Bucket != "7d" || f.Bucket != 7*24*time.Hour || f.GroupBy != "cell" || f.Limit != 10 {
+		t.Errorf("filter = %+v with bucket %s", f, q.Bucket)
+	}
+	if f.Airborne == nil || *f.Airborne || !f.From.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)) || !f.To.Equal(queryNow) {
+		t.Errorf("airborne = %v, range = %v to %v", f.Airborne, f.From, f.To)
+	}
+
+	_, err = server.ParseStatsQuery(url.Values{"bucket": {"2h"}}, queryNow)
+	var verr *server.ValidationError
+	if !errors.As(err, &verr) || len(verr.Errors) != 1 || verr.Errors[0].Message != "must be one of none, 1h, 3h, 6h, 12h, 1d, 7d" {
+		t.Errorf("bucket 2h: error = %v", err)
+	}
+}
+
+func TestParseCoverageQueryBuckets(t *testing.T) {
+	for bucket, want := range map[string]time.Duration{"": 0, "none": 0, "1h": time.Hour, "12h": 12 * time.Hour, "1d": 24 * time.Hour} {
+		values := url.Values{}
+		if bucket != "" {
+			values.Set("bucket", bucket)
+		}
+		q, err := server.ParseCoverageQuery(values, queryNow)
+		if err != nil {
+			t.Fatalf("bucket %q: %v", bucket, err)
+		}
+		if q.Filter.Bucket != want || (bucket == "" && q.Bucket != "none") {
+			t.Errorf("bucket %q = %s of %v, want %v", bucket, q.Bucket, q.Filter.Bucket, want)
+		}
+	}
+}
@@ -0,0 +1,731 @@
+package server
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/northeastloon/flight_tracker/internal/domain"
+)
+
+// Unit systems accepted by the units query parameter.
+const (
+	UnitsMetric   = "metric"
+	UnitsAviation = "aviation"
+)
+
+// TelemetryBaseV1 holds the fields of a /api/v1 telemetry record that do not
+// depend on the requested unit system. Field names are part of the public API
+// and must not change within v1.
+type TelemetryBaseV1 struct {
+	Source              string              `json:"source"`  // provider that reported the state
+	Sources             []string            `json:"sources"` // every provider that saw the aircraft at the same time
+	ICAO24              string              `json:"icao24"`
+	Callsign            *string             `json:"callsign"`
+	OriginCountry       string              `json:"origin_country"`
+	TimePosition        *string             `json:"time_position"` // RFC 3339
+	LastContact         string              `json:"last_contact"`  // RFC 3339
+	Longitude           *float64            `json:"longitude"`     // WGS-84 decimal degrees
+	Latitude            *float64            `json:"latitude"`      // WGS-84 decimal degrees
+	OnGround            bool                `json:"on_ground"`
+	TrueTrackDeg        *float64            `json:"true_track_deg"` // clockwise from north
+	Sensors             *[]int              `json:"sensors"`
+	Squawk              *string             `json:"squawk"`
+	SPI                 bool                `json:"spi"`
+	PositionSource      int                 `json:"position_source"`
+	PositionSourceLabel *string             `json:"position_source_label"`
+	Category            int                 `json:"category"`
+	CategoryLabel       *string             `json:"category_label"`
+	Registry            *AircraftRegistryV1 `json:"registry"`     // null when the aircraft is not in the registry
+	Registration        *string             `json:"registration"` // from the registry, else derived from the address
+	ICAO24Allocated     bool                `json:"icao24_allocated"`
+	Airline             *AirlineV1          `json:"airline"`       // null unless the callsign's airline is known
+	FlightNumber        *string             `json:"flight_number"` // e.g. BA123; null without an IATA airline code or a numeric flight identifier
+	Extras              map[string]any      `json:"extras"`        // provider-specific fields
+}
+
+// TelemetryV1 is a telemetry record in SI units (the default).
+type TelemetryV1 struct {
+	TelemetryBaseV1
+	BaroAltitudeM   *float64 `json:"baro_altitude_m"`
+	GeoAltitudeM    *float64 `json:"geo_altitude_m"`
+	VelocityMps     *float64 `json:"velocity_mps"`
+	VerticalRateMps *float64 `json:"vertical_rate_mps"`
+}
+
+// TelemetryAviationV1 is a telemetry record in aviation units, returned when
+// units=aviation is requested.
+type TelemetryAviationV1 struct {
+	TelemetryBaseV1
+	BaroAltitudeFt  *float64 `json:"baro_altitude_ft"`
+	GeoAltitudeFt   *float64 `json:"geo_altitude_ft"`
+	VelocityKt      *float64 `json:"velocity_kt"`
+	VerticalRateFpm *float64 `json:"vertical_rate_fpm"`
+}
+
+func newTelemetryBaseV1(t domain.Telemetry) TelemetryBaseV1 {
+	address, allocated := domain.DecodeAddress(t.ICAO24)
+	registration := address.Registration
+	if t.Registry != nil && t.Registry.Registration != nil {
+		registration = t.Registry.Registration
+	}
+	country := t.OriginCountry
+	if country == "" {
+		country = address.Country
+	}
+
+	return TelemetryBaseV1{
+		Source:              t.Source,
+		Sources:             t.Sources,
+		ICAO24:              t.ICAO24,
+		Callsign:            t.Callsign,
+		OriginCountry:       country,
+		TimePosition:        formatOptionalTime(t.TimePosition),
+		LastContact:         formatTime(t.LastContact),
+		Longitude:           t.Longitude,
+		Latitude:            t.Latitude,
+		OnGround:            t.OnGround,
+		TrueTrackDeg:        t.TrueTrack,
+		Sensors:             t.Sensors,
+		Squawk:              t.Squawk,
+		SPI:                 t.SPI,
+		PositionSource:      t.PositionSource,
+		PositionSourceLabel: t.PositionSourceLabel,
+		Category:            t.Category,
+		CategoryLabel:       t.CategoryLabel,
+		Registry:            newAircraftRegistryV1(t.Registry),
+		Registration:        registration,
+		ICAO24Allocated:     allocated,
+		Airline:             newAirlineV1(t.Airline),
+		FlightNumber:        domain.FlightNumber(t.Airline, t.Callsign),
+		Extras:              t.Extras,
+	}
+}
+
+// AircraftRegistryV1 is an aircraft's entry in the aircraft registry.
+type AircraftRegistryV1 struct {
+	Registration *string `json:"registration"`
+	Manufacturer *string `json:"manufacturer"`
+	Model        *string `json:"model"`
+	TypeCode     *string `json:"typecode"` // ICAO type designator
+	Operator     *string `json:"operator"`
+	OperatorICAO *string `json:"operator_icao"` // ICAO airline designator
+	Owner        *string `json:"owner"`
+}
+
+// AirlineV1 is the airline a callsign was decoded to.
+type AirlineV1 struct {
+	ICAO      string  `json:"icao"` // ICAO airline designator
+	IATA      *string `json:"iata"`
+	Name      string  `json:"name"`
+	Country   *string `json:"country"`
+	Telephony *string `json:"telephony"` // radiotelephony callsign, e.g. SPEEDBIRD
+}
+
+func newAirlineV1(a *domain.Airline) *AirlineV1 {
+	if a == nil {
+		return nil
+	}
+	return &AirlineV1{
+		ICAO:      a.ICAO,
+		IATA:      a.IATA,
+		Name:      a.Name,
+		Country:   a.Country,
+		Telephony: a.Telephony,
+	}
+}
+
+func newAircraftRegistryV1(a *domain.AircraftInfo) *AircraftRegistryV1 {
+	if a == nil {
+		return nil
+	}
+	return &AircraftRegistryV1{
+		Registration: a.Registration,
+		Manufacturer: a.Manufacturer,
+		Model:        a.Model,
+		TypeCode:     a.TypeCode,
+		Operator:     a.Operator,
+		OperatorICAO: a.OperatorICAO,
+		Owner:        a.Owner,
+	}
+}
+
+// NewTelemetryV1 converts a domain record into its SI-unit response form.
+func NewTelemetryV1(t domain.Telemetry) TelemetryV1 {
+	return TelemetryV1{
+		TelemetryBaseV1: newTelemetryBaseV1(t),
+		BaroAltitudeM:   t.BaroAltitude,
+		GeoAltitudeM:    t.GeoAltitude,
+		VelocityMps:     t.Velocity,
+		VerticalRateMps: t.VerticalRate,
+	}
+}
+
+// NewTelemetryAviationV1 converts a domain record into its aviation-unit
+// response form (feet, knots and feet per minute).
+func NewTelemetryAviationV1(t domain.Telemetry) TelemetryAviationV1 {
+	return TelemetryAviationV1{
+		TelemetryBaseV1: newTelemetryBaseV1(t),
+		BaroAltitudeFt:  scale(t.BaroAltitude, domain.MetresToFeet),
+		GeoAltitudeFt:   scale(t.GeoAltitude, domain.MetresToFeet),
+		VelocityKt:      scale(t.Velocity, domain.MpsToKnots),
+		VerticalRateFpm: scale(t.VerticalRate, domain.MpsToFeetPerMinute),
+	}
+}
+
+// telemetryResponse converts records into the response type for units.
+func telemetryResponse(telemetry []domain.Telemetry, units string) (any, error) {
+	switch units {
+	case "", UnitsMetric:
+		out := make([]TelemetryV1, 0, len(telemetry))
+		for _, t := range telemetry {
+			out = append(out, NewTelemetryV1(t))
+		}
+		return out, nil
+	case UnitsAviation:
+		out := make([]TelemetryAviationV1, 0, len(telemetry))
+		for _, t := range telemetry {
+			out = append(out, NewTelemetryAviationV1(t))
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("unknown units %q: expected %q or %q", units, UnitsMetric, UnitsAviation)
+	}
+}
+
+// AircraftDetailBaseV1 holds the unit-independent fields of an
+// /api/v1/aircraft/:icao24 response.
+type AircraftDetailBaseV1 struct {
+	ICAO24    string              `json:"icao24"`
+	FirstSeen *string             `json:"first_seen"` // RFC 3339; null when the track is empty
+	LastSeen  *string             `json:"last_seen"`  // RFC 3339; null when the track is empty
+	Registry  *AircraftRegistryV1 `json:"registry"`   // null when the aircraft is not in the registry
+}
+
+// AircraftDetailV1 is the aircraft detail response in SI units.
+type AircraftDetailV1 struct {
+	AircraftDetailBaseV1
+	Latest         TelemetryV1   `json:"latest"`
+	Track          []TelemetryV1 `json:"track"` // oldest first
+	MaxAltitudeM   *float64      `json:"max_altitude_m"`
+	MaxVelocityMps *float64      `json:"max_velocity_mps"`
+	DistanceM      float64       `json:"distance_m"`
+}
+
+// AircraftDetailAviationV1 is the aircraft detail response in aviation units.
+type AircraftDetailAviationV1 struct {
+	AircraftDetailBaseV1
+	Latest        TelemetryAviationV1   `json:"latest"`
+	Track         []TelemetryAviationV1 `json:"track"` // oldest first
+	MaxAltitudeFt *float64              `json:"max_altitude_ft"`
+	MaxVelocityKt *float64              `json:"max_velocity_kt"`
+	DistanceNM    float64               `json:"distance_nm"`
+}
+
+func newAircraftDetailBaseV1(s domain.AircraftSummary) AircraftDetailBaseV1 {
+	return AircraftDetailBaseV1{
+		ICAO24:    s.ICAO24,
+		FirstSeen: formatOptionalTime(s.FirstSeen),
+		LastSeen:  formatOptionalTime(s.LastSeen),
+		Registry:  newAircraftRegistryV1(s.Latest.Registry),
+	}
+}
+
+// aircraftResponse converts a summary into the response type for units.
+func aircraftResponse(s domain.AircraftSummary, units string) (any, error) {
+	switch units {
+	case "", UnitsMetric:
+		track := make([]TelemetryV1, 0, len(s.Track))
+		for _, t := range s.Track {
+			track = append(track, NewTelemetryV1(t))
+		}
+		return AircraftDetailV1{
+			AircraftDetailBaseV1: newAircraftDetailBaseV1(s),
+			Latest:               NewTelemetryV1(s.Latest),
+			Track:                track,
+			MaxAltitudeM:         s.MaxAltitude,
+			MaxVelocityMps:       s.MaxVelocity,
+			DistanceM:            s.Distance,
+		}, nil
+	case UnitsAviation:
+		track := make([]TelemetryAviationV1, 0, len(s.Track))
+		for _, t := range s.Track {
+			track = append(track, NewTelemetryAviationV1(t))
+		}
+		return AircraftDetailAviationV1{
+			AircraftDetailBaseV1: newAircraftDetailBaseV1(s),
+			Latest:               NewTelemetryAviationV1(s.Latest),
+			Track:                track,
+			MaxAltitudeFt:        scale(s.MaxAltitude, domain.MetresToFeet),
+			MaxVelocityKt:        scale(s.MaxVelocity, domain.MpsToKnots),
+			DistanceNM:           s.Distance / domain.MetresPerNM,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown units %q: expected %q or %q", units, UnitsMetric, UnitsAviation)
+	}
+}
+
+// FlightBaseV1 holds the unit-independent fields of an /api/v1/flights
+// record.
+type FlightBaseV1 struct {
+	ID             string     `json:"id"`
+	ICAO24         string     `json:"icao24"`
+	Callsign       *string    `json:"callsign"`      // most recent
+	Callsigns      []string   `json:"callsigns"`     // every callsign used, in order
+	Airline        *AirlineV1 `json:"airline"`       // null unless the callsign's airline is known
+	FlightNumber   *string    `json:"flight_number"` // e.g. BA123; null without an IATA airline code or a numeric flight identifier
+	OriginCountry  string     `json:"origin_country"`
+	Category       int        `json:"category"`
+	Status         string     `json:"status"`     // active, landed or lost
+	FirstSeen      string     `json:"first_seen"` // RFC 3339
+	LastSeen       string     `json:"last_seen"`  // RFC 3339
+	Takeoff        *string    `json:"takeoff"`    // RFC 3339, null unless seen leaving the ground
+	Landing        *string    `json:"landing"`    // RFC 3339, null unless seen landing
+	StartLatitude  *float64   `json:"start_latitude"`
+	StartLongitude *float64   `json:"start_longitude"`
+	EndLatitude    *float64   `json:"end_latitude"`
+	EndLongitude   *float64   `json:"end_longitude"`
+	Departure      *string    `json:"departure_airport"` // airport ident, null when not inferred
+	Arrival        *string    `json:"arrival_airport"`   // airport ident, null when not inferred
+	Observations   int        `json:"observations"`
+}
+
+// FlightV1 is a flight in SI units.
+type FlightV1 struct {
+	FlightBaseV1
+	MaxAltitudeM   *float64 `json:"max_altitude_m"`
+	MaxVelocityMps *float64 `json:"max_velocity_mps"`
+	DistanceM      float64  `json:"distance_m"`
+}
+
+// FlightAviationV1 is a flight in aviation units.
+type FlightAviationV1 struct {
+	FlightBaseV1
+	MaxAltitudeFt *float64 `json:"max_altitude_ft"`
+	MaxVelocityKt *float64 `json:"max_velocity_kt"`
+	DistanceNM    float64  `json:"distance_nm"`
+}
+
+func newFlightBaseV1(f domain.Flight) FlightBaseV1 {
+	callsigns := f.Callsigns
+	if callsigns == nil {
+		callsigns = []string{}
+	}
+	return FlightBaseV1{
+		ID:             f.ID,
+		ICAO24:         f.ICAO24,
+		Callsign:       f.Callsign,
+		Callsigns:      callsigns,
+		Airline:        newAirlineV1(f.Airline),
+		FlightNumber:   domain.FlightNumber(f.Airline, f.Callsign),
+		OriginCountry:  f.OriginCountry,
+		Category:       f.Category,
+		Status:         f.Status,
+		FirstSeen:      formatTime(f.FirstSeen),
+		LastSeen:       formatTime(f.LastSeen),
+		Takeoff:        formatOptionalTime(f.Takeoff),
+		Landing:        formatOptionalTime(f.Landing),
+		StartLatitude:  f.StartLatitude,
+		StartLongitude: f.StartLongitude,
+		EndLatitude:    f.EndLatitude,
+		EndLongitude:   f.EndLongitude,
+		Departure:      f.DepartureAirport,
+		Arrival:        f.ArrivalAirport,
+		Observations:   f.Observations,
+	}
+}
+
+// flightsResponse converts flights into the response type for units.
+func flightsResponse(flights []domain.Flight, units string) (any, error) {
+	switch units {
+	case "", UnitsMetric:
+		out := make([]FlightV1, 0, len(flights))
+		for _, f := range flights {
+			out = append(out, FlightV1{
+				FlightBaseV1:   newFlightBaseV1(f),
+				MaxAltitudeM:   f.MaxAltitude,
+				MaxVelocityMps: f.MaxVelocity,
+				DistanceM:      f.Distance,
+			})
+		}
+		return out, nil
+	case UnitsAviation:
+		out := make([]FlightAviationV1, 0, len(flights))
+		for _, f := range flights {
+			out = append(out, FlightAviationV1{
+				FlightBaseV1:  newFlightBaseV1(f),
+				MaxAltitudeFt: scale(f.MaxAltitude, domain.MetresToFeet),
+				MaxVelocityKt: scale(f.MaxVelocity, domain.MpsToKnots),
+				DistanceNM:    f.Distance / domain.MetresPerNM,
+			})
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("unknown units %q: expected %q or %q", units, UnitsMetric, UnitsAviation)
+	}
+}
+
+// AirportBaseV1 holds the unit-independent fields of an /api/v1/airports
+// record.
+type AirportBaseV1 struct {
+	Ident            string  `json:"ident"`
+	Type             string  `json:"type"`
+	Name             string  `json:"name"`
+	ICAOCode         *string `json:"icao_code"`
+	IATACode         *string `json:"iata_code"`
+	Latitude         float64 `json:"latitude"`
+	Longitude        float64 `json:"longitude"`
+	Country          string  `json:"iso_country"`
+	Region           string  `json:"iso_region"`
+	Municipality     *string `json:"municipality"`
+	ScheduledService bool    `json:"scheduled_service"`
+}
+
+// AirportV1 is an airport in SI units.
+type AirportV1 struct {
+	AirportBaseV1
+	ElevationM *float64   `json:"elevation_m"`
+	DistanceM  *float64   `json:"distance_m,omitempty"` // nearest-airport searches only
+	Runways    []RunwayV1 `json:"runways,omitempty"`    // lookups by code only
+}
+
+// AirportAviationV1 is an airport in aviation units.
+type AirportAviationV1 struct {
+	AirportBaseV1
+	ElevationFt *float64           `json:"elevation_ft"`
+	DistanceNM  *float64           `json:"distance_nm,omitempty"` // nearest-airport searches only
+	Runways     []RunwayAviationV1 `json:"runways,omitempty"`     // lookups by code only
+}
+
+// RunwayBaseV1 holds the unit-independent fields of a runway.
+type RunwayBaseV1 struct {
+	Surface       *string  `json:"surface"`
+	Lighted       bool     `json:"lighted"`
+	Closed        bool     `json:"closed"`
+	LowIdent      *string  `json:"le_ident"`
+	LowLatitude   *float64 `json:"le_latitude"`
+	LowLongitude  *float64 `json:"le_longitude"`
+	LowHeading    *float64 `json:"le_heading_deg"` // degrees true
+	HighIdent     *string  `json:"he_ident"`
+	HighLatitude  *float64 `json:"he_latitude"`
+	HighLongitude *float64 `json:"he_longitude"`
+	HighHeading   *float64 `json:"he_heading_deg"` // degrees true
+}
+
+// RunwayV1 is a runway in SI units.
+type RunwayV1 struct {
+	RunwayBaseV1
+	LengthM        *float64 `json:"length_m"`
+	WidthM         *float64 `json:"width_m"`
+	LowElevationM  *float64 `json:"le_elevation_m"`
+	HighElevationM *float64 `json:"he_elevation_m"`
+}
+
+// RunwayAviationV1 is a runway in aviation units.
+type RunwayAviationV1 struct {
+	RunwayBaseV1
+	LengthFt        *float64 `json:"length_ft"`
+	WidthFt         *float64 `json:"width_ft"`
+	LowElevationFt  *float64 `json:"le_elevation_ft"`
+	HighElevationFt *float64 `json:"he_elevation_ft"`
+}
+
+func newAirportBaseV1(a domain.Airport) AirportBaseV1 {
+	return AirportBaseV1{
+		Ident:            a.Ident,
+		Type:             a.Type,
+		Name:             a.Name,
+		ICAOCode:         a.ICAOCode,
+		IATACode:         a.IATACode,
+		Latitude:         a.Latitude,
+		Longitude:        a.Longitude,
+		Country:          a.Country,
+		Region:           a.Region,
+		Municipality:     a.Municipality,
+		ScheduledService: a.ScheduledService,
+	}
+}
+
+func newRunwayBaseV1(r domain.Runway) RunwayBaseV1 {
+	return RunwayBaseV1{
+		Surface:       r.Surface,
+		Lighted:       r.Lighted,
+		Closed:        r.Closed,
+		LowIdent:      r.LowEnd.Ident,
+		LowLatitude:   r.LowEnd.Latitude,
+		LowLongitude:  r.LowEnd.Longitude,
+		LowHeading:    r.LowEnd.Heading,
+		HighIdent:     r.HighEnd.Ident,
+		HighLatitude:  r.HighEnd.Latitude,
+		HighLongitude: r.HighEnd.Longitude,
+		HighHeading:   r.HighEnd.Heading,
+	}
+}
+
+// NewAirportV1 converts an airport into its SI-unit response form.
+func NewAirportV1(a domain.Airport) AirportV1 {
+	out := AirportV1{
+		AirportBaseV1: newAirportBaseV1(a),
+		ElevationM:    a.Elevation,
+		DistanceM:     a.Distance,
+	}
+	for _, r := range a.Runways {
+		out.Runways = append(out.Runways, RunwayV1{
+			RunwayBaseV1:   newRunwayBaseV1(r),
+			LengthM:        r.Length,
+			WidthM:         r.Width,
+			LowElevationM:  r.LowEnd.Elevation,
+			HighElevationM: r.HighEnd.Elevation,
+		})
+	}
+	return out
+}
+
+// NewAirportAviationV1 converts an airport into its aviation-unit response
+// form (feet and nautical miles).
+func NewAirportAviationV1(a domain.Airport) AirportAviationV1 {
+	out := AirportAviationV1{
+		AirportBaseV1: newAirportBaseV1(a),
+		ElevationFt:   scale(a.Elevation, domain.MetresToFeet),
+		DistanceNM:    scale(a.Distance, 1/domain.MetresPerNM),
+	}
+	for _, r := range a.Runways {
+		out.Runways = append(out.Runways, RunwayAviationV1{
+			RunwayBaseV1:    newRunwayBaseV1(r),
+			LengthFt:        scale(r.Length, domain.MetresToFeet),
+			WidthFt:         scale(r.Width, domain.MetresToFeet),
+			LowElevationFt:  scale(r.LowEnd.Elevation, domain.MetresToFeet),
+			HighElevationFt: scale(r.HighEnd.Elevation, domain.MetresToFeet),
+		})
+	}
+	return out
+}
+
+// airportResponse converts an airport into the response type for units.
+func airportResponse(a domain.Airport, units string) (any, error) {
+	switch units {
+	case "", UnitsMetric:
+		return NewAirportV1(a), nil
+	case UnitsAviation:
+		return NewAirportAviationV1(a), nil
+	default:
+		return nil, fmt.Errorf("unknown units %q: expected %q or %q", units, UnitsMetric, UnitsAviation)
+	}
+}
+
+// airportsResponse converts airports into the response type for units.
+func airportsResponse(airports []domain.Airport, units string) (any, error) {
+	switch units {
+	case "", UnitsMetric:
+		out := make([]AirportV1, 0, len(airports))
+		for _, a := range airports {
+			out = append(out, NewAirportV1(a))
+		}
+		return out, nil
+	case UnitsAviation:
+		out := make([]AirportAviationV1, 0, len(airports))
+		for _, a := range airports {
+			out = append(out, NewAirportAviationV1(a))
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("unknown units %q: expected %q or %q", units, UnitsMetric, UnitsAviation)
+	}
+}
+
+// StatsV1 is the /api/v1/stats response.
+type StatsV1 struct {
+	From    string       `json:"from"` // RFC 3339
+	To      string       `json:"to"`   // RFC 3339
+	Bucket  string       `json:"bucket"`
+	GroupBy string       `json:"group_by"`
+	Rows    []StatsRowV1 `json:"rows"`
+}
+
+// StatsRowV1 is one group within one time bucket.
+type StatsRowV1 struct {
+	BucketStart  *string `json:"bucket_start"` // RFC 3339, null when bucket=none
+	Key          string  `json:"key"`
+	Aircraft     int64   `json:"aircraft"`
+	Observations int64   `json:"observations"`
+}
+
+func newStatsV1(q *StatsQuery, rows []domain.StatsRow) StatsV1 {
+	out := StatsV1{
+		From:    formatTime(q.Filter.From),
+		To:      formatTime(q.Filter.To),
+		Bucket:  q.Bucket,
+		GroupBy: q.Filter.GroupBy,
+		Rows:    make([]StatsRowV1, 0, len(rows)),
+	}
+	for _, r := range rows {
+		out.Rows = append(out.Rows, StatsRowV1{
+			BucketStart:  formatOptionalTime(r.BucketStart),
+			Key:          r.Key,
+			Aircraft:     r.Aircraft,
+			Observations: r.Observations,
+		})
+	}
+	return out
+}
+
+// CoverageV1 is the /api/v1/stats/sources response.
+type CoverageV1 struct {
+	From   string          `json:"from"` // RFC 3339
+	To     string          `json:"to"`   // RFC 3339
+	Bucket string          `json:"bucket"`
+	Rows   []CoverageRowV1 `json:"rows"`
+}
+
+// CoverageRowV1 is one feed's coverage within one time bucket.
+type CoverageRowV1 struct {
+	BucketStart   *string `json:"bucket_start"` // RFC 3339, null when bucket=none
+	Source        string  `json:"source"`
+	Aircraft      int64   `json:"aircraft"`
+	TotalAircraft int64   `json:"total_aircraft"`
+	Coverage      float64 `json:"coverage"` // aircraft / total_aircraft
+	Observations  int64   `json:"observations"`
+	Selected      int64   `json:"selected"`
+	Exclusive     int64   `json:"exclusive"`
+}
+
+func newCoverageV1(q *CoverageQuery, rows []domain.CoverageRow) CoverageV1 {
+	out := CoverageV1{
+		From:   formatTime(q.Filter.From),
+		To:     formatTime(q.Filter.To),
+		Bucket: q.Bucket,
+		Rows:   make([]CoverageRowV1, 0, len(rows)),
+	}
+	for _, r := range rows {
+		row := CoverageRowV1{
+			BucketStart:   formatOptionalTime(r.BucketStart),
+			Source:        r.Source,
+			Aircraft:      r.Aircraft,
+			TotalAircraft: r.TotalAircraft,
+			Observations:  r.Observations,
+			Selected:      r.Selected,
+			Exclusive:     r.Exclusive,
+		}
+		if r.TotalAircraft > 0 {
+			row.Coverage = float64(r.Aircraft) / float64(r.TotalAircraft)
+		}
+		out.Rows = append(out.Rows, row)
+	}
+	return out
+}
+
+// ReadinessV1 is the /readyz response.
+type ReadinessV1 struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// StatusV1 is the /api/v1/status response.
+type StatusV1 struct {
+	Time          string          `json:"time"` // RFC 3339
+	UptimeSeconds float64         `json:"uptime_seconds"`
+	Replica       *string         `json:"replica"` // this replica's lease holder name
+	Ingest        *IngestStatusV1 `json:"ingest"`  // null when ingestion runs in another process
+	Storage       StorageStatusV1 `json:"storage"`
+	Lease         *LeaseStatusV1  `json:"lease"` // null until a replica takes the ingest lease
+}
+
+type IngestStatusV1 struct {
+	LastAttempt         *string `json:"last_attempt"` // RFC 3339
+	LastSuccess         *string `json:"last_success"` // RFC 3339
+	LastError           *string `json:"last_error"`
+	LastErrorAt         *string `json:"last_error_at"` // RFC 3339
+	ProviderLatencyMs   float64 `json:"provider_latency_ms"`
+	StoreDurationMs     float64 `json:"store_duration_ms"`
+	RowsLastIngest      int     `json:"rows_last_ingest"`
+	ConsecutiveFailures int     `json:"consecutive_failures"`
+	Leader              bool    `json:"leader"` // this replica holds the ingest lease
+	IntervalMs          float64 `json:"interval_ms"`
+	NextRun             *string `json:"next_run"`             // RFC 3339
+	RateLimitRemaining  *int    `json:"rate_limit_remaining"` // provider credits, when reported
+	QueueDepth          int     `json:"queue_depth"`
+	QueueCapacity       int     `json:"queue_capacity"`
+	SpillSnapshots      int     `json:"spill_snapshots"` // buffered on disk awaiting replay
+	SpillBytes          int64   `json:"spill_bytes"`
+}
+
+type StorageStatusV1 struct {
+	Rows              int64    `json:"rows"`
+	Aircraft          int64    `json:"aircraft"`
+	NewestLastContact *string  `json:"newest_last_contact"` // RFC 3339
+	DataAgeSeconds    *float64 `json:"data_age_seconds"`    // now minus newest_last_contact
+	MigrationsApplied bool     `json:"migrations_applied"`
+}
+
+// LeaseStatusV1 reports which replica holds the ingest lease.
+type LeaseStatusV1 struct {
+	Holder     string `json:"holder"`
+	AcquiredAt string `json:"acquired_at"` // RFC 3339
+	RenewedAt  string `json:"renewed_at"`  // RFC 3339
+	ExpiresAt  string `json:"expires_at"`  // RFC 3339
+	Valid      bool   `json:"valid"`       // false once expires_at has passed
+}
+
+func newLeaseStatusV1(l domain.LeaseStatus) LeaseStatusV1 {
+	return LeaseStatusV1{
+		Holder:     l.Holder,
+		AcquiredAt: formatTime(l.AcquiredAt),
+		RenewedAt:  formatTime(l.RenewedAt),
+		ExpiresAt:  formatTime(l.ExpiresAt),
+		Valid:      l.Valid,
+	}
+}
+
+func newIngestStatusV1(s domain.IngestStatus) IngestStatusV1 {
+	out := IngestStatusV1{
+		LastAttempt:         formatNonZeroTime(s.LastAttempt),
+		LastSuccess:         formatNonZeroTime(s.LastSuccess),
+		LastErrorAt:         formatNonZeroTime(s.LastErrorAt),
+		ProviderLatencyMs:   milliseconds(s.FetchDuration),
+		StoreDurationMs:     milliseconds(s.StoreDuration),
+		RowsLastIngest:      s.RowsLastIngest,
+		ConsecutiveFailures: s.ConsecutiveFail,
+		Leader:              s.Leader,
+		IntervalMs:          milliseconds(s.Interval),
+		NextRun:             formatNonZeroTime(s.NextRun),
+		RateLimitRemaining:  s.RateLimitRemaining,
+		QueueDepth:          s.QueueDepth,
+		QueueCapacity:       s.QueueCapacity,
+		SpillSnapshots:      s.SpillSnapshots,
+		SpillBytes:          s.SpillBytes,
+	}
+	if s.LastError != "" {
+		out.LastError = &s.LastError
+	}
+	return out
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func formatOptionalTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	s := formatTime(*t)
+	return &s
+}
+
+func formatNonZeroTime(t time.Time) *string {
+	if t.IsZero() {
+		return nil
+	}
+	return formatOptionalTime(&t)
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func scale(v *float64, factor float64) *float64 {
+	if v == nil {
+		return nil
+	}
+	s := *v * factor
+	return &s
+}
@@ -5,19 +5,72 @@ import (
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/labstack/echo/otelecho"
 )
 
 type Server struct {
-	Echo       *echo.Echo
-	ApiHandler *APIHandler
-	WebHandler *WebHandler
+	Echo          *echo.Echo
+	ApiHandler    *APIHandler
+	WebHandler    *WebHandler
+	HealthHandler *HealthHandler
 }
 
-func NewServer(store TelemetryStore) (*Server, error) {
+type options struct {
+	ingest         IngestStatusSource
+	staleAfter     time.Duration
+	requestTimeout time.Duration
+	replica        string
+}
+
+type Option func(o *options)
+
+// WithIngestStatus reports the in-process ingestion loop on /readyz and
+// /api/v1/status.
+func WithIngestStatus(src IngestStatusSource) Option {
+	return func(o *options) {
+		o.ingest = src
+	}
+}
+
+// WithStaleAfter sets how long ingestion may go without success before
+// /readyz fails.
+func WithStaleAfter(d time.Duration) Option {
+	return func(o *options) {
+		o.staleAfter = d
+	}
+}
+
+// WithRequestTimeout bounds how long a handler may run before the request is
+// answered with 503.
+func WithRequestTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.requestTimeout = d
+	}
+}
+
+// WithReplicaID names this replica on /api/v1/status, matching the holder
+// reported for the ingest lease.
+func WithReplicaID(id string) Option {
+	return func(o *options) {
+		o.replica = id
+	}
+}
+
+func NewServer(store TelemetryStore, opts ...Option) (*Server, error) {
 	e := echo.New()
 
+	o := options{
+		staleAfter:     5 * time.Minute,
+		requestTimeout: 15 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	// API handler needs store for data access
 	apiHandler := NewAPIHandler(store)
+	healthHandler := NewHealthHandler(store, o.ingest, o.staleAfter, o.replica)
 	// Web handler only needs templates
 	webHandler, err := NewWebHandler()
 	if err != nil {
@@ -25,15 +78,18 @@ func NewServer(store TelemetryStore) (*Server, error) {
 	}
 
 	s := &Server{
-		Echo:       e,
-		ApiHandler: apiHandler,
-		WebHandler: webHandler,
+		Echo:          e,
+		ApiHandler:    apiHandler,
+		WebHandler:    webHandler,
+		HealthHandler: healthHandler,
 	}
 
+	e.Use(otelecho.Middleware("flight_tracker"))
+	e.Use(metricsMiddleware())
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
 	e.Use(middleware.TimeoutWithConfig(middleware.TimeoutConfig{
-		Timeout: 15 * time.Second,
+		Timeout: o.requestTimeout,
 	}))
 
 	// Map routes
@@ -50,12 +106,27 @@ func NewServer(store TelemetryStore) (*Server, error) {
 
 func (s *Server) mapRoutes() {
 
+	// Probes
+	s.Echo.GET("/healthz", s.HealthHandler.Healthz)
+	s.Echo.GET("/readyz", s.HealthHandler.Readyz)
+	s.Echo.GET("/metrics", echo.WrapHandler(promhttp.Handler()))
+
 	// API routes
 	api := s.Echo.Group("/api/v1")
 	api.GET("/telemetry", s.ApiHandler.GetTelemetry)
+	api.GET("/aircraft/:icao24", s.ApiHandler.GetAircraft)
+	api.GET("/stats", s.ApiHandler.GetStats)
+	api.GET("/stats/sources", s.ApiHandler.GetSourceCoverage)
+	api.GET("/flights", s.ApiHandler.GetFlights)
+	api.GET("/airports/nearest", s.ApiHandler.GetNearestAirports)
+	api.GET("/airports/:icao", s.ApiHandler.GetAirport)
+	api.GET("/status", s.HealthHandler.GetStatus)
+	api.GET("/openapi.json", s.ApiHandler.GetOpenAPI)
+	api.GET("/docs", s.WebHandler.DocsHandler)
 
 	// Web routes
 	s.Echo.GET("/", s.WebHandler.GlobeHandler)
+	s.Echo.GET(redocPath, s.WebHandler.RedocHandler)
 
 	// Static files
 	s.Echo.Static("/static", "internal/server/web/static")
@@ -6,6 +6,7 @@ import (
 	"html/template"
 	"io"
 	"net/http"
+	"strings"
 
 	"github.com/labstack/echo/v4"
 )
@@ -13,6 +14,33 @@ import (
 //go:embed templates/*.tmpl
 var templateFS embed.FS
 
+// The API docs page runs the Redoc release named in static/redoc/VERSION,
+// loaded from the Redoc CDN by default. For deployments without internet
+// access, go generate downloads that release next to VERSION; a build with
+// the bundle in place embeds it and serves it locally instead.
+//
+//go:generate sh -c "curl -sSfL -o static/redoc/redoc.standalone.js https://cdn.redoc.ly/redoc/v$(cat static/redoc/VERSION)/bundles/redoc.standalone.js"
+//go:embed static/redoc
+var redocFS embed.FS
+
+// redocPath is where a vendored Redoc bundle is served.
+const redocPath = "/docs/redoc.standalone.js"
+
+var redocVersion, redocBundle = func() (string, []byte) {
+	version, _ := redocFS.ReadFile("static/redoc/VERSION")
+	bundle, _ := redocFS.ReadFile("static/redoc/redoc.standalone.js")
+	return strings.TrimSpace(string(version)), bundle
+}()
+
+// redocURL returns the URL of the pinned Redoc bundle: the vendored copy when
+// the build embedded one, otherwise the CDN.
+func redocURL() string {
+	if len(redocBundle) > 0 {
+		return redocPath
+	}
+	return "https://cdn.redoc.ly/redoc/v" + redocVersion + "/bundles/redoc.standalone.js"
+}
+
 type WebHandler struct {
 	templates *template.Template
 }
@@ -44,3 +72,20 @@ func (h *WebHandler) GlobeHandler(c echo.Context) error {
 		"Title": "Flight Map",
 	})
 }
+
+func (h *WebHandler) DocsHandler(c echo.Context) error {
+	return c.Render(http.StatusOK, "docs.tmpl", map[string]interface{}{
+		"Title":    "Flight Tracker API",
+		"SpecURL":  "/api/v1/openapi.json",
+		"RedocURL": redocURL(),
+	})
+}
+
+// RedocHandler serves the vendored Redoc bundle. Without one, the docs page
+// uses the CDN and this route answers 404.
+func (h *WebHandler) RedocHandler(c echo.Context) error {
+	if len(redocBundle) == 0 {
+		return echo.ErrNotFound
+	}
+	return c.Blob(http.StatusOK, echo.MIMEApplicationJavaScript, redocBundle)
+}
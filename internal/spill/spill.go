@@ -0,0 +1,325 @@
+// Package spill buffers ingestion snapshots on local disk while the store is
+// unavailable, so they can be replayed in order once it recovers.
+package spill
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/northeastloon/flight_tracker/internal/metrics"
+)
+
+// DefaultMaxBytes caps the records waiting to be replayed.
+const DefaultMaxBytes = 1 << 30
+
+// recordVersion versions the envelope Append writes each record in, so a
+// change to the record type can tell old records from new ones.
+const recordVersion = 1
+
+type record[T any] struct {
+	Version int `json:"v"`
+	Data    T   `json:"data"`
+}
+
+// File is an append-only queue of snapshots, one versioned JSON record per
+// line.
+// Replayed records are consumed from the head; the file is truncated once
+// every record has been replayed. The head is not persisted, so after a
+// restart the whole file is replayed again and the store's deduplication
+// absorbs the repeats.
+//
+// When an append would take the waiting records past the size cap, the
+// oldest are evicted to make room, and the file is rewritten once the
+// evicted and consumed records at its head outgrow the cap.
+type File[T any] struct {
+	mu       sync.Mutex
+	path     string
+	maxBytes int64
+	f        *os.File
+	head     int64 // offset of the oldest record not yet replayed
+	headSeq  int64 // sequence number of the record at head
+	size     int64
+	count    int
+}
+
+type options struct {
+	maxBytes int64
+}
+
+type Option func(o *options)
+
+// WithMaxBytes caps the records waiting to be replayed at n bytes, evicting
+// the oldest to make room for new ones. 0 removes the cap.
+func WithMaxBytes(n int64) Option {
+	return func(o *options) {
+		o.maxBytes = n
+	}
+}
+
+// Open opens or creates the spill file at path. A record left half-written
+// by a crash is cut off.
+func Open[T any](path string, opts ...Option) (*File[T], error) {
+	o := options{maxBytes: DefaultMaxBytes}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create spill directory: %w", err)
+	}
+
+	f, err := openFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &File[T]{path: path, maxBytes: o.maxBytes, f: f}
+	if err := b.scan(); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return b, nil
+}
+
+func openFile(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open spill file: %w", err)
+	}
+	return f, nil
+}
+
+// scan counts the complete records in the file and truncates a trailing
+// partial one.
+func (b *File[T]) scan() error {
+	r := bufio.NewReader(io.NewSectionReader(b.f, 0, 1<<62))
+	var offset int64
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 {
+				slog.Warn("Discarding partial spill record", "path", b.path, "bytes", len(line))
+				if err := b.f.Truncate(offset); err != nil {
+					return fmt.Errorf("failed to truncate spill file: %w", err)
+				}
+			}
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read spill file: %w", err)
+		}
+		offset += int64(len(line))
+		b.count++
+	}
+
+	b.size = offset
+	return nil
+}
+
+// Append writes data to the end of the file and syncs it to disk, first
+// evicting the oldest records if the file would outgrow its cap.
+func (b *File[T]) Append(data T) error {
+	line, err := json.Marshal(record[T]{Version: recordVersion, Data: data})
+	if err != nil {
+		return fmt.Errorf("failed to encode spill record: %w", err)
+	}
+	line = append(line, '\n')
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.makeRoom(int64(len(line))); err != nil {
+		return err
+	}
+
+	if _, err := b.f.Write(line); err != nil {
+		return fmt.Errorf("failed to write spill record: %w", err)
+	}
+	if err := b.f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync spill file: %w", err)
+	}
+
+	b.size += int64(len(line))
+	b.count++
+	return nil
+}
+
+// makeRoom evicts records from the head until n more bytes fit under the
+// cap, and compacts the file once the unused space at its head exceeds the
+// cap.
+func (b *File[T]) makeRoom(n int64) error {
+	if b.maxBytes <= 0 {
+		return nil
+	}
+
+	evicted := 0
+	for b.count > 0 && b.size-b.head+n > b.maxBytes {
+		line, err := b.readAt(b.head)
+		if err != nil {
+			return err
+		}
+		b.head += int64(len(line))
+		b.headSeq++
+		b.count--
+		evicted++
+	}
+	if evicted > 0 {
+		slog.Warn("Spill file full, evicting the oldest snapshots", "path", b.path, "evicted", evicted)
+		metrics.IngestSpillEvicted.Add(float64(evicted))
+	}
+
+	if b.head > b.maxBytes {
+		return b.compact()
+	}
+	return nil
+}
+
+// compact rewrites the file without the records before head.
+func (b *File[T]) compact() error {
+	tmp := b.path + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to compact spill file: %w", err)
+	}
+	_, err = io.Copy(out, io.NewSectionReader(b.f, b.head, b.size-b.head))
+	if err == nil {
+		err = out.Sync()
+	}
+	if err = errors.Join(err, out.Close()); err == nil {
+		err = os.Rename(tmp, b.path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to compact spill file: %w", err)
+	}
+
+	f, err := openFile(b.path)
+	if err != nil {
+		return err
+	}
+	b.f.Close()
+	b.f = f
+	b.size -= b.head
+	b.head = 0
+	return nil
+}
+
+// Replay calls fn for each record, oldest first, and consumes the records for
+// which it returns nil. It stops at the first error, leaving that record at
+// the head for the next attempt. Records that cannot be decoded are logged
+// and skipped. Appends may continue while a replay is running.
+func (b *File[T]) Replay(fn func(T) error) error {
+	for {
+		line, seq, err := b.peek()
+		if err != nil {
+			return err
+		}
+		if line == nil {
+			return nil
+		}
+
+		if data, err := b.decode(line); err != nil {
+			slog.Error("Skipping undecodable spill record", "path", b.path, "error", err)
+		} else if err := fn(data); err != nil {
+			return err
+		}
+
+		if err := b.consume(seq, int64(len(line))); err != nil {
+			return err
+		}
+	}
+}
+
+// decode decodes a record of the current version.
+func (b *File[T]) decode(line []byte) (T, error) {
+	var data T
+	var r record[json.RawMessage]
+	if err := json.Unmarshal(line, &r); err != nil {
+		return data, err
+	}
+	if r.Version != recordVersion {
+		return data, fmt.Errorf("unsupported spill record version %d", r.Version)
+	}
+	err := json.Unmarshal(r.Data, &data)
+	return data, err
+}
+
+// peek returns the record at the head and its sequence number, or nil when
+// the file is drained.
+func (b *File[T]) peek() ([]byte, int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.head >= b.size {
+		return nil, 0, nil
+	}
+
+	line, err := b.readAt(b.head)
+	if err != nil {
+		return nil, 0, err
+	}
+	return bytes.Clone(line), b.headSeq, nil
+}
+
+func (b *File[T]) readAt(offset int64) ([]byte, error) {
+	r := bufio.NewReader(io.NewSectionReader(b.f, offset, b.size-offset))
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spill record: %w", err)
+	}
+	return line, nil
+}
+
+// consume drops the n-byte record seq from the head, unless an append has
+// evicted it meanwhile, and truncates the file once it has been fully
+// replayed.
+func (b *File[T]) consume(seq, n int64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if seq != b.headSeq {
+		return nil
+	}
+	b.head += n
+	b.headSeq++
+	b.count--
+	if b.head < b.size {
+		return nil
+	}
+
+	if err := b.f.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate spill file: %w", err)
+	}
+	b.head, b.size, b.count = 0, 0, 0
+	return nil
+}
+
+// Len returns the number of records waiting to be replayed.
+func (b *File[T]) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.count
+}
+
+// Size returns the bytes waiting to be replayed.
+func (b *File[T]) Size() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.size - b.head
+}
+
+// Close closes the file. Records not yet replayed stay on disk.
+func (b *File[T]) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.f.Close()
+}
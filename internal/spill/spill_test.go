@@ -0,0 +1,151 @@
+package spill_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/northeastloon/flight_tracker/internal/metrics"
+	"github.com/northeastloon/flight_tracker/internal/spill"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// recordSize is the size on disk of a single-digit record.
+const recordSize = int64(len(`{"v":1,"data":1}` + "\n"))
+
+func open(t *testing.T, path string, opts ...spill.Option) *spill.File[int] {
+	t.Helper()
+	f, err := spill.Open[int](path, opts...)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func appendAll(t *testing.T, f *spill.File[int], records ...int) {
+	t.Helper()
+	for _, r := range records {
+		if err := f.Append(r); err != nil {
+			t.Fatalf("Append(%d): %v", r, err)
+		}
+	}
+}
+
+// replay consumes up to n records, or all of them when n is negative.
+func replay(t *testing.T, f *spill.File[int], n int) []int {
+	t.Helper()
+	var got []int
+	stop := errors.New("stop")
+	err := f.Replay(func(r int) error {
+		if len(got) == n {
+			return stop
+		}
+		got = append(got, r)
+		return nil
+	})
+	if err != nil && !errors.Is(err, stop) {
+		t.Fatalf("Replay: %v", err)
+	}
+	return got
+}
+
+func fileSize(t *testing.T, path string) int64 {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info.Size()
+}
+
+func TestSpillReplaysInOrder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spill", "ingest.ndjson")
+	f := open(t, path)
+	appendAll(t, f, 1, 2, 3)
+
+	if got := replay(t, f, 2); !slices.Equal(got, []int{1, 2}) {
+		t.Fatalf("first replay = %v, want [1 2]", got)
+	}
+	if f.Len() != 1 || f.Size() != recordSize {
+		t.Errorf("after a partial replay Len = %d, Size = %d; want 1, %d", f.Len(), f.Size(), recordSize)
+	}
+
+	// appends during or after a replay queue behind what is left
+	appendAll(t, f, 4)
+	if got := replay(t, f, -1); !slices.Equal(got, []int{3, 4}) {
+		t.Fatalf("second replay = %v, want [3 4]", got)
+	}
+
+	// a drained file is truncated
+	if f.Len() != 0 || f.Size() != 0 || fileSize(t, path) != 0 {
+		t.Errorf("drained spill has Len %d, Size %d and %d bytes on disk", f.Len(), f.Size(), fileSize(t, path))
+	}
+}
+
+func TestSpillSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ingest.ndjson")
+	f := open(t, path)
+	appendAll(t, f, 1, 2, 3)
+	replay(t, f, 1)
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// a crash left half a record behind
+	w, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.WriteString("4")
+	w.Close()
+
+	// the head is not persisted, so the consumed record comes back too
+	f = open(t, path)
+	if f.Len() != 3 {
+		t.Fatalf("reopened Len = %d, want 3", f.Len())
+	}
+	appendAll(t, f, 5)
+	if got := replay(t, f, -1); !slices.Equal(got, []int{1, 2, 3, 5}) {
+		t.Errorf("replay after restart = %v, want [1 2 3 5]", got)
+	}
+}
+
+func TestSpillEvictsOldest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ingest.ndjson")
+	// the cap holds three records
+	f := open(t, path, spill.WithMaxBytes(3*recordSize))
+	evicted := testutil.ToFloat64(metrics.IngestSpillEvicted)
+
+	appendAll(t, f, 1, 2, 3, 4, 5, 6, 7, 8)
+	if f.Len() != 3 || f.Size() != 3*recordSize {
+		t.Errorf("Len = %d, Size = %d; want 3, %d", f.Len(), f.Size(), 3*recordSize)
+	}
+	if got := testutil.ToFloat64(metrics.IngestSpillEvicted) - evicted; got != 5 {
+		t.Errorf("evicted %v snapshots, want 5", got)
+	}
+
+	// the evicted head outgrew the cap, so the file was compacted to at
+	// most twice the cap rather than holding all eight records
+	if size := fileSize(t, path); size > 6*recordSize {
+		t.Errorf("spill file holds %d bytes, want at most %d", size, 6*recordSize)
+	}
+	if got := replay(t, f, -1); !slices.Equal(got, []int{6, 7, 8}) {
+		t.Errorf("replay = %v, want [6 7 8]", got)
+	}
+}
+
+func TestSpillSkipsRecordsOfAnotherVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ingest.ndjson")
+	if err := os.WriteFile(path, []byte(`{"v":1,"data":1}`+"\n"+`{"v":2,"data":2}`+"\n2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	f := open(t, path)
+	appendAll(t, f, 3)
+	if got := replay(t, f, -1); !slices.Equal(got, []int{1, 3}) {
+		t.Errorf("replay = %v, want [1 3]", got)
+	}
+}